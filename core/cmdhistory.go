@@ -0,0 +1,315 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/friedelschoen/editor/core/toolbarparser"
+	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/uiutil/event"
+)
+
+// History of the toolbar cmds run in rows, by directory. Saved in the home directory. Keys in the row toolbar cycle through the row directory history.
+// The state is handled in the UI goroutine, except saving.
+type CmdHistory struct {
+	ed         *Editor
+	loaded     bool
+	loadFailed bool                // don't overwrite a file that could not be read
+	dirs       map[string][]string // oldest first
+	cycles     map[*ERow]*cmdHistoryCycle
+
+	saveMu  sync.Mutex // serializes the file writes
+	unsaved struct {
+		sync.Mutex
+		adds []cmdHistoryEntry
+	}
+}
+
+func NewCmdHistory(ed *Editor) *CmdHistory {
+	h := &CmdHistory{ed: ed}
+	h.dirs = map[string][]string{}
+	h.cycles = map[*ERow]*cmdHistoryCycle{}
+	return h
+}
+
+//----------
+
+// Called on row close.
+func (h *CmdHistory) Close(erow *ERow) {
+	delete(h.cycles, erow)
+}
+
+// Adds the cmd of the toolbar part to the row directory history.
+func (h *CmdHistory) Add(erow *ERow, part *toolbarparser.Part) {
+	if erow == nil || len(part.Args) == 0 {
+		return
+	}
+	dir := erow.Info.Dir()
+	if dir == "" { // special rows
+		return
+	}
+	h.load()
+	s := part.FromArgString(0)
+	h.dirs[dir] = cmdHistoryAdd(h.dirs[dir], s)
+	if h.loadFailed {
+		return
+	}
+	h.unsaved.Lock()
+	h.unsaved.adds = append(h.unsaved.adds, cmdHistoryEntry{dir, s})
+	h.unsaved.Unlock()
+	go h.save()
+}
+
+func (h *CmdHistory) Dir(dir string) []string {
+	h.load()
+	return h.dirs[dir]
+}
+
+//----------
+
+func (h *CmdHistory) load() {
+	if h.loaded {
+		return
+	}
+	h.loaded = true
+	dirs, err := cmdHistoryRead(cmdHistoryFilename())
+	if err != nil {
+		h.loadFailed = true
+		h.ed.Errorf("cmd history: %w", err)
+		return
+	}
+	h.dirs = dirs
+}
+
+// Merges the unsaved cmds with the file content (ex: other editor instances). Runs on its own goroutine.
+func (h *CmdHistory) save() {
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+
+	h.unsaved.Lock()
+	adds := h.unsaved.adds
+	h.unsaved.adds = nil
+	h.unsaved.Unlock()
+	if len(adds) == 0 { // saved by a previous call
+		return
+	}
+
+	if err := cmdHistorySave(cmdHistoryFilename(), adds); err != nil {
+		h.ed.Errorf("cmd history: %w", err)
+	}
+}
+
+//----------
+
+// Lists the history in the "+History" row, newest first, grouped by directory (the row directory first). Only cmds containing the query are listed.
+func (h *CmdHistory) Show(erow *ERow, query string) {
+	h.load()
+
+	dirs := []string{}
+	for dir := range h.dirs {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	if erow != nil {
+		if i := slices.Index(dirs, erow.Info.Dir()); i > 0 {
+			dirs = slices.Delete(dirs, i, i+1)
+			dirs = slices.Insert(dirs, 0, erow.Info.Dir())
+		}
+	}
+
+	sb := &strings.Builder{}
+	for _, dir := range dirs {
+		cmds := cmdHistorySearch(h.dirs[dir], query)
+		if len(cmds) == 0 {
+			continue
+		}
+		fmt.Fprintf(sb, "%s:\n", dir)
+		for _, s := range cmds {
+			fmt.Fprintf(sb, "\t%s\n", s)
+		}
+	}
+	str := sb.String()
+
+	erow2, _ := ExistingERowOrNewBasic(h.ed, "+History")
+	// keep the query in the toolbar to allow editing it
+	tb := " | History"
+	if query != "" {
+		tb += " " + query
+	}
+	erow2.ToolbarSetStrAfterNameClearHistory(tb)
+	erow2.Flash()
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		_, err := fmt.Fprintf(rw, "history:\n%s", str)
+		return err
+	})
+}
+
+//----------
+
+// Cycles through the row directory history in the toolbar (alt+up/down). The toolbar part at the cursor is replaced (or a new part is added if at the row name).
+func (h *CmdHistory) OnToolbarInput(erow *ERow, ev *ui.TextAreaInputEvent) bool {
+	kd, ok := ev.Event.(*event.KeyDown)
+	if !ok || !kd.Mods.ClearLocks().Is(event.ModAlt) {
+		return false
+	}
+	switch kd.KeySym {
+	case event.KSymUp:
+		h.cycle(erow, -1)
+		return true
+	case event.KSymDown:
+		h.cycle(erow, 1)
+		return true
+	}
+	return false
+}
+
+func (h *CmdHistory) cycle(erow *ERow, dir int) {
+	tb := erow.Row.Toolbar
+
+	// start a new cycle if the toolbar was changed meanwhile
+	c, ok := h.cycles[erow]
+	if !ok || c.str != tb.Str() {
+		cmds := h.Dir(erow.Info.Dir())
+		if len(cmds) == 0 {
+			return
+		}
+		c = &cmdHistoryCycle{cmds: slices.Clone(cmds), index: len(cmds), at: tb.CursorIndex()}
+		// original text to return to
+		if p, ok := erow.TbData.PartAtIndex(c.at); ok && p != erow.TbData.Parts[0] && len(p.Args) > 0 {
+			c.orig = p.FromArgString(0)
+		}
+		h.cycles[erow] = c
+	}
+
+	i := c.index + dir
+	if i < 0 || i > len(c.cmds) {
+		return
+	}
+	c.index = i
+	s := c.orig
+	if i < len(c.cmds) {
+		s = c.cmds[i]
+	}
+
+	res := toolbarparser.ReplacePartArgsAtIndex(&erow.TbData, c.at, s)
+	if err := tb.SetStr(res.S); err != nil {
+		erow.Ed.Error(err)
+		return
+	}
+	tb.SetCursorIndex(res.End)
+	c.at = res.End
+	c.str = tb.Str()
+}
+
+//----------
+
+type cmdHistoryEntry struct {
+	dir string
+	cmd string
+}
+
+//----------
+
+type cmdHistoryCycle struct {
+	cmds  []string
+	index int    // len(cmds) is the original text
+	orig  string // original text of the part
+	at    int    // toolbar index of the part
+	str   string // toolbar content set by the cycle
+}
+
+//----------
+
+const cmdHistoryMax = 200 // per directory
+
+// Appends the cmd, removing a previous equal entry.
+func cmdHistoryAdd(cmds []string, s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return cmds
+	}
+	cmds = slices.DeleteFunc(cmds, func(s2 string) bool { return s2 == s })
+	cmds = append(cmds, s)
+	if len(cmds) > cmdHistoryMax {
+		cmds = cmds[len(cmds)-cmdHistoryMax:]
+	}
+	return cmds
+}
+
+// Cmds containing the query (ignoring case), newest first.
+func cmdHistorySearch(cmds []string, query string) []string {
+	query = strings.ToLower(query)
+	res := []string{}
+	for i := len(cmds) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(cmds[i]), query) {
+			res = append(res, cmds[i])
+		}
+	}
+	return res
+}
+
+// Reads the history file, which is empty if it doesn't exist.
+func cmdHistoryRead(filename string) (map[string][]string, error) {
+	dirs := map[string][]string{}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return dirs, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &dirs); err != nil {
+		return nil, err
+	}
+	if dirs == nil { // ex: "null" in the file
+		dirs = map[string][]string{}
+	}
+	return dirs, nil
+}
+
+// Adds the cmds to the current file content. Writes to a temporary file that replaces the file to not leave a partial file.
+func cmdHistorySave(filename string, adds []cmdHistoryEntry) error {
+	dirs, err := cmdHistoryRead(filename)
+	if err != nil {
+		return err
+	}
+	for _, a := range adds {
+		dirs[a.dir] = cmdHistoryAdd(dirs[a.dir], a.cmd)
+	}
+	b, err := json.MarshalIndent(dirs, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+func cmdHistoryFilename() string {
+	return homeFilename(".editor_history.json")
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCmdHistory1(t *testing.T) {
+	cmds := []string{}
+	for _, s := range []string{"go test", " go build ", "make", "go test", ""} {
+		cmds = cmdHistoryAdd(cmds, s)
+	}
+	if fmt.Sprint(cmds) != "[go build make go test]" {
+		t.Fatal(cmds)
+	}
+	res := cmdHistorySearch(cmds, "GO")
+	if fmt.Sprint(res) != "[go test go build]" {
+		t.Fatal(res)
+	}
+}
+
+func TestCmdHistory2(t *testing.T) {
+	// saves merge with the file content (ex: other instances)
+	fn := filepath.Join(t.TempDir(), "history.json")
+	if err := cmdHistorySave(fn, []cmdHistoryEntry{{"/a", "ls"}, {"/b", "make"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmdHistorySave(fn, []cmdHistoryEntry{{"/a", "go test"}, {"/a", "ls"}}); err != nil {
+		t.Fatal(err)
+	}
+	dirs, err := cmdHistoryRead(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(dirs) != "map[/a:[go test ls] /b:[make]]" {
+		t.Fatal(dirs)
+	}
+
+	// don't overwrite a file that can't be read
+	if err := os.WriteFile(fn, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmdHistorySave(fn, []cmdHistoryEntry{{"/a", "ls"}}); err == nil {
+		t.Fatal("expecting error")
+	}
+	if b, _ := os.ReadFile(fn); string(b) != "{" {
+		t.Fatal(string(b))
+	}
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/uiutil/event"
+)
+
+// Shows code lenses (lsproto) in file rows as clickable lines above the lines they refer to (ex: "run test"). The lines are only drawn (inlay hints), they are not part of the content. Clicking (buttonRight) a lens runs its command in the server.
+// Lenses are requested when the row is visible, and only the lenses in the visible part are resolved (the unresolved are not shown).
+type CodeLens struct {
+	ed   *Editor
+	rows map[*ERow]*codeLensRow
+}
+
+func NewCodeLens(ed *Editor) *CodeLens {
+	cl := &CodeLens{ed: ed}
+	cl.rows = map[*ERow]*codeLensRow{}
+	return cl
+}
+
+//----------
+
+// Called on new file rows. The request is done on layout, if visible.
+func (cl *CodeLens) Init(erow *ERow) {
+	if !erow.Info.IsFileButNotDir() {
+		return
+	}
+	if _, err := cl.ed.LSProtoMan.LangManager(erow.Info.Name()); err != nil {
+		return
+	}
+	cl.rows[erow] = &codeLensRow{stale: true}
+}
+
+// Called on row close.
+func (cl *CodeLens) Close(erow *ERow) {
+	r, ok := cl.rows[erow]
+	if !ok {
+		return
+	}
+	r.stop()
+	delete(cl.rows, erow)
+}
+
+// Keeps the current lenses in place and refreshes after a delay if visible. Duplicate rows share the content, but only the written row gets the event: all are updated.
+func (cl *CodeLens) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	if !ev.Changed {
+		return
+	}
+	for _, erow2 := range erow.Info.ERows {
+		r, ok := cl.rows[erow2]
+		if !ok {
+			continue
+		}
+		r.version++
+		r.stale = true
+		w := []*codeLensEntry{}
+		for _, e := range r.entries {
+			if u := shiftInlayHints([]*drawutil.InlayHint{e.hint}, ev); len(u) == 1 {
+				w = append(w, &codeLensEntry{u[0], e.lenses, e.spans})
+			}
+		}
+		cl.setEntries(erow2, r, w)
+		if _, n := inlayHintsVisible(erow2); n > 0 {
+			cl.schedule(erow2, codeLensDelay)
+		}
+	}
+}
+
+// Requests if visible and outdated, or resolves if the visible range is not covered by the last request (ex: scroll).
+func (cl *CodeLens) OnLayout(erow *ERow) {
+	r, ok := cl.rows[erow]
+	if !ok || r.timer != nil {
+		return
+	}
+	o, n := inlayHintsVisible(erow)
+	if n == 0 {
+		return // not visible
+	}
+	if !r.stale && o >= r.offset && o+n <= r.offset+r.n {
+		return
+	}
+	cl.schedule(erow, inlayHintsDelay)
+}
+
+// Runs the clicked lens. Returns false if the hint is not a lens.
+func (cl *CodeLens) OnSelectInlayHint(erow *ERow, ev *ui.TextAreaSelectInlayHintEvent) event.Handled {
+	r, ok := cl.rows[erow]
+	if !ok {
+		return false
+	}
+	entries := erow.Row.TextArea.Drawer.Opt.InlayHints.Entries
+	if ev.HintIndex < 0 || ev.HintIndex >= len(entries) {
+		return false
+	}
+	hint := entries[ev.HintIndex]
+	for _, e := range r.entries {
+		if e.hint != hint {
+			continue
+		}
+		for i, sp := range e.spans {
+			if ev.Offset >= sp[0] && ev.Offset < sp[1] {
+				cl.execute(erow, e.lenses[i])
+				return true
+			}
+		}
+		return true // separators
+	}
+	return false
+}
+
+// Lenses drawer hints, ordered by offset.
+func (cl *CodeLens) hints(erow *ERow) []*drawutil.InlayHint {
+	r, ok := cl.rows[erow]
+	if !ok {
+		return nil
+	}
+	w := make([]*drawutil.InlayHint, 0, len(r.entries))
+	for _, e := range r.entries {
+		w = append(w, e.hint)
+	}
+	return w
+}
+
+//----------
+
+func (cl *CodeLens) schedule(erow *ERow, delay time.Duration) {
+	r := cl.rows[erow]
+	open := func() bool { return cl.rows[erow] == r }
+	r.schedule(cl.ed, erow, delay, 8*time.Second, open, func() lsprotoRowReqFn {
+		return cl.request(erow, r)
+	})
+}
+
+func (cl *CodeLens) request(erow *ERow, r *codeLensRow) lsprotoRowReqFn {
+	// visible range with one page of margin before and after
+	o, n := inlayHintsVisible(erow)
+	rd0 := erow.Row.TextArea.RW()
+	s := max(o-n, rd0.Min())
+	e := min(o+n+n, rd0.Max())
+	r.offset, r.n = s, e-s
+	stale := r.stale
+	r.stale = false
+	filename := erow.Info.Name()
+	lenses := slices.Clone(r.lenses)
+
+	return func(ctx context.Context, rd iorw.ReaderAt) func() {
+		man := cl.ed.LSProtoMan
+		if stale {
+			u, err := man.TextDocumentCodeLens(ctx, filename, rd)
+			if err != nil {
+				return nil // best effort, don't bother the user
+			}
+			lenses = u
+		}
+
+		// resolve the lenses of the visible lines
+		p1, err1 := lsproto.OffsetToPosition(rd, s)
+		p2, err2 := lsproto.OffsetToPosition(rd, e)
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+		for i, lens := range lenses {
+			if lens.Command != nil || lens.Range == nil {
+				continue
+			}
+			if l := lens.Range.Start.Line; l < p1.Line || l > p2.Line {
+				continue
+			}
+			if lens2, err := man.CodeLensResolve(ctx, filename, lens); err == nil {
+				lenses[i] = lens2
+			}
+		}
+
+		entries := codeLensEntries(rd, lenses)
+		return func() {
+			r.lenses = lenses
+			cl.setEntries(erow, r, entries)
+		}
+	}
+}
+
+func (cl *CodeLens) setEntries(erow *ERow, r *codeLensRow, entries []*codeLensEntry) {
+	if len(entries) == 0 && len(r.entries) == 0 {
+		return
+	}
+	r.entries = entries
+	updateDrawerInlayHints(cl.ed, erow)
+}
+
+//----------
+
+// Runs with a busy cursor on the row. Canceled with the row internal cmds (ex: esc key).
+func (cl *CodeLens) execute(erow *ERow, lens *lsproto.CodeLens) {
+	ctx, cancel := erow.newInternalCmdCtx()
+	filename := erow.Info.Name()
+	cl.ed.RunAsyncBusyCursor(erow.Row, func() {
+		defer cancel()
+		ctx2, cancel2 := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel2()
+		if err := cl.ed.LSProtoMan.CodeLensExecute(ctx2, filename, lens); err != nil {
+			cl.ed.Errorf("codelens: %w", err)
+		}
+	})
+}
+
+//----------
+
+const codeLensDelay = time.Second
+
+type codeLensRow struct {
+	lsprotoRowReq
+	stale     bool // lenses need to be requested
+	offset, n int  // last resolved range
+	lenses    []*lsproto.CodeLens
+	entries   []*codeLensEntry
+}
+
+// Lenses of one line, drawn as a single hint.
+type codeLensEntry struct {
+	hint   *drawutil.InlayHint
+	lenses []*lsproto.CodeLens
+	spans  [][2]int // lens title [start,end) in the hint bytes
+}
+
+//----------
+
+// One entry per line, at the line start, indented like the line. The lenses are separated by " | ". Unresolved lenses (no command) are not shown.
+func codeLensEntries(rd iorw.ReaderAt, lenses []*lsproto.CodeLens) []*codeLensEntry {
+	lines := map[int][]*lsproto.CodeLens{}
+	for _, lens := range lenses {
+		if lens.Range == nil || lens.Command == nil {
+			continue
+		}
+		l := lens.Range.Start.Line
+		lines[l] = append(lines[l], lens)
+	}
+
+	w := []*codeLensEntry{}
+	for l, u := range lines {
+		offset, _, err := lsproto.RangeToOffsetLen(rd, &lsproto.Range{Start: lsproto.Position{Line: l}, End: lsproto.Position{Line: l}})
+		if err != nil {
+			continue
+		}
+		sort.SliceStable(u, func(i, j int) bool {
+			return u[i].Range.Start.Character < u[j].Range.Start.Character
+		})
+
+		sb := &strings.Builder{}
+		sb.WriteString(lineIndent(rd, offset))
+		e := &codeLensEntry{lenses: u}
+		for i, lens := range u {
+			if i > 0 {
+				sb.WriteString(" | ")
+			}
+			title := strings.Join(strings.Fields(lens.Command.Title), " ")
+			e.spans = append(e.spans, [2]int{sb.Len(), sb.Len() + len(title)})
+			sb.WriteString(title)
+		}
+		e.hint = &drawutil.InlayHint{Offset: offset, Bytes: []byte(sb.String()), Line: true}
+		w = append(w, e)
+	}
+	sort.Slice(w, func(i, j int) bool {
+		return w[i].hint.Offset < w[j].hint.Offset
+	})
+	return w
+}
+
+func lineIndent(rd iorw.ReaderAt, offset int) string {
+	b, err := rd.ReadFastAt(offset, min(rd.Max()-offset, 256))
+	if err != nil {
+		return ""
+	}
+	k := 0
+	for k < len(b) && (b[k] == ' ' || b[k] == '\t') {
+		k++
+	}
+	return string(b[:k])
+}
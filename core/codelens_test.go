@@ -0,0 +1,33 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+func TestCodeLensEntries(t *testing.T) {
+	s := "package a\n\n\tfunc TestA(t *testing.T) {}\n"
+	rd := iorw.NewBytesReadWriterAt([]byte(s))
+	lens := func(line, char int, title string) *lsproto.CodeLens {
+		r := &lsproto.Range{Start: lsproto.Position{Line: line, Character: char}}
+		cl := &lsproto.CodeLens{Range: r}
+		if title != "" {
+			cl.Command = &lsproto.Command{Title: title}
+		}
+		return cl
+	}
+	lenses := []*lsproto.CodeLens{lens(2, 6, "debug test"), lens(2, 1, "run test"), lens(0, 0, "")}
+	entries := codeLensEntries(rd, lenses)
+	if len(entries) != 1 {
+		t.Fatal(entries)
+	}
+	e := entries[0]
+	if e.hint.Offset != 11 || string(e.hint.Bytes) != "\trun test | debug test" || !e.hint.Line {
+		t.Fatalf("%+v", e.hint)
+	}
+	if e.lenses[0] != lenses[1] || e.spans[1] != [2]int{12, 22} {
+		t.Fatal(e.spans)
+	}
+}
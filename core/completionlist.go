@@ -0,0 +1,303 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/uiutil/event"
+)
+
+// Completion list (lsproto) in the context float box. Navigable with up/down, applied with enter/tab (or a click). Applying a snippet leaves tab-stops that are visited with tab.
+// All state is handled in the UI goroutine.
+type CompletionList struct {
+	ed *Editor
+
+	list struct {
+		erow    *ERow // if not nil, the list is on
+		pending *ERow // row of the request waiting for the results
+		offset  int   // cursor index at the request
+		items   []*lsproto.CompletionItem
+		index   int // selected item
+	}
+	stops struct {
+		erow    *ERow // if not nil, tab-stops are on
+		entries []*lsproto.SnippetTabStop
+		index   int // current tab-stop
+	}
+}
+
+func NewCompletionList(ed *Editor) *CompletionList {
+	return &CompletionList{ed: ed}
+}
+
+//----------
+
+func (cl *CompletionList) IsOn() bool {
+	return cl.list.erow != nil
+}
+
+// Handles the keys while the list or the tab-stops are on. Ctrl+space opens the list.
+func (cl *CompletionList) OnInput(erow *ERow, ev *ui.TextAreaInputEvent) event.Handled {
+	kd, ok := ev.Event.(*event.KeyDown)
+	if !ok {
+		return false
+	}
+	m := kd.Mods.ClearLocks()
+	switch {
+	case m.Is(event.ModCtrl) && kd.KeySym == event.KSymSpace:
+		return event.Handled(cl.Open(erow))
+	case m.IsEmpty():
+		if cl.list.erow == erow {
+			switch kd.KeySym {
+			case event.KSymUp:
+				cl.move(-1)
+				return true
+			case event.KSymDown:
+				cl.move(1)
+				return true
+			case event.KSymPageUp:
+				cl.move(-completionListLines)
+				return true
+			case event.KSymPageDown:
+				cl.move(completionListLines)
+				return true
+			case event.KSymReturn, event.KSymTab:
+				cl.apply(cl.list.index)
+				return true
+			}
+		}
+		if cl.stops.erow == erow && kd.KeySym == event.KSymTab {
+			return event.Handled(cl.nextTabStop())
+		}
+	}
+	return false
+}
+
+// The list (or the pending request) is no longer valid after an edit. Tab-stops are kept in place.
+func (cl *CompletionList) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	if cl.list.erow == erow || cl.list.pending == erow {
+		cl.ed.cancelInfoFloatBox()
+	}
+	if cl.stops.erow == erow && ev.Changed {
+		if !shiftTabStops(cl.stops.entries, ev) {
+			cl.ClearTabStops()
+		}
+	}
+}
+
+// Called on row close.
+func (cl *CompletionList) Close(erow *ERow) {
+	if cl.list.erow == erow || cl.list.pending == erow {
+		cl.ed.cancelInfoFloatBox()
+	}
+	if cl.stops.erow == erow {
+		cl.ClearTabStops()
+	}
+}
+
+func (cl *CompletionList) ClearTabStops() {
+	cl.stops.erow = nil
+	cl.stops.entries = nil
+}
+
+// Called when the float box is canceled.
+func (cl *CompletionList) closeList() {
+	cl.list.erow = nil
+	cl.list.pending = nil
+	cl.list.items = nil
+}
+
+//----------
+
+// Requests the completions at the cursor. Returns false if the row has no lsproto registration.
+func (cl *CompletionList) Open(erow *ERow) bool {
+	if !erow.Info.IsFileButNotDir() {
+		return false
+	}
+	if _, err := cl.ed.LSProtoMan.LangManager(erow.Info.Name()); err != nil {
+		return false
+	}
+	ta := erow.Row.TextArea
+	if ta.Cursor().HaveSelection() {
+		return false
+	}
+
+	ctx := cl.ed.ifbw.NewCtx(erow.ctx) // closes the current list
+	offset := ta.CursorIndex()
+	filename := erow.Info.Name()
+
+	cfb := cl.ed.ifbw.ui()
+	cfb.SetRefPointToTextAreaCursor(ta)
+	cfb.TextArea.ClearPos()
+	cfb.SetStrClearHistory("Loading...")
+	cfb.Show()
+
+	rd, err := erow.ContentCopy()
+	if err != nil {
+		cl.ed.Error(err)
+		return true
+	}
+	cl.list.pending = erow // canceled on write
+
+	cl.ed.RunAsyncBusyCursor(cfb, func() {
+		clist, err := cl.ed.LSProtoMan.TextDocumentCompletion(ctx, filename, rd, offset)
+		cl.ed.UI.RunOnUIGoRoutine(func() {
+			if ctx.Err() != nil {
+				return // canceled (ex: edited while waiting)
+			}
+			cl.list.pending = nil
+			if err != nil {
+				cl.ed.cancelInfoFloatBox()
+				cl.ed.Error(err)
+				return
+			}
+			if len(clist.Items) == 0 {
+				cfb.SetStrClearHistory("0 results")
+				return
+			}
+			cl.list.erow = erow
+			cl.list.offset = offset
+			cl.list.items = clist.Items
+			cl.list.index = 0
+			cl.render()
+		})
+	})
+	return true
+}
+
+//----------
+
+func (cl *CompletionList) move(d int) {
+	n := len(cl.list.items)
+	cl.list.index = max(0, min(n-1, cl.list.index+d))
+	cl.render()
+}
+
+func (cl *CompletionList) render() {
+	items := cl.list.items
+	index := cl.list.index
+
+	// visible window of items around the selected item
+	start := max(0, min(index-completionListLines/2, len(items)-completionListLines))
+	end := min(start+completionListLines, len(items))
+
+	sb := &strings.Builder{}
+	selStart, selEnd := 0, 0
+	for i := start; i < end; i++ {
+		if i == index {
+			selStart = sb.Len()
+		}
+		sb.WriteString(lsproto.CompletionItemString(items[i]))
+		if i == index {
+			selEnd = sb.Len()
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(sb, "[%d/%d]", index+1, len(items))
+
+	// selected item documentation
+	ci := items[index]
+	if doc := strings.TrimSpace(ci.Documentation.String()); doc != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(doc)
+	}
+
+	cfb := cl.ed.ifbw.ui()
+	cfb.TextArea.ClearPos()
+	cfb.SetStrClearHistory(sb.String())
+	cfb.TextArea.Cursor().SetSelection(selStart, selEnd)
+	cfb.Show()
+
+	erow, offset := cl.list.erow, cl.list.offset
+	cl.ed.ifbw.setChoices(func(i int) {
+		if i < end-start {
+			cl.apply2(erow, offset, items, start+i)
+		}
+	})
+}
+
+//----------
+
+func (cl *CompletionList) apply(index int) {
+	erow, offset, items := cl.list.erow, cl.list.offset, cl.list.items
+	cl.ed.cancelInfoFloatBox() // closes the list
+	cl.apply2(erow, offset, items, index)
+}
+
+func (cl *CompletionList) apply2(erow *ERow, offset int, items []*lsproto.CompletionItem, index int) {
+	if index < 0 || index >= len(items) {
+		return
+	}
+	cl.ClearTabStops()
+
+	ta := erow.Row.TextArea
+	edits, stops, err := lsproto.CompletionItemEdits(ta.RW(), items[index], offset)
+	if err != nil {
+		cl.ed.Error(err)
+		return
+	}
+	ta.BeginUndoGroup()
+	err = lsproto.PatchTextEditsRW(ta.RW(), edits)
+	ta.EndUndoGroup()
+	if err != nil {
+		cl.ed.Error(err)
+		return
+	}
+
+	if len(stops) > 1 {
+		cl.stops.erow = erow
+		cl.stops.entries = stops
+	}
+	cl.stops.index = 0
+	cl.selectTabStop(ta, stops[0])
+}
+
+//----------
+
+func (cl *CompletionList) nextTabStop() bool {
+	cl.stops.index++
+	if cl.stops.index >= len(cl.stops.entries) {
+		cl.ClearTabStops()
+		return false
+	}
+	ta := cl.stops.erow.Row.TextArea
+	cl.selectTabStop(ta, cl.stops.entries[cl.stops.index])
+	if cl.stops.index == len(cl.stops.entries)-1 {
+		cl.ClearTabStops() // final position
+	}
+	return true
+}
+
+func (cl *CompletionList) selectTabStop(ta *ui.TextArea, ts *lsproto.SnippetTabStop) {
+	if ts.N > 0 {
+		ta.Cursor().SetSelection(ts.Offset, ts.Offset+ts.N)
+	} else {
+		ta.SetCursorIndex(ts.Offset)
+	}
+	ta.MakeCursorVisible()
+}
+
+//----------
+
+const completionListLines = 10
+
+// Updates the tab-stops offsets after the write. Writes inside a tab-stop change its length. Returns false if a write overlaps a tab-stop boundary.
+func shiftTabStops(stops []*lsproto.SnippetTabStop, ev *iorw.RWEvWrite2) bool {
+	d := ev.In - ev.Dn
+	for _, ts := range stops {
+		switch {
+		case ev.Index+ev.Dn <= ts.Offset && ev.Index < ts.Offset:
+			ts.Offset += d
+		case ev.Index >= ts.Offset && ev.Index+ev.Dn <= ts.Offset+ts.N:
+			ts.N += d
+		case ev.Index >= ts.Offset+ts.N:
+			// after
+		default:
+			return false
+		}
+	}
+	return true
+}
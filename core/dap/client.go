@@ -0,0 +1,241 @@
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Client connection to a debug adapter. Events are handled in order, in a goroutine that is not the read loop (event handlers can make calls).
+type Client struct {
+	rwc     io.ReadWriteCloser
+	onEvent func(*Message)
+
+	events struct { // unbounded, the read loop never blocks on slow handlers
+		sync.Mutex
+		cond   *sync.Cond
+		q      []*Message
+		closed bool
+	}
+	done    chan struct{} // read loop done
+	doneErr error
+
+	wmu sync.Mutex // writes
+	mu  struct {
+		sync.Mutex
+		seq     int
+		pending map[int]chan *Message // request seq -> response
+		closed  bool
+	}
+}
+
+func NewClientTCP(ctx context.Context, addr string, onEvent func(*Message)) (*Client, error) {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientIO(conn, onEvent), nil
+}
+
+func NewClientIO(rwc io.ReadWriteCloser, onEvent func(*Message)) *Client {
+	cli := &Client{rwc: rwc, onEvent: onEvent}
+	cli.mu.pending = map[int]chan *Message{}
+	cli.events.cond = sync.NewCond(&cli.events)
+	cli.done = make(chan struct{})
+	go func() {
+		err := cli.readLoop()
+		cli.Close()
+		cli.closeEvents()
+		cli.doneErr = err
+		close(cli.done)
+	}()
+	go func() {
+		for {
+			msg, ok := cli.nextEvent()
+			if !ok {
+				return
+			}
+			if cli.onEvent != nil {
+				cli.onEvent(msg)
+			}
+		}
+	}()
+	return cli
+}
+
+//----------
+
+func (cli *Client) readLoop() error {
+	br := bufio.NewReader(cli.rwc)
+	for {
+		b, err := readMessage(br)
+		if err != nil {
+			if cli.isClosed() || errors.Is(err, io.EOF) {
+				return nil // no error if done
+			}
+			return err
+		}
+		msg := &Message{}
+		if err := json.Unmarshal(b, msg); err != nil {
+			return fmt.Errorf("dap: decode: %w", err)
+		}
+		switch msg.Type {
+		case "response":
+			cli.mu.Lock()
+			ch, ok := cli.mu.pending[msg.RequestSeq]
+			delete(cli.mu.pending, msg.RequestSeq)
+			cli.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case "event":
+			cli.pushEvent(msg)
+		case "request":
+			// reverse requests (ex: runInTerminal) are not supported
+			go func() {
+				_ = cli.reply(msg, false, "not supported", nil)
+			}()
+		}
+	}
+}
+
+func (cli *Client) pushEvent(msg *Message) {
+	cli.events.Lock()
+	defer cli.events.Unlock()
+	cli.events.q = append(cli.events.q, msg)
+	cli.events.cond.Signal()
+}
+
+func (cli *Client) closeEvents() {
+	cli.events.Lock()
+	defer cli.events.Unlock()
+	cli.events.closed = true
+	cli.events.cond.Signal()
+}
+
+// Waits for the next event. Not ok after the read loop is done and all events were handled.
+func (cli *Client) nextEvent() (*Message, bool) {
+	cli.events.Lock()
+	defer cli.events.Unlock()
+	for len(cli.events.q) == 0 && !cli.events.closed {
+		cli.events.cond.Wait()
+	}
+	if len(cli.events.q) == 0 {
+		return nil, false
+	}
+	msg := cli.events.q[0]
+	cli.events.q[0] = nil
+	cli.events.q = cli.events.q[1:]
+	return msg, true
+}
+
+//----------
+
+func (cli *Client) Call(ctx context.Context, command string, args any, body any) error {
+	ch, err := cli.Send(command, args)
+	if err != nil {
+		return err
+	}
+	return cli.Wait2(ctx, ch, body)
+}
+
+// Sends the request without waiting for the response (ex: "launch" only replies after the configuration is done). Use Wait2 to get the response.
+func (cli *Client) Send(command string, args any) (<-chan *Message, error) {
+	cli.mu.Lock()
+	if cli.mu.closed {
+		cli.mu.Unlock()
+		return nil, fmt.Errorf("dap: connection closed")
+	}
+	cli.mu.seq++
+	seq := cli.mu.seq
+	ch := make(chan *Message, 1)
+	cli.mu.pending[seq] = ch
+	cli.mu.Unlock()
+
+	req := &RequestMessage{Command: command, Arguments: args}
+	req.Seq = seq
+	req.Type = "request"
+	if err := cli.write(req); err != nil {
+		cli.mu.Lock()
+		delete(cli.mu.pending, seq)
+		cli.mu.Unlock()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Waits for the response of Send, and decodes the body (if not nil).
+func (cli *Client) Wait2(ctx context.Context, ch <-chan *Message, body any) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case msg, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("dap: connection closed")
+		}
+		if err := msg.Error(); err != nil {
+			return err
+		}
+		if body != nil && len(msg.Body) > 0 {
+			return json.Unmarshal(msg.Body, body)
+		}
+		return nil
+	}
+}
+
+func (cli *Client) reply(req *Message, success bool, message string, body any) error {
+	resp := &ResponseMessage{RequestSeq: req.Seq, Success: success, Command: req.Command, Message: message, Body: body}
+	resp.Type = "response"
+	cli.mu.Lock()
+	cli.mu.seq++
+	resp.Seq = cli.mu.seq
+	cli.mu.Unlock()
+	return cli.write(resp)
+}
+
+func (cli *Client) write(v any) error {
+	cli.wmu.Lock()
+	defer cli.wmu.Unlock()
+	return writeMessage(cli.rwc, v)
+}
+
+//----------
+
+// Pending calls get a "connection closed" error.
+func (cli *Client) Close() error {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+	if cli.mu.closed {
+		return nil
+	}
+	cli.mu.closed = true
+	for seq, ch := range cli.mu.pending {
+		close(ch)
+		delete(cli.mu.pending, seq)
+	}
+	return cli.rwc.Close()
+}
+
+func (cli *Client) isClosed() bool {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+	return cli.mu.closed
+}
+
+// Waits for the read loop to end.
+func (cli *Client) Wait() error {
+	<-cli.done
+	return cli.doneErr
+}
+
+// Closed when the connection ends.
+func (cli *Client) Done() <-chan struct{} {
+	return cli.done
+}
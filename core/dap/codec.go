@@ -0,0 +1,65 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Messages have a content-length header followed by the json body (same base protocol as lsp).
+
+func readMessage(br *bufio.Reader) ([]byte, error) {
+	length := 0
+	headersSize := 0
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		headersSize += len(line)
+		if headersSize > 10*1024 {
+			return nil, errors.New("headers too long")
+		}
+
+		// header finished (empty line)
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		// header line
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+		if strings.EqualFold(name, "content-length") {
+			l, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+			if err != nil || l <= 0 {
+				return nil, fmt.Errorf("invalid content-length: %v", value)
+			}
+			length = int(l)
+		}
+	}
+	if length == 0 {
+		return nil, fmt.Errorf("missing content-length")
+	}
+	b := make([]byte, length)
+	_, err := io.ReadFull(br, b)
+	return b, err
+}
+
+func writeMessage(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h := fmt.Sprintf("Content-Length: %v\r\n\r\n", len(b))
+	buf := make([]byte, 0, len(h)+len(b))
+	buf = append(buf, h...)
+	buf = append(buf, b...)
+	_, err = w.Write(buf)
+	return err
+}
@@ -0,0 +1,438 @@
+package dap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Notes:
+// - Manager has the registrations, the breakpoints, and runs one Session at a time
+// - Session runs the debug adapter process and its Client
+// - Client handles the client connection to the debug adapter
+type Manager struct {
+	regs  []*Registration
+	msgFn func(string)
+	evFn  func(*ManagerEvent) // called from a non-ui goroutine
+
+	mu struct {
+		sync.Mutex
+		sess *Session
+		bps  map[string][]int // filename -> sorted lines
+		stop *StopLocation
+	}
+
+	adapterW io.Writer // test purposes only
+}
+
+func NewManager(msgFn func(string), evFn func(*ManagerEvent)) *Manager {
+	man := &Manager{msgFn: msgFn, evFn: evFn}
+	man.mu.bps = map[string][]int{}
+	return man
+}
+
+//----------
+
+func (man *Manager) Error(err error) {
+	man.Message(fmt.Sprintf("error: %v", err))
+}
+
+func (man *Manager) Message(s string) {
+	if man.msgFn != nil {
+		man.msgFn(s)
+	}
+}
+
+func (man *Manager) event(ev *ManagerEvent) {
+	if man.evFn != nil {
+		man.evFn(ev)
+	}
+}
+
+//----------
+
+func (man *Manager) Register(reg *Registration) error {
+	// replace if already exists
+	for i, reg2 := range man.regs {
+		if reg2.Language == reg.Language {
+			man.regs[i] = reg
+			return nil
+		}
+	}
+	man.regs = append(man.regs, reg)
+	return nil
+}
+
+func (man *Manager) Registration(filename string) (*Registration, error) {
+	for _, reg := range man.regs {
+		if reg.hasExt(filename) {
+			return reg, nil
+		}
+	}
+	return nil, fmt.Errorf("no dap registration for file: %q", filename)
+}
+
+//----------
+
+// Starts a debug session for the given file (the registration launch arguments are filled with it).
+func (man *Manager) Start(ctx context.Context, filename string) error {
+	reg, err := man.Registration(filename)
+	if err != nil {
+		return err
+	}
+
+	man.mu.Lock()
+	if man.mu.sess != nil {
+		man.mu.Unlock()
+		return errors.New("debug session already running")
+	}
+	bps := map[string][]int{}
+	for k, v := range man.mu.bps {
+		bps[k] = slices.Clone(v)
+	}
+	man.mu.Unlock()
+
+	// session outlives the given ctx
+	sess, err := startSession(context.Background(), reg, man.adapterW, man.onEvent)
+	if err != nil {
+		return err
+	}
+
+	man.mu.Lock()
+	if man.mu.sess != nil {
+		man.mu.Unlock()
+		_ = sess.stop()
+		return errors.New("debug session already running")
+	}
+	man.mu.sess = sess
+	man.mu.Unlock()
+
+	go func() {
+		<-sess.cli.Done()
+		man.endSession(sess)
+	}()
+
+	if err := sess.launch(ctx, filename, bps); err != nil {
+		man.endSession(sess)
+		_ = sess.stop()
+		return err
+	}
+	return nil
+}
+
+func (man *Manager) Stop() error {
+	man.mu.Lock()
+	sess := man.mu.sess
+	man.mu.Unlock()
+	if sess == nil {
+		return nil
+	}
+	man.endSession(sess)
+	return sess.stop()
+}
+
+func (man *Manager) Running() bool {
+	man.mu.Lock()
+	defer man.mu.Unlock()
+	return man.mu.sess != nil
+}
+
+func (man *Manager) endSession(sess *Session) {
+	man.mu.Lock()
+	if man.mu.sess != sess {
+		man.mu.Unlock()
+		return
+	}
+	man.mu.sess = nil
+	man.mu.stop = nil
+	man.mu.Unlock()
+	man.event(&ManagerEvent{Type: ManagerEventTerminated})
+}
+
+//----------
+
+// Returns true if the breakpoint was added, false if removed. Lines start at 1.
+func (man *Manager) ToggleBreakpoint(ctx context.Context, filename string, line int) (bool, error) {
+	man.mu.Lock()
+	lines := man.mu.bps[filename]
+	i, found := slices.BinarySearch(lines, line)
+	if found {
+		lines = slices.Delete(lines, i, i+1)
+	} else {
+		lines = slices.Insert(lines, i, line)
+	}
+	if len(lines) == 0 {
+		delete(man.mu.bps, filename)
+	} else {
+		man.mu.bps[filename] = lines
+	}
+	lines = slices.Clone(lines)
+	sess := man.mu.sess
+	man.mu.Unlock()
+
+	if sess != nil {
+		if err := sess.setBreakpoints(ctx, filename, lines); err != nil {
+			return !found, err
+		}
+	}
+	return !found, nil
+}
+
+func (man *Manager) Breakpoints(filename string) []int {
+	man.mu.Lock()
+	defer man.mu.Unlock()
+	return slices.Clone(man.mu.bps[filename])
+}
+
+// Updates the breakpoints lines of a file (ex: after lines were inserted). Not sent to a running session.
+func (man *Manager) SetBreakpoints(filename string, lines []int) {
+	man.mu.Lock()
+	defer man.mu.Unlock()
+	if len(lines) == 0 {
+		delete(man.mu.bps, filename)
+		return
+	}
+	man.mu.bps[filename] = slices.Clone(lines)
+}
+
+//----------
+
+func (man *Manager) Continue(ctx context.Context) error {
+	return man.resume(ctx, "continue")
+}
+func (man *Manager) Next(ctx context.Context) error {
+	return man.resume(ctx, "next")
+}
+func (man *Manager) StepIn(ctx context.Context) error {
+	return man.resume(ctx, "stepIn")
+}
+func (man *Manager) StepOut(ctx context.Context) error {
+	return man.resume(ctx, "stepOut")
+}
+
+func (man *Manager) resume(ctx context.Context, command string) error {
+	sess, stop, err := man.stoppedSession()
+	if err != nil {
+		return err
+	}
+
+	// the adapter doesn't send a "continued" event for these requests
+	man.mu.Lock()
+	if man.mu.stop == stop {
+		man.mu.stop = nil
+	}
+	man.mu.Unlock()
+	man.event(&ManagerEvent{Type: ManagerEventContinued})
+
+	args := &ThreadArguments{ThreadId: stop.ThreadId}
+	return sess.cli.Call(ctx, command, args, nil)
+}
+
+// Current stop location, nil if the program is not stopped.
+func (man *Manager) StopLocation() *StopLocation {
+	man.mu.Lock()
+	defer man.mu.Unlock()
+	return man.mu.stop
+}
+
+func (man *Manager) stoppedSession() (*Session, *StopLocation, error) {
+	man.mu.Lock()
+	defer man.mu.Unlock()
+	if man.mu.sess == nil {
+		return nil, nil, errors.New("no debug session")
+	}
+	if man.mu.stop == nil {
+		return nil, nil, errors.New("program is not stopped")
+	}
+	return man.mu.sess, man.mu.stop, nil
+}
+
+//----------
+
+// Stack of the stopped thread.
+func (man *Manager) StackTrace(ctx context.Context) ([]*StackFrame, error) {
+	sess, stop, err := man.stoppedSession()
+	if err != nil {
+		return nil, err
+	}
+	return sess.stackTrace(ctx, stop.ThreadId, 0)
+}
+
+// Variables of the stopped frame, by scope. Variables with children are expanded one level. Expensive scopes (ex: globals) are not fetched.
+func (man *Manager) Variables(ctx context.Context) ([]*ScopeVariables, error) {
+	sess, stop, err := man.stoppedSession()
+	if err != nil {
+		return nil, err
+	}
+	body := ScopesResponseBody{}
+	if err := sess.cli.Call(ctx, "scopes", &ScopesArguments{FrameId: stop.FrameId}, &body); err != nil {
+		return nil, err
+	}
+	res := []*ScopeVariables{}
+	for _, sc := range body.Scopes {
+		sv := &ScopeVariables{Scope: sc}
+		res = append(res, sv)
+		if sc.Expensive {
+			continue
+		}
+		vars, err := sess.variables(ctx, sc.VariablesReference, 1)
+		if err != nil {
+			return nil, err
+		}
+		sv.Vars = vars
+	}
+	return res, nil
+}
+
+//----------
+
+func (man *Manager) onEvent(sess *Session, msg *Message) {
+	switch msg.Event {
+	case "stopped":
+		body := StoppedEventBody{}
+		if err := unmarshalBody(msg, &body); err != nil {
+			man.Error(err)
+			return
+		}
+		stop, err := man.stopLocation(sess, &body)
+		if err != nil {
+			man.Error(err)
+			return
+		}
+		man.mu.Lock()
+		if man.mu.sess != sess {
+			man.mu.Unlock()
+			return
+		}
+		man.mu.stop = stop
+		man.mu.Unlock()
+		man.event(&ManagerEvent{Type: ManagerEventStopped, Location: stop})
+	case "continued":
+		man.mu.Lock()
+		man.mu.stop = nil
+		man.mu.Unlock()
+		man.event(&ManagerEvent{Type: ManagerEventContinued})
+	case "output":
+		body := OutputEventBody{}
+		if err := unmarshalBody(msg, &body); err != nil {
+			man.Error(err)
+			return
+		}
+		if body.Category == "telemetry" {
+			return
+		}
+		man.event(&ManagerEvent{Type: ManagerEventOutput, Output: body.Output})
+	case "exited":
+		body := ExitedEventBody{}
+		if err := unmarshalBody(msg, &body); err == nil {
+			man.Message(fmt.Sprintf("dap: program exited with code %v", body.ExitCode))
+		}
+	case "terminated":
+		man.endSession(sess)
+		go func() { _ = sess.stop() }()
+	}
+}
+
+func (man *Manager) stopLocation(sess *Session, body *StoppedEventBody) (*StopLocation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	threadId := body.ThreadId
+	if threadId == 0 {
+		tbody := ThreadsResponseBody{}
+		if err := sess.cli.Call(ctx, "threads", nil, &tbody); err != nil {
+			return nil, err
+		}
+		if len(tbody.Threads) == 0 {
+			return nil, errors.New("stopped: no threads")
+		}
+		threadId = tbody.Threads[0].Id
+	}
+
+	frames, err := sess.stackTrace(ctx, threadId, 1)
+	if err != nil {
+		return nil, err
+	}
+	stop := &StopLocation{Reason: body.Reason, ThreadId: threadId}
+	if len(frames) > 0 {
+		f := frames[0]
+		stop.FrameId = f.Id
+		stop.Line = f.Line
+		stop.Column = f.Column
+		if f.Source != nil {
+			stop.Filename = f.Source.Path
+		}
+	}
+	return stop, nil
+}
+
+//----------
+
+func (sess *Session) stackTrace(ctx context.Context, threadId int, levels int) ([]*StackFrame, error) {
+	args := &StackTraceArguments{ThreadId: threadId, Levels: levels}
+	body := StackTraceResponseBody{}
+	if err := sess.cli.Call(ctx, "stackTrace", args, &body); err != nil {
+		return nil, err
+	}
+	return body.StackFrames, nil
+}
+
+func (sess *Session) variables(ctx context.Context, ref int, depth int) ([]*VariableNode, error) {
+	body := VariablesResponseBody{}
+	if err := sess.cli.Call(ctx, "variables", &VariablesArguments{VariablesReference: ref}, &body); err != nil {
+		return nil, err
+	}
+	res := []*VariableNode{}
+	for _, v := range body.Variables {
+		vn := &VariableNode{Variable: v}
+		res = append(res, vn)
+		if v.VariablesReference > 0 && depth > 0 {
+			u, err := sess.variables(ctx, v.VariablesReference, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			vn.Children = u
+		}
+	}
+	return res, nil
+}
+
+//----------
+
+type ManagerEvent struct {
+	Type     ManagerEventType
+	Location *StopLocation // stopped
+	Output   string        // output
+}
+
+type ManagerEventType int
+
+const (
+	ManagerEventStopped ManagerEventType = iota
+	ManagerEventContinued
+	ManagerEventOutput
+	ManagerEventTerminated
+)
+
+type StopLocation struct {
+	Filename string
+	Line     int // starts at 1
+	Column   int // starts at 1
+	Reason   string
+	ThreadId int
+	FrameId  int
+}
+
+type ScopeVariables struct {
+	Scope *Scope
+	Vars  []*VariableNode
+}
+
+type VariableNode struct {
+	*Variable
+	Children []*VariableNode
+}
@@ -0,0 +1,279 @@
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary is also the fake debug adapter
+	if mode := os.Getenv("DAP_FAKE_ADAPTER"); mode != "" {
+		runFakeAdapter(os.Stdin, os.Stdout, mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestManager1(t *testing.T) {
+	t.Setenv("DAP_FAKE_ADAPTER", "1")
+
+	evs := make(chan *ManagerEvent, 16)
+	man := NewManager(func(s string) { t.Log(s) }, func(ev *ManagerEvent) { evs <- ev })
+	reg := &Registration{Language: "fake", Exts: []string{".go"}, Network: "stdio", Cmd: os.Args[0]}
+	if err := man.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	defer man.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filename := "/fake/main.go"
+	waitEv := func(typ ManagerEventType) *ManagerEvent {
+		t.Helper()
+		for {
+			select {
+			case ev := <-evs:
+				if ev.Type == typ {
+					return ev
+				}
+			case <-ctx.Done():
+				t.Fatalf("timeout waiting for event %v", typ)
+			}
+		}
+	}
+
+	if added, err := man.ToggleBreakpoint(ctx, filename, 3); err != nil || !added {
+		t.Fatal(added, err)
+	}
+	if err := man.Start(ctx, filename); err != nil {
+		t.Fatal(err)
+	}
+
+	ev := waitEv(ManagerEventStopped)
+	if ev.Location.Filename != filename || ev.Location.Line != 3 || ev.Location.Reason != "breakpoint" {
+		t.Fatalf("%+v", ev.Location)
+	}
+
+	if err := man.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ev = waitEv(ManagerEventStopped)
+	if ev.Location.Line != 4 {
+		t.Fatalf("%+v", ev.Location)
+	}
+
+	frames, err := man.StackTrace(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := StackFramesToString(frames, "/fake")
+	exp := "\tmain.go:4:1: main.main\n\tmain.go:10:1: main.run\n"
+	if s != exp {
+		t.Fatalf("%q", s)
+	}
+
+	scopes, err := man.Variables(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = VariablesToString(scopes)
+	exp = "Locals:\n\ta int = 4\n\tp point = {...}\n\t\tx int = 1\nGlobals:\n\t(not fetched)\n"
+	if s != exp {
+		t.Fatalf("%q", s)
+	}
+
+	if err := man.Continue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitEv(ManagerEventTerminated)
+	if man.Running() {
+		t.Fatal("still running")
+	}
+}
+
+func TestManagerLaunchError1(t *testing.T) {
+	t.Setenv("DAP_FAKE_ADAPTER", "launcherror")
+
+	man := NewManager(func(s string) { t.Log(s) }, func(ev *ManagerEvent) {})
+	reg := &Registration{Language: "fake", Exts: []string{".go"}, Network: "stdio", Cmd: os.Args[0]}
+	if err := man.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	defer man.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := man.Start(ctx, "/fake/main.go")
+	if err == nil || !strings.Contains(err.Error(), "build failed") {
+		t.Fatal(err)
+	}
+	if man.Running() {
+		t.Fatal("still running")
+	}
+}
+
+func TestClientEvents1(t *testing.T) {
+	// many events while an event handler waits for a response
+	r1, w1 := io.Pipe() // client -> adapter
+	r2, w2 := io.Pipe() // adapter -> client
+	go func() {
+		br := bufio.NewReader(r1)
+		_ = writeMessage(w2, map[string]any{"seq": 1, "type": "event", "event": "stopped"})
+		b, err := readMessage(br)
+		if err != nil {
+			return
+		}
+		req := &Message{}
+		_ = json.Unmarshal(b, req)
+		for i := 0; i < 200; i++ {
+			_ = writeMessage(w2, map[string]any{"seq": 2 + i, "type": "event", "event": "output"})
+		}
+		_ = writeMessage(w2, map[string]any{"seq": 300, "type": "response", "request_seq": req.Seq, "command": req.Command, "success": true})
+	}()
+
+	type rwc struct {
+		io.Reader
+		io.WriteCloser
+	}
+	done := make(chan error, 1)
+	clis := make(chan *Client, 1)
+	cli := NewClientIO(&rwc{r2, w1}, func(msg *Message) {
+		if msg.Event == "stopped" {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			done <- (<-clis).Call(ctx, "threads", nil, nil)
+		}
+	})
+	defer cli.Close()
+	clis <- cli
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+//----------
+
+// Simulates a program with lines 1..5 in a single file. Mode "launcherror" fails the launch (ex: build error) without sending the initialized event.
+func runFakeAdapter(r io.Reader, w io.Writer, mode string) {
+	br := bufio.NewReader(r)
+	seq := 0
+	send := func(v map[string]any) {
+		seq++
+		v["seq"] = seq
+		_ = writeMessage(w, v)
+	}
+	respond := func(req *Message, body any) {
+		send(map[string]any{"type": "response", "request_seq": req.Seq, "command": req.Command, "success": true, "body": body})
+	}
+	event := func(name string, body any) {
+		send(map[string]any{"type": "event", "event": name, "body": body})
+	}
+
+	filename := ""
+	bps := []int{}
+	line := 0
+	var launchReq *Message
+	run := func(reason string, fromLine int, stopAtBps bool) {
+		for l := fromLine; l <= 5; l++ {
+			if !stopAtBps || slices.Contains(bps, l) {
+				line = l
+				event("stopped", map[string]any{"reason": reason, "threadId": 1})
+				return
+			}
+		}
+		event("exited", map[string]any{"exitCode": 0})
+		event("terminated", nil)
+	}
+
+	for {
+		b, err := readMessage(br)
+		if err != nil {
+			return
+		}
+		req := &Message{}
+		if err := json.Unmarshal(b, req); err != nil {
+			return
+		}
+		switch req.Command {
+		case "initialize":
+			respond(req, map[string]any{"supportsConfigurationDoneRequest": true})
+			if mode != "launcherror" {
+				event("initialized", nil)
+			}
+		case "launch":
+			if mode == "launcherror" {
+				send(map[string]any{"type": "response", "request_seq": req.Seq, "command": req.Command, "success": false, "message": "build failed"})
+				continue
+			}
+			launchReq = req // replied after the configuration is done
+		case "setBreakpoints":
+			args := SetBreakpointsArguments{}
+			_ = json.Unmarshal(req.Arguments, &args)
+			filename = args.Source.Path
+			bps = []int{}
+			for _, bp := range args.Breakpoints {
+				bps = append(bps, bp.Line)
+			}
+			respond(req, map[string]any{"breakpoints": []any{}})
+		case "configurationDone":
+			respond(req, nil)
+			if launchReq != nil {
+				respond(launchReq, nil)
+			}
+			run("breakpoint", 1, true)
+		case "next", "stepIn", "stepOut":
+			respond(req, nil)
+			run("step", line+1, false)
+		case "continue":
+			respond(req, map[string]any{"allThreadsContinued": true})
+			run("breakpoint", line+1, true)
+		case "threads":
+			respond(req, map[string]any{"threads": []any{map[string]any{"id": 1, "name": "main"}}})
+		case "stackTrace":
+			args := StackTraceArguments{}
+			_ = json.Unmarshal(req.Arguments, &args)
+			src := map[string]any{"path": filename}
+			frames := []any{
+				map[string]any{"id": 1, "name": "main.main", "source": src, "line": line, "column": 1},
+				map[string]any{"id": 2, "name": "main.run", "source": src, "line": 10, "column": 1},
+			}
+			if args.Levels > 0 && args.Levels < len(frames) {
+				frames = frames[:args.Levels]
+			}
+			respond(req, map[string]any{"stackFrames": frames})
+		case "scopes":
+			respond(req, map[string]any{"scopes": []any{
+				map[string]any{"name": "Locals", "variablesReference": 10},
+				map[string]any{"name": "Globals", "variablesReference": 11, "expensive": true},
+			}})
+		case "variables":
+			args := VariablesArguments{}
+			_ = json.Unmarshal(req.Arguments, &args)
+			vars := []any{}
+			switch args.VariablesReference {
+			case 10:
+				vars = []any{
+					map[string]any{"name": "a", "type": "int", "value": strconv.Itoa(line), "variablesReference": 0},
+					map[string]any{"name": "p", "type": "point", "value": "{...}", "variablesReference": 12},
+				}
+			case 12:
+				vars = []any{map[string]any{"name": "x", "type": "int", "value": "1", "variablesReference": 0}}
+			}
+			respond(req, map[string]any{"variables": vars})
+		case "disconnect":
+			respond(req, nil)
+			return
+		default:
+			send(map[string]any{"type": "response", "request_seq": req.Seq, "command": req.Command, "success": false, "message": "unsupported"})
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// https://microsoft.github.io/debug-adapter-protocol/specification
+
+type ProtocolMessage struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"` // {request,response,event}
+}
+
+type RequestMessage struct {
+	ProtocolMessage
+	Command   string `json:"command"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+type ResponseMessage struct {
+	ProtocolMessage
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type EventMessage struct {
+	ProtocolMessage
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// Received message: a response, an event, or a request from the adapter ("reverse request").
+type Message struct {
+	ProtocolMessage
+	Command    string          `json:"command,omitempty"`     // request, response
+	Arguments  json.RawMessage `json:"arguments,omitempty"`   // request
+	RequestSeq int             `json:"request_seq,omitempty"` // response
+	Success    bool            `json:"success,omitempty"`     // response
+	Message    string          `json:"message,omitempty"`     // response
+	Event      string          `json:"event,omitempty"`       // event
+	Body       json.RawMessage `json:"body,omitempty"`        // response, event
+}
+
+func (msg *Message) Error() error {
+	if msg.Success {
+		return nil
+	}
+	if msg.Message != "" {
+		return fmt.Errorf("%v: %v", msg.Command, msg.Message)
+	}
+	return fmt.Errorf("%v: failed", msg.Command)
+}
+
+//----------
+
+type InitializeRequestArguments struct {
+	ClientId        string `json:"clientID"`
+	ClientName      string `json:"clientName"`
+	AdapterId       string `json:"adapterID"`
+	LinesStartAt1   bool   `json:"linesStartAt1"`
+	ColumnsStartAt1 bool   `json:"columnsStartAt1"`
+	PathFormat      string `json:"pathFormat"`
+}
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest,omitempty"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest,omitempty"`
+}
+
+type DisconnectArguments struct {
+	TerminateDebuggee bool `json:"terminateDebuggee"`
+}
+
+//----------
+
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type SetBreakpointsArguments struct {
+	Source      Source              `json:"source"`
+	Breakpoints []*SourceBreakpoint `json:"breakpoints"`
+}
+type SourceBreakpoint struct {
+	Line int `json:"line"`
+}
+type SetBreakpointsResponseBody struct {
+	Breakpoints []*Breakpoint `json:"breakpoints"`
+}
+type Breakpoint struct {
+	Id       int    `json:"id,omitempty"`
+	Verified bool   `json:"verified"`
+	Message  string `json:"message,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+//----------
+
+// Used in continue/next/stepIn/stepOut.
+type ThreadArguments struct {
+	ThreadId int `json:"threadId"`
+}
+
+type StackTraceArguments struct {
+	ThreadId   int `json:"threadId"`
+	StartFrame int `json:"startFrame,omitempty"`
+	Levels     int `json:"levels,omitempty"`
+}
+type StackTraceResponseBody struct {
+	StackFrames []*StackFrame `json:"stackFrames"`
+	TotalFrames int           `json:"totalFrames,omitempty"`
+}
+type StackFrame struct {
+	Id     int     `json:"id"`
+	Name   string  `json:"name"`
+	Source *Source `json:"source,omitempty"`
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+}
+
+type ScopesArguments struct {
+	FrameId int `json:"frameId"`
+}
+type ScopesResponseBody struct {
+	Scopes []*Scope `json:"scopes"`
+}
+type Scope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive,omitempty"`
+}
+
+type VariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+type VariablesResponseBody struct {
+	Variables []*Variable `json:"variables"`
+}
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"` // >0 if it has children
+}
+
+//----------
+
+type StoppedEventBody struct {
+	Reason            string `json:"reason"` // ex: step, breakpoint, exception, pause
+	Description       string `json:"description,omitempty"`
+	ThreadId          int    `json:"threadId,omitempty"`
+	AllThreadsStopped bool   `json:"allThreadsStopped,omitempty"`
+	Text              string `json:"text,omitempty"`
+}
+type OutputEventBody struct {
+	Category string `json:"category,omitempty"` // ex: console, stdout, stderr, telemetry
+	Output   string `json:"output"`
+}
+type ExitedEventBody struct {
+	ExitCode int `json:"exitCode"`
+}
+
+type ThreadsResponseBody struct {
+	Threads []*Thread `json:"threads"`
+}
+type Thread struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,90 @@
+package dap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+type Registration struct {
+	Language string         `json:"language"`
+	Exts     []string       `json:"extensions"`
+	Network  string         `json:"transport"` // {stdio,tcp}
+	Cmd      string         `json:"command"`   // template values: {.Addr,.Host,.Port}
+	Launch   map[string]any `json:"launch"`    // "launch" request arguments; string values are templates with values: {.File,.Dir}
+}
+
+func (reg *Registration) String() string {
+	exts := strings.Join(reg.Exts, " ")
+	if len(reg.Exts) >= 2 {
+		exts = fmt.Sprintf("%q", exts)
+	}
+	u := []string{reg.Language, exts, reg.Network, fmt.Sprintf("%q", reg.Cmd)}
+	if len(reg.Launch) > 0 {
+		b, err := json.Marshal(reg.Launch)
+		if err == nil {
+			u = append(u, string(b))
+		}
+	}
+	return strings.Join(u, ",")
+}
+
+func (reg *Registration) hasExt(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range reg.Exts {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+//----------
+
+// Launch arguments with the templates filled for the given file.
+func (reg *Registration) launchArgs(filename string) (map[string]any, error) {
+	type tdata struct {
+		File string
+		Dir  string
+	}
+	data := &tdata{File: filename, Dir: filepath.Dir(filename)}
+
+	args := map[string]any{}
+	for k, v := range reg.Launch {
+		s, ok := v.(string)
+		if !ok {
+			args[k] = v
+			continue
+		}
+		tmpl, err := template.New("").Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("launch arg %q: %w", k, err)
+		}
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("launch arg %q: %w", k, err)
+		}
+		args[k] = buf.String()
+	}
+	return args, nil
+}
+
+//----------
+
+func DefaultRegistrations() []*Registration {
+	return []*Registration{
+		{
+			Language: "go",
+			Exts:     []string{".go"},
+			Network:  "tcp",
+			Cmd:      "dlv dap --listen={{.Addr}}",
+			Launch: map[string]any{
+				"mode":    "debug",
+				"program": "{{.Dir}}",
+			},
+		},
+	}
+}
@@ -0,0 +1,262 @@
+package dap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/friedelschoen/editor/util/ctxutil"
+	"github.com/friedelschoen/editor/util/iout"
+	"github.com/friedelschoen/editor/util/osutil"
+)
+
+// Session runs a debug adapter process and its client connection.
+type Session struct {
+	reg    *Registration
+	cli    *Client
+	cmd    osutil.CmdI
+	cancel context.CancelFunc
+
+	caps        Capabilities
+	initialized chan struct{} // "initialized" event received
+}
+
+func startSession(ctx context.Context, reg *Registration, outw io.Writer, onEvent func(*Session, *Message)) (*Session, error) {
+	ctx2, cancel := context.WithCancel(ctx)
+	sess := &Session{reg: reg, cancel: cancel}
+	sess.initialized = make(chan struct{})
+
+	onEvent2 := func(msg *Message) {
+		if msg.Event == "initialized" {
+			select {
+			case <-sess.initialized:
+			default:
+				close(sess.initialized)
+			}
+			return
+		}
+		onEvent(sess, msg)
+	}
+
+	var err error
+	switch reg.Network {
+	case "tcp":
+		err = sess.startTCP(ctx2, outw, onEvent2)
+	case "stdio":
+		err = sess.startStdio(ctx2, outw, onEvent2)
+	default:
+		err = fmt.Errorf("unexpected network: %v", reg.Network)
+	}
+	if err != nil {
+		cancel()
+		_ = sess.Wait()
+		return nil, err
+	}
+	return sess, nil
+}
+
+//----------
+
+func (sess *Session) startTCP(ctx context.Context, outw io.Writer, onEvent func(*Message)) error {
+	// multiple editors can have multiple adapters, need unique port
+	port, err := osutil.GetFreeTcpPort()
+	if err != nil {
+		return err
+	}
+	cmd, addr, err := cmdTemplate(sess.reg.Cmd, "127.0.0.1", port)
+	if err != nil {
+		return err
+	}
+
+	sess.cmd = newCmd(ctx, cmd)
+	if outw != nil {
+		sess.cmd.Cmd().Stdout = outw
+		sess.cmd.Cmd().Stderr = outw
+	}
+	if err := sess.cmd.Start(); err != nil {
+		sess.cmd = nil
+		return err
+	}
+
+	// client connect with retries (the adapter might take a while to listen)
+	ctx2, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	fn := func() error {
+		cli, err := NewClientTCP(ctx2, addr, onEvent)
+		if err != nil {
+			return err
+		}
+		sess.cli = cli
+		return nil
+	}
+	lateFn := func(err error) {
+		if err == nil && sess.cli != nil {
+			_ = sess.cli.Close()
+		}
+	}
+	return ctxutil.Retry(ctx2, 300*time.Millisecond, "dapclienttcp", fn, lateFn)
+}
+
+func (sess *Session) startStdio(ctx context.Context, outw io.Writer, onEvent func(*Message)) error {
+	sess.cmd = newCmd(ctx, sess.reg.Cmd)
+
+	pr1, pw1 := io.Pipe()
+	pr2, pw2 := io.Pipe()
+	sess.cmd.Cmd().Stdin = pr1
+	sess.cmd.Cmd().Stdout = pw2
+	sess.cmd.Cmd().Stderr = outw
+
+	rwc := &rwc{ReadCloser: pr2, WriteCloser: pw1}
+	// ensure pipe close in case of error after start()
+	sess.cmd = osutil.NewOnWaitDoneCmd(sess.cmd, func(err error) {
+		_ = rwc.Close()
+	})
+	if err := sess.cmd.Start(); err != nil {
+		sess.cmd = nil
+		_ = rwc.Close()
+		return err
+	}
+	sess.cli = NewClientIO(rwc, onEvent)
+	return nil
+}
+
+//----------
+
+// Initializes the adapter and launches the program. The breakpoints (filename -> lines) are set before the program runs.
+func (sess *Session) launch(ctx context.Context, filename string, bps map[string][]int) error {
+	args := &InitializeRequestArguments{
+		ClientId:        "editor",
+		ClientName:      "editor",
+		AdapterId:       sess.reg.Language,
+		LinesStartAt1:   true,
+		ColumnsStartAt1: true,
+		PathFormat:      "path",
+	}
+	if err := sess.cli.Call(ctx, "initialize", args, &sess.caps); err != nil {
+		return err
+	}
+
+	largs, err := sess.reg.launchArgs(filename)
+	if err != nil {
+		return err
+	}
+	// the launch response might only arrive after the configuration is done
+	ch, err := sess.cli.Send("launch", largs)
+	if err != nil {
+		return err
+	}
+
+	// a failed launch (ex: build error) never sends the initialized event
+	launched := false
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case msg, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("dap: connection closed")
+		}
+		if err := msg.Error(); err != nil {
+			return err
+		}
+		launched = true
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sess.initialized:
+		}
+	case <-sess.initialized:
+	}
+
+	for filename, lines := range bps {
+		if err := sess.setBreakpoints(ctx, filename, lines); err != nil {
+			return err
+		}
+	}
+	if sess.caps.SupportsConfigurationDoneRequest {
+		if err := sess.cli.Call(ctx, "configurationDone", nil, nil); err != nil {
+			return err
+		}
+	}
+
+	if launched {
+		return nil
+	}
+	return sess.cli.Wait2(ctx, ch, nil)
+}
+
+func (sess *Session) setBreakpoints(ctx context.Context, filename string, lines []int) error {
+	args := &SetBreakpointsArguments{Source: Source{Path: filename}}
+	args.Breakpoints = []*SourceBreakpoint{} // not nil: clears the file breakpoints
+	for _, l := range lines {
+		args.Breakpoints = append(args.Breakpoints, &SourceBreakpoint{Line: l})
+	}
+	return sess.cli.Call(ctx, "setBreakpoints", args, nil)
+}
+
+//----------
+
+func (sess *Session) stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	args := &DisconnectArguments{TerminateDebuggee: true}
+	_ = sess.cli.Call(ctx, "disconnect", args, nil)
+	sess.cancel()
+	return sess.Wait()
+}
+
+func (sess *Session) Wait() error {
+	defer sess.cancel()
+	me := iout.MultiError{}
+	if sess.cli != nil {
+		_ = sess.cli.Close()
+		me.Add(sess.cli.Wait())
+	}
+	if sess.cmd != nil {
+		sess.cancel() // the adapter might not exit by itself (ex: tcp)
+		_ = sess.cmd.Wait()
+	}
+	return me.Result()
+}
+
+//----------
+
+func newCmd(ctx context.Context, cmd string) osutil.CmdI {
+	args := strings.Split(cmd, " ") // TODO: escapes
+	return osutil.NewCmdIShell(ctx, args...)
+}
+
+func cmdTemplate(cmdTmpl string, host string, port int) (string, string, error) {
+	tmpl, err := template.New("").Parse(cmdTmpl)
+	if err != nil {
+		return "", "", err
+	}
+	type tdata struct {
+		Addr string
+		Host string
+		Port int
+	}
+	data := &tdata{Host: host, Port: port}
+	data.Addr = fmt.Sprintf("%s:%d", host, port)
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", "", err
+	}
+	return out.String(), data.Addr, nil
+}
+
+//----------
+
+type rwc struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (rwc *rwc) Close() error {
+	err1 := rwc.ReadCloser.Close()
+	err2 := rwc.WriteCloser.Close()
+	return iout.MultiErrors(err1, err2)
+}
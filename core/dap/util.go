@@ -0,0 +1,65 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+func unmarshalBody(msg *Message, v any) error {
+	if len(msg.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Body, v); err != nil {
+		return fmt.Errorf("dap: %v event: %w", msg.Event, err)
+	}
+	return nil
+}
+
+//----------
+
+func StackFramesToString(frames []*StackFrame, baseDir string) string {
+	sb := &strings.Builder{}
+	for _, f := range frames {
+		loc := "?"
+		if f.Source != nil && f.Source.Path != "" {
+			loc = f.Source.Path
+			if baseDir != "" {
+				if u, err := filepath.Rel(baseDir, loc); err == nil {
+					loc = u
+				}
+			}
+		} else if f.Source != nil && f.Source.Name != "" {
+			loc = f.Source.Name
+		}
+		fmt.Fprintf(sb, "\t%v:%v:%v: %v\n", loc, f.Line, f.Column, f.Name)
+	}
+	return sb.String()
+}
+
+func VariablesToString(scopes []*ScopeVariables) string {
+	sb := &strings.Builder{}
+	for _, sv := range scopes {
+		fmt.Fprintf(sb, "%v:\n", sv.Scope.Name)
+		if sv.Scope.Expensive {
+			fmt.Fprintf(sb, "\t(not fetched)\n")
+			continue
+		}
+		writeVariables(sb, sv.Vars, 1)
+	}
+	return sb.String()
+}
+
+func writeVariables(sb *strings.Builder, vars []*VariableNode, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for _, v := range vars {
+		sb.WriteString(indent)
+		sb.WriteString(v.Name)
+		if v.Type != "" {
+			sb.WriteString(" " + v.Type)
+		}
+		sb.WriteString(" = " + v.Value + "\n")
+		writeVariables(sb, v.Children, depth+1)
+	}
+}
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/friedelschoen/editor/core/dap"
+	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/parseutil"
+	"github.com/friedelschoen/editor/util/uiutil/event"
+)
+
+// Debugger (dap) in file rows: breakpoint markers at the line starts (only drawn, not part of the content), keys to toggle breakpoints and to step, and the program stop location highlighted.
+// All state is handled in the UI goroutine.
+type Debugger struct {
+	ed   *Editor
+	rows map[*ERow]*debuggerRow
+	stop *dap.StopLocation
+}
+
+func NewDebugger(ed *Editor) *Debugger {
+	dbg := &Debugger{ed: ed}
+	dbg.rows = map[*ERow]*debuggerRow{}
+	return dbg
+}
+
+//----------
+
+// Called on new file rows.
+func (dbg *Debugger) Init(erow *ERow) {
+	if !erow.Info.IsFileButNotDir() {
+		return
+	}
+	if _, err := dbg.ed.DAPMan.Registration(erow.Info.Name()); err != nil {
+		return
+	}
+	dbg.rows[erow] = &debuggerRow{}
+	dbg.updateMarkers(erow)
+	dbg.updateStopHighlight(erow)
+}
+
+// Called on row close.
+func (dbg *Debugger) Close(erow *ERow) {
+	delete(dbg.rows, erow)
+}
+
+// Keeps the breakpoints and the stop location on their lines.
+func (dbg *Debugger) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	r, ok := dbg.rows[erow]
+	if !ok || !ev.Changed {
+		return
+	}
+	ta := erow.Row.TextArea
+	if i, ok := ta.LineHighlight(); ok && i > ev.Index {
+		ta.SetLineHighlight(max(ev.Index, i+ev.In-ev.Dn))
+	}
+	if len(r.markers) == 0 {
+		return
+	}
+	markers := shiftInlayHints(r.markers, ev)
+	lines := []int{}
+	for _, m := range markers {
+		l, _, err := parseutil.IndexLineColumn(ta.RW(), m.Offset)
+		if err != nil {
+			continue
+		}
+		if len(lines) == 0 || lines[len(lines)-1] != l {
+			lines = append(lines, l)
+		}
+	}
+	dbg.ed.DAPMan.SetBreakpoints(erow.Info.Name(), lines)
+	dbg.updateMarkers(erow)
+}
+
+// Keys: f9 toggles a breakpoint, f5 continues (starts if not running), f10 steps over, f11 steps in, shift+f11 steps out.
+func (dbg *Debugger) OnInput(erow *ERow, ev *ui.TextAreaInputEvent) event.Handled {
+	if _, ok := dbg.rows[erow]; !ok {
+		return false
+	}
+	kd, ok := ev.Event.(*event.KeyDown)
+	if !ok {
+		return false
+	}
+	m := kd.Mods.ClearLocks()
+	switch {
+	case m.IsEmpty() && kd.KeySym == event.KSymF9:
+		dbg.ToggleBreakpoint(erow)
+	case m.IsEmpty() && kd.KeySym == event.KSymF5:
+		dbg.Continue(erow)
+	case m.IsEmpty() && kd.KeySym == event.KSymF10:
+		dbg.Next(erow)
+	case m.IsEmpty() && kd.KeySym == event.KSymF11:
+		dbg.StepIn(erow)
+	case m.Is(event.ModShift) && kd.KeySym == event.KSymF11:
+		dbg.StepOut(erow)
+	default:
+		return false
+	}
+	return true
+}
+
+// Breakpoint markers, ordered by offset.
+func (dbg *Debugger) hints(erow *ERow) []*drawutil.InlayHint {
+	r, ok := dbg.rows[erow]
+	if !ok {
+		return nil
+	}
+	return r.markers
+}
+
+//----------
+
+// Toggles a breakpoint at the cursor line.
+func (dbg *Debugger) ToggleBreakpoint(erow *ERow) {
+	ta := erow.Row.TextArea
+	line, _, err := parseutil.IndexLineColumn(ta.RW(), ta.CursorIndex())
+	if err != nil {
+		dbg.ed.Error(err)
+		return
+	}
+	filename := erow.Info.Name()
+	dbg.run(erow, 10*time.Second, func(ctx context.Context) error {
+		_, err := dbg.ed.DAPMan.ToggleBreakpoint(ctx, filename, line)
+		dbg.ed.UI.RunOnUIGoRoutine(func() {
+			dbg.updateFileMarkers(filename)
+		})
+		return err
+	})
+}
+
+// Starts a debug session for the row file.
+func (dbg *Debugger) Start(erow *ERow) {
+	filename := erow.Info.Name()
+	dbg.run(erow, 60*time.Second, func(ctx context.Context) error {
+		return dbg.ed.DAPMan.Start(ctx, filename)
+	})
+}
+
+// Stops the debug session asynchronously. The row is optional.
+func (dbg *Debugger) Stop(erow *ERow) {
+	dbg.run(erow, 10*time.Second, func(ctx context.Context) error {
+		return dbg.ed.DAPMan.Stop()
+	})
+}
+
+// Continues the stopped program, or starts a debug session if none is running.
+func (dbg *Debugger) Continue(erow *ERow) {
+	if !dbg.ed.DAPMan.Running() {
+		dbg.Start(erow)
+		return
+	}
+	dbg.run(erow, 10*time.Second, dbg.ed.DAPMan.Continue)
+}
+
+func (dbg *Debugger) Next(erow *ERow) {
+	dbg.run(erow, 10*time.Second, dbg.ed.DAPMan.Next)
+}
+func (dbg *Debugger) StepIn(erow *ERow) {
+	dbg.run(erow, 10*time.Second, dbg.ed.DAPMan.StepIn)
+}
+func (dbg *Debugger) StepOut(erow *ERow) {
+	dbg.run(erow, 10*time.Second, dbg.ed.DAPMan.StepOut)
+}
+
+// Runs with a busy cursor on the row. Canceled with the row internal cmds (ex: esc key).
+// The row is optional (ex: root toolbar), without it there is no busy cursor.
+func (dbg *Debugger) run(erow *ERow, timeout time.Duration, fn func(context.Context) error) {
+	if erow == nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := fn(ctx); err != nil {
+				dbg.ed.Errorf("dap: %w", err)
+			}
+		}()
+		return
+	}
+	ctx, cancel := erow.newInternalCmdCtx()
+	dbg.ed.RunAsyncBusyCursor(erow.Row, func() {
+		defer cancel()
+		ctx2, cancel2 := context.WithTimeout(ctx, timeout)
+		defer cancel2()
+		if err := fn(ctx2); err != nil {
+			dbg.ed.Errorf("dap: %w", err)
+		}
+	})
+}
+
+//----------
+
+// Lists the stack of the stopped program in the "+DapStack" row. If create is false, only updates an existing row.
+func (dbg *Debugger) ShowStack(create bool) {
+	dbg.showRow("+DapStack", create, func(ctx context.Context, w io.Writer) error {
+		frames, err := dbg.ed.DAPMan.StackTrace(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "dap stack:\n%v", dap.StackFramesToString(frames, ""))
+		return nil
+	})
+}
+
+// Lists the variables of the stopped frame in the "+DapVariables" row. If create is false, only updates an existing row.
+func (dbg *Debugger) ShowVariables(create bool) {
+	dbg.showRow("+DapVariables", create, func(ctx context.Context, w io.Writer) error {
+		scopes, err := dbg.ed.DAPMan.Variables(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "dap variables:\n%v", dap.VariablesToString(scopes))
+		return nil
+	})
+}
+
+func (dbg *Debugger) showRow(name string, create bool, fn func(context.Context, io.Writer) error) {
+	if !create {
+		if _, ok := dbg.ed.ReadERowInfo(name).FirstERow(); !ok {
+			return
+		}
+	}
+	// special row to run on (single instance, reused)
+	erow, isNew := ExistingERowOrNewBasic(dbg.ed, name)
+	if isNew {
+		erow.ToolbarSetStrAfterNameClearHistory(" | Stop")
+	}
+	if create {
+		erow.Flash()
+	}
+	erow.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+		ctx2, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		return fn(ctx2, rw)
+	})
+}
+
+//----------
+
+// Called from the manager (not in the UI goroutine).
+func (dbg *Debugger) OnManagerEvent(ev *dap.ManagerEvent) {
+	switch ev.Type {
+	case dap.ManagerEventStopped:
+		dbg.ed.UI.RunOnUIGoRoutine(func() {
+			dbg.setStop(ev.Location)
+			dbg.ShowStack(false)
+			dbg.ShowVariables(false)
+		})
+	case dap.ManagerEventContinued, dap.ManagerEventTerminated:
+		dbg.ed.UI.RunOnUIGoRoutine(func() {
+			dbg.setStop(nil)
+		})
+	case dap.ManagerEventOutput:
+		if s := strings.TrimRight(ev.Output, "\n"); s != "" {
+			dbg.ed.Message(s)
+		}
+	}
+}
+
+func (dbg *Debugger) setStop(loc *dap.StopLocation) {
+	dbg.stop = loc
+	// show the stop location (new rows get the highlight on init)
+	if loc != nil && loc.Filename != "" && loc.Line > 0 {
+		conf := &OpenFileERowConfig{
+			FilePos:             &parseutil.FilePos{Filename: loc.Filename, Line: loc.Line, Column: loc.Column},
+			RowPos:              dbg.ed.GoodRowPos(),
+			NewIfNotExistent:    true,
+			FlashVisibleOffsets: true,
+		}
+		OpenFileERow(dbg.ed, conf)
+	}
+	for erow := range dbg.rows {
+		dbg.updateStopHighlight(erow)
+	}
+}
+
+func (dbg *Debugger) updateStopHighlight(erow *ERow) {
+	ta := erow.Row.TextArea
+	loc := dbg.stop
+	if loc == nil || loc.Filename != erow.Info.Name() {
+		ta.ClearLineHighlight()
+		return
+	}
+	offset, err := parseutil.LineColumnIndex(ta.RW(), loc.Line, 1)
+	if err != nil {
+		ta.ClearLineHighlight()
+		return
+	}
+	ta.SetLineHighlight(offset)
+}
+
+//----------
+
+func (dbg *Debugger) updateFileMarkers(filename string) {
+	for erow := range dbg.rows {
+		if erow.Info.Name() == filename {
+			dbg.updateMarkers(erow)
+		}
+	}
+}
+
+func (dbg *Debugger) updateMarkers(erow *ERow) {
+	r := dbg.rows[erow]
+	rd := erow.Row.TextArea.RW()
+	w := []*drawutil.InlayHint{}
+	for _, l := range dbg.ed.DAPMan.Breakpoints(erow.Info.Name()) {
+		offset, err := parseutil.LineColumnIndex(rd, l, 1)
+		if err != nil {
+			continue
+		}
+		w = append(w, &drawutil.InlayHint{Offset: offset, Bytes: []byte(breakpointMarker)})
+	}
+	if len(w) == 0 && len(r.markers) == 0 {
+		return
+	}
+	r.markers = w
+	updateDrawerInlayHints(dbg.ed, erow)
+}
+
+//----------
+
+const breakpointMarker = "● "
+
+type debuggerRow struct {
+	markers []*drawutil.InlayHint // breakpoints
+}
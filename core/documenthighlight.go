@@ -0,0 +1,164 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+// Highlights the occurrences of the symbol at the cursor (lsproto), with distinct colors for read and write access. Without results, the drawer highlights the word matches.
+type DocumentHighlight struct {
+	ed   *Editor
+	rows map[*ERow]*lsprotoRowReq
+}
+
+func NewDocumentHighlight(ed *Editor) *DocumentHighlight {
+	dh := &DocumentHighlight{ed: ed}
+	dh.rows = map[*ERow]*lsprotoRowReq{}
+	return dh
+}
+
+//----------
+
+// Called on new file rows.
+func (dh *DocumentHighlight) Init(erow *ERow) {
+	if !erow.Info.IsFileButNotDir() {
+		return
+	}
+	if _, err := dh.ed.LSProtoMan.LangManager(erow.Info.Name()); err != nil {
+		return
+	}
+	dh.rows[erow] = &lsprotoRowReq{}
+}
+
+// Called on row close.
+func (dh *DocumentHighlight) Close(erow *ERow) {
+	r, ok := dh.rows[erow]
+	if !ok {
+		return
+	}
+	r.stop()
+	delete(dh.rows, erow)
+}
+
+// Occurrences are no longer valid after an edit. Duplicate rows share the content, but only the written row gets the event: all are cleared, and only the written row requests again (cursor there).
+func (dh *DocumentHighlight) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	if !ev.Changed {
+		return
+	}
+	for _, e := range erow.Info.ERows {
+		r, ok := dh.rows[e]
+		if !ok {
+			continue
+		}
+		r.version++
+		dh.setEntries(e, nil)
+		if e == erow {
+			dh.schedule(e)
+		} else {
+			r.stop()
+		}
+	}
+}
+
+// Updates on cursor changes (keys/buttons).
+func (dh *DocumentHighlight) OnMouseKeyDown(erow *ERow) {
+	if _, ok := dh.rows[erow]; !ok {
+		return
+	}
+	// run after the current event is handled (cursor is updated)
+	dh.ed.UI.RunOnUIGoRoutine(func() {
+		r, ok := dh.rows[erow]
+		if !ok {
+			return
+		}
+		// still inside the current occurrences
+		ci := erow.Row.TextArea.CursorIndex()
+		for _, e := range dh.entries(erow) {
+			if ci >= e.Offset && ci <= e.Offset+e.N {
+				r.stop()
+				return
+			}
+		}
+		dh.schedule(erow)
+	})
+}
+
+//----------
+
+func (dh *DocumentHighlight) schedule(erow *ERow) {
+	r := dh.rows[erow]
+	open := func() bool { return dh.rows[erow] == r }
+	r.schedule(dh.ed, erow, docHighlightDelay, 5*time.Second, open, func() lsprotoRowReqFn {
+		return dh.request(erow)
+	})
+}
+
+func (dh *DocumentHighlight) request(erow *ERow) lsprotoRowReqFn {
+	offset := erow.Row.TextArea.CursorIndex()
+	filename := erow.Info.Name()
+	return func(ctx context.Context, rd iorw.ReaderAt) func() {
+		hls, err := dh.ed.LSProtoMan.TextDocumentDocumentHighlight(ctx, filename, rd, offset)
+		if err != nil {
+			hls = nil // best effort, fallback to the word highlight
+		}
+		entries := docHighlightEntries(rd, hls)
+		return func() { dh.setEntries(erow, entries) }
+	}
+}
+
+//----------
+
+func (dh *DocumentHighlight) entries(erow *ERow) []*drawutil.WordOccurrence {
+	return erow.Row.TextArea.Drawer.Opt.WordHighlight.Occurrences.Entries
+}
+
+func (dh *DocumentHighlight) setEntries(erow *ERow, entries []*drawutil.WordOccurrence) {
+	ta := erow.Row.TextArea
+	if len(entries) == 0 && len(dh.entries(erow)) == 0 {
+		return
+	}
+	ta.Drawer.SetWordOccurrences(entries)
+	ta.MarkNeedsPaint()
+}
+
+//----------
+
+const docHighlightDelay = 150 * time.Millisecond
+
+//----------
+
+// Entries ordered by offset, without overlaps.
+func docHighlightEntries(rd iorw.ReaderAt, hls []*lsproto.DocumentHighlight) []*drawutil.WordOccurrence {
+	w := []*drawutil.WordOccurrence{}
+	for _, hl := range hls {
+		offset, n, err := lsproto.RangeToOffsetLen(rd, &hl.Range)
+		if err != nil || n <= 0 {
+			continue
+		}
+		kind := drawutil.WOKText
+		switch hl.Kind {
+		case 2:
+			kind = drawutil.WOKRead
+		case 3:
+			kind = drawutil.WOKWrite
+		}
+		w = append(w, &drawutil.WordOccurrence{Offset: offset, N: n, Kind: kind})
+	}
+	sort.Slice(w, func(i, j int) bool {
+		return w[i].Offset < w[j].Offset
+	})
+	// remove overlaps
+	w2 := []*drawutil.WordOccurrence{}
+	for _, e := range w {
+		if k := len(w2); k > 0 && e.Offset < w2[k-1].Offset+w2[k-1].N {
+			continue
+		}
+		w2 = append(w2, e)
+	}
+	return w2
+}
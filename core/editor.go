@@ -16,14 +16,17 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/friedelschoen/editor/core/dap"
 	"github.com/friedelschoen/editor/core/fswatcher"
 	"github.com/friedelschoen/editor/core/lsproto"
 	"github.com/friedelschoen/editor/ui"
 	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/evreg"
 	"github.com/friedelschoen/editor/util/fontutil"
 	"github.com/friedelschoen/editor/util/imageutil"
 	"github.com/friedelschoen/editor/util/iout/iorw"
 	"github.com/friedelschoen/editor/util/osutil"
+	"github.com/friedelschoen/editor/util/parseutil"
 	"github.com/friedelschoen/editor/util/uiutil/event"
 	"github.com/friedelschoen/editor/util/uiutil/widget"
 	"golang.org/x/image/font/opentype"
@@ -35,7 +38,17 @@ type Editor struct {
 	Watcher           fswatcher.Watcher
 	RowReopener       *RowReopener
 	LSProtoMan        *lsproto.Manager
+	DAPMan            *dap.Manager
 	InlineComplete    *InlineComplete
+	SignatureHelp     *SignatureHelp
+	InlayHints        *InlayHints
+	CodeLens          *CodeLens
+	DocHighlight      *DocumentHighlight
+	CompletionList    *CompletionList
+	SelectionRange    *SelectionRange
+	Debugger          *Debugger
+	Jobs              *Jobs
+	CmdHistory        *CmdHistory
 	Plugins           *Plugins
 	EEvents           *EEvents // editor events (used by plugins)
 	FsCaseInsensitive bool     // filesystem
@@ -45,6 +58,8 @@ type Editor struct {
 	erowInfos    map[string]*ERowInfo // use ed.ERowInfo*() to access
 	preSaveHooks []PreSaveHook
 
+	wsEditPreview *wsEditPreview // lsproto changes waiting for confirmation
+
 	zipSessionsFile bool
 }
 
@@ -60,6 +75,15 @@ func RunEditor(opt *Options) error {
 	ed.RowReopener = NewRowReopener(ed)
 	ed.dndh = NewDndHandler(ed)
 	ed.InlineComplete = NewInlineComplete(ed)
+	ed.SignatureHelp = NewSignatureHelp(ed)
+	ed.InlayHints = NewInlayHints(ed)
+	ed.CodeLens = NewCodeLens(ed)
+	ed.DocHighlight = NewDocumentHighlight(ed)
+	ed.CompletionList = NewCompletionList(ed)
+	ed.SelectionRange = NewSelectionRange(ed)
+	ed.Debugger = NewDebugger(ed)
+	ed.Jobs = NewJobs(ed)
+	ed.CmdHistory = NewCmdHistory(ed)
 	ed.EEvents = NewEEvents()
 
 	if err := ed.init(opt); err != nil {
@@ -99,6 +123,10 @@ func (ed *Editor) init(opt *Options) error {
 	// TODO: ensure it has the window measure
 	ed.EnsureOneColumn()
 
+	// before plugins and rows (rows keep lsproto documents open)
+	ed.initLSProto(opt)
+	ed.initDAP(opt)
+
 	// setup plugins
 	setupInitialRows := true
 	err = ed.setupPlugins(opt)
@@ -114,7 +142,6 @@ func (ed *Editor) init(opt *Options) error {
 		})
 	}
 
-	ed.initLSProto(opt)
 	ed.initPreSaveHooks(opt)
 
 	return nil
@@ -123,8 +150,22 @@ func (ed *Editor) init(opt *Options) error {
 func (ed *Editor) initLSProto(opt *Options) {
 	// language server protocol manager
 	ed.LSProtoMan = lsproto.NewManager(ed.Message)
+	ed.LSProtoMan.SetApplyEditFn(ed.LSProtoApplyWorkspaceEdit)
 	for _, reg := range opt.LSProtos {
-		ed.LSProtoMan.Register(&reg)
+		if err := ed.LSProtoMan.Register(&reg); err != nil {
+			ed.Error(err)
+		}
+	}
+}
+
+func (ed *Editor) initDAP(opt *Options) {
+	// debug adapter protocol manager
+	ed.DAPMan = dap.NewManager(ed.Message, ed.Debugger.OnManagerEvent)
+	for _, reg := range dap.DefaultRegistrations() {
+		ed.DAPMan.Register(reg)
+	}
+	for _, reg := range opt.DAPs {
+		ed.DAPMan.Register(&reg)
 	}
 }
 
@@ -136,6 +177,7 @@ func (ed *Editor) initPreSaveHooks(opt *Options) {
 
 func (ed *Editor) Close() {
 	ed.LSProtoMan.Stop()
+	ed.DAPMan.Stop()
 	ed.UI.AppendEvent(&editorCloseEv{})
 }
 
@@ -360,15 +402,16 @@ func (ed *Editor) setupRootMenuToolbar() {
 		{"ColorTheme", "FontTheme"},
 		{"CopyFilePosition"},
 		{"CtxutilCallsState"},
+		{"ExpandSelection", "ShrinkSelection"},
 		{"Find -h"},
 		{"FontRunes", "RuneCodes"},
-		{"GoDebug -h", "GoDebug run -h", "GoDebug connect -h"},
-		{"GoDebugFind"},
-		{"GoDebugTrace"},
+		{"DapStart", "DapStop", "DapContinue", "DapNext", "DapStepIn", "DapStepOut", "DapBreakpoint", "DapStack", "DapVariables"},
 		{"GotoLine"},
+		{"History"},
+		{"Jobs", "JobsFocus", "JobsInt", "JobsTerm", "JobsKill", "JobsRestart"},
 		{"ListDir", "ListDir -hidden", "ListDir -sub"},
 		{"ListSessions", "OpenSession", "DeleteSession", "SaveSession"},
-		{"LsprotoRename", "LsprotoCloseAll", "LsprotoCallers", "LsprotoCallees", "LsprotoReferences"},
+		{"LsprotoRename", "LsprotoCloseAll", "LsprotoStatus", "LsprotoCallers", "LsprotoCallees", "LsprotoSupertypes", "LsprotoSubtypes", "LsprotoReferences", "LsprotoDiagnostics", "LsprotoTypeDefinition", "LsprotoDeclaration", "LsprotoFormat", "LsprotoCodeActions", "LsprotoOutline", "LsprotoSymbols"},
 		{"NewColumn", "NewRow", "ReopenRow", "MaximizeRow"},
 		{"NewFile", "SaveAllFiles", "Save"},
 		{"OpenExternal", "OpenFilemanager", "OpenTerminal"},
@@ -541,6 +584,8 @@ func (ed *Editor) handleGlobalShortcuts(ev any) (handled bool) {
 				switch t2.KeySym {
 				case event.KSymEscape:
 					ed.InlineComplete.CancelAndClear()
+					ed.SignatureHelp.CancelAndClear()
+					ed.CompletionList.ClearTabStops()
 					ed.cancelERowInfosCmds()
 					ed.cancelERowsContentCmds()
 					ed.cancelERowsInternalCmds()
@@ -551,6 +596,9 @@ func (ed *Editor) handleGlobalShortcuts(ev any) (handled bool) {
 					autoCloseInfo = false
 					ed.toggleInfoFloatBox()
 					return true
+				case event.KSymUp, event.KSymDown, event.KSymPageUp, event.KSymPageDown, event.KSymReturn, event.KSymTab:
+					// keys handled by the completion list
+					autoCloseInfo = !ed.CompletionList.IsOn()
 				}
 			}
 		}
@@ -678,6 +726,15 @@ func (ed *Editor) toggleInfoFloatBox() {
 		// ui feedback while loading
 		v := fmt.Sprintf("Loading lsproto(%v)...", lang.Reg.Language)
 		showAsync(v)
+		// lsproto hover (signature and documentation)
+		if ta == erow.Row.TextArea && erow.Info.IsFileButNotDir() {
+			s, err := ed.lsprotoManHover(ctx, ta, erow)
+			if err == nil && s != "" {
+				showAsync(s)
+				return
+			}
+			// no hover info: fallback to autocomplete
+		}
 		// lsproto autocomplete
 		s, err := ed.lsprotoManAutoComplete(ctx, ta, erow)
 		if err != nil {
@@ -689,6 +746,24 @@ func (ed *Editor) toggleInfoFloatBox() {
 	})
 }
 
+// Shows the items (one per line) in the context float box at the textarea cursor. Clicking (buttonRight) on an item calls fn with the item index. Must be called from the UI goroutine.
+func (ed *Editor) ShowInfoFloatBoxChoices(ta *ui.TextArea, items []string, fn func(int)) {
+	ed.ifbw.Cancel() // cancel previous run
+	u := make([]string, len(items))
+	for i, s := range items {
+		u[i] = strings.ReplaceAll(s, "\n", " ")
+	}
+	cfb := ed.ifbw.ui()
+	cfb.SetRefPointToTextAreaCursor(ta)
+	cfb.TextArea.ClearPos()
+	cfb.SetStrClearHistory(strings.Join(u, "\n"))
+	cfb.Show()
+	ed.ifbw.setChoices(fn)
+}
+
+func (ed *Editor) lsprotoManHover(ctx context.Context, ta *ui.TextArea, erow *ERow) (string, error) {
+	return ed.LSProtoMan.TextDocumentHoverString(ctx, erow.Info.Name(), ta.RW(), ta.CursorIndex())
+}
 func (ed *Editor) lsprotoManAutoComplete(ctx context.Context, ta *ui.TextArea, erow *ERow) (string, error) {
 	//ta := erow.Row.TextArea
 	comps, err := ed.LSProtoMan.TextDocumentCompletionDetailStrings(ctx, erow.Info.Name(), ta.RW(), ta.CursorIndex())
@@ -763,6 +838,11 @@ func (ed *Editor) setAnnotations2(annotator Annotator, ta *ui.TextArea, selIndex
 		annotation.set()
 	case AnnotatorInlineComplete:
 		annotation.set()
+	case AnnotatorSignatureHelp:
+		if ed.InlineComplete.IsOn(ta) {
+			return
+		}
+		annotation.set()
 	default:
 		panic("todo")
 	}
@@ -781,6 +861,19 @@ func (ed *Editor) AnnotationsHandled(erow *ERow, ev *ui.TextAreaInlineCompleteEv
 //----------
 
 func (ed *Editor) runPreSaveHooks(ctx context.Context, info *ERowInfo, b []byte) ([]byte, error) {
+	// lsproto formatting (registrations with the "format" flag)
+	langs, _ := ed.LSProtoMan.LangManagers(info.Name())
+	for _, lang := range langs {
+		if lang.Reg.HasOptional("format") {
+			b2, err := ed.runLSProtoPreSaveHook(ctx, lang, info, b)
+			if err != nil {
+				return nil, lang.WrapError(fmt.Errorf("presavehook: %w", err))
+			}
+			b = b2
+			break
+		}
+	}
+
 	ext := filepath.Ext(info.Name())
 	for _, h := range ed.preSaveHooks {
 		for _, e := range h.Exts {
@@ -810,6 +903,20 @@ func (ed *Editor) runPreSaveHook(ctx context.Context, info *ERowInfo, content []
 	return osutil.RunCmdStdin(ctx2, dir, r, cmd2...)
 }
 
+func (ed *Editor) runLSProtoPreSaveHook(ctx context.Context, lang *lsproto.LangManager, info *ERowInfo, content []byte) ([]byte, error) {
+	// timeout for the server to reply
+	timeout := 5 * time.Second
+	ctx2, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rd := iorw.NewBytesReadWriterAt(content)
+	edits, err := ed.LSProtoMan.LangTextDocumentFormatting(ctx2, lang, info.Name(), rd)
+	if err != nil {
+		return nil, err
+	}
+	return lsproto.PatchTextEdits(content, edits)
+}
+
 //----------
 
 func (ed *Editor) loadSessions() (*Sessions, error) {
@@ -879,6 +986,7 @@ const (
 	AnnotatorGoDebug Annotator = iota
 	AnnotatorGoDebugStart
 	AnnotatorInlineComplete
+	AnnotatorSignatureHelp
 )
 
 //----------
@@ -904,6 +1012,11 @@ type InfoFloatBoxWrap struct {
 	ed   *Editor
 	ctx  context.Context
 	canc context.CancelFunc
+
+	choices struct {
+		fn  func(int) // called with the chosen item index
+		reg *evreg.Regist
+	}
 }
 
 func NewInfoFloatBox(ed *Editor) *InfoFloatBoxWrap {
@@ -919,11 +1032,36 @@ func (ifbw *InfoFloatBoxWrap) Cancel() {
 		ifbw.canc()
 		ifbw.canc = nil
 	}
+	ifbw.choices.fn = nil
+	ifbw.ed.CompletionList.closeList()
 }
 func (ifbw *InfoFloatBoxWrap) ui() *ui.ContextFloatBox {
 	return ifbw.ed.UI.Root.ContextFloatBox
 }
 
+func (ifbw *InfoFloatBoxWrap) setChoices(fn func(int)) {
+	ifbw.choices.fn = fn
+	if ifbw.choices.reg == nil {
+		ta := ifbw.ui().TextArea
+		ifbw.choices.reg = ta.EvReg.Add(ui.TextAreaCmdEventId, func(ev0 any) {
+			ev := ev0.(*ui.TextAreaCmdEvent)
+			ifbw.choose(ev.Index)
+		})
+	}
+}
+func (ifbw *InfoFloatBoxWrap) choose(index int) {
+	fn := ifbw.choices.fn
+	if fn == nil {
+		return
+	}
+	l, _, err := parseutil.IndexLineColumn(ifbw.ui().TextArea.RW(), index)
+	if err != nil {
+		return
+	}
+	ifbw.ed.cancelInfoFloatBox()
+	fn(l - 1)
+}
+
 //----------
 
 type editorCloseEv struct{}
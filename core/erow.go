@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/friedelschoen/editor/core/toolbarparser"
 	"github.com/friedelschoen/editor/ui"
@@ -26,7 +29,9 @@ type ERow struct {
 	highlightDuplicates bool
 
 	terminalOpt    terminalOpt
+	termSize       atomic.Pointer[image.Point] // textarea size in cells (cols,rows), updated on layout
 	scrollDownMode string
+	execMode       string
 
 	ctx       context.Context // erow general context
 	cancelCtx context.CancelFunc
@@ -113,6 +118,15 @@ func (erow *ERow) init(info *ERowInfo, rowPos *ui.RowPos) {
 	detectSetupSyntaxHighlight(erow)
 	erow.initHandlers()
 
+	// keep lsproto documents open while there are rows for the file
+	if info.IsFileButNotDir() {
+		erow.Ed.LSProtoMan.KeepDocumentOpen(info.Name())
+	}
+	erow.Ed.InlayHints.Init(erow)
+	erow.Ed.CodeLens.Init(erow)
+	erow.Ed.DocHighlight.Init(erow)
+	erow.Ed.Debugger.Init(erow)
+
 	erow.updateToolbarNameEncoding2("")
 
 	// editor events
@@ -180,6 +194,9 @@ func (erow *ERow) Reload() error {
 	case erow.Info.IsSpecial() && erow.Info.Name() == "+Sessions":
 		ListSessions(erow.Ed)
 		return nil
+	case erow.Info.IsSpecial() && erow.Info.Name() == JobsRowName:
+		erow.Ed.Jobs.Show(false)
+		return nil
 	case erow.Info.IsDir():
 		ListDirERow(erow, erow.Info.Name(), false, true)
 		return nil
@@ -223,10 +240,42 @@ func (erow *ERow) initHandlers() {
 	row.Toolbar.EvReg.Add(ui.TextAreaCmdEventId, func(ev0 any) {
 		InternalOrExternalCmdFromRowTb(erow)
 	})
+	// toolbar keys (cmd history)
+	row.Toolbar.EvReg.Add(ui.TextAreaInputEventId, func(ev0 any) {
+		ev := ev0.(*ui.TextAreaInputEvent)
+		if erow.Ed.CmdHistory.OnToolbarInput(erow, ev) {
+			ev.ReplyHandled = true
+		}
+	})
 	// textarea on write
 	row.TextArea.RWEvReg.Add(iorw.RWEvIdWrite2, func(ev0 any) {
 		ev := ev0.(*iorw.RWEvWrite2)
 		erow.Info.HandleRWEvWrite2(erow, ev)
+		erow.Ed.SignatureHelp.OnWrite(erow, ev)
+		erow.Ed.InlayHints.OnWrite(erow, ev)
+		erow.Ed.CodeLens.OnWrite(erow, ev)
+		erow.Ed.DocHighlight.OnWrite(erow, ev)
+		erow.Ed.CompletionList.OnWrite(erow, ev)
+		erow.Ed.SelectionRange.OnWrite(erow, ev)
+		erow.Ed.Debugger.OnWrite(erow, ev)
+	})
+	// textarea layout (ex: scroll)
+	row.TextArea.EvReg.Add(ui.TextAreaLayoutEventId, func(ev0 any) {
+		erow.updateTerminalSize()
+		erow.Ed.InlayHints.OnLayout(erow)
+		erow.Ed.CodeLens.OnLayout(erow)
+	})
+	// textarea keys (completion list, selection range, debugger)
+	row.TextArea.EvReg.Add(ui.TextAreaInputEventId, func(ev0 any) {
+		ev := ev0.(*ui.TextAreaInputEvent)
+		if erow.Ed.CompletionList.OnInput(erow, ev) || erow.Ed.SelectionRange.OnInput(erow, ev) || erow.Ed.Debugger.OnInput(erow, ev) {
+			ev.ReplyHandled = true
+		}
+	})
+	// textarea inlay hint click (code lens)
+	row.TextArea.EvReg.Add(ui.TextAreaSelectInlayHintEventId, func(ev0 any) {
+		ev := ev0.(*ui.TextAreaSelectInlayHintEvent)
+		ev.ReplyHandled = erow.Ed.CodeLens.OnSelectInlayHint(erow, ev)
 	})
 	// textarea content cmds
 	row.TextArea.EvReg.Add(ui.TextAreaCmdEventId, func(ev0 any) {
@@ -246,6 +295,8 @@ func (erow *ERow) initHandlers() {
 		switch ev.Event.(type) {
 		case *event.KeyDown, *event.MouseDown:
 			erow.Ed.AnnotationsOnMouseKeyDown()
+			erow.Ed.SignatureHelp.OnMouseKeyDown(erow)
+			erow.Ed.DocHighlight.OnMouseKeyDown(erow)
 		}
 
 		switch evt := ev.Event.(type) {
@@ -294,6 +345,13 @@ func (erow *ERow) initHandlers() {
 
 		// cancel general context
 		erow.cancelCtx()
+		erow.Ed.InlayHints.Close(erow)
+		erow.Ed.CodeLens.Close(erow)
+		erow.Ed.DocHighlight.Close(erow)
+		erow.Ed.CompletionList.Close(erow)
+		erow.Ed.SelectionRange.Close(erow)
+		erow.Ed.Debugger.Close(erow)
+		erow.Ed.CmdHistory.Close(erow)
 
 		// ensure execution (if any) is stopped
 		erow.Exec.Stop()
@@ -304,6 +362,15 @@ func (erow *ERow) initHandlers() {
 			erow.Ed.DeleteERowInfo(erow.Info.Name())
 		}
 
+		// close lsproto document
+		if erow.Info.IsFileButNotDir() && len(erow.Info.ERows) == 0 {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = erow.Ed.LSProtoMan.DidCloseDocument(ctx, erow.Info.Name()) // best effort
+			}()
+		}
+
 		// update row state
 		erow.Info.UpdateDuplicateRowState()
 		erow.Info.UpdateDuplicateHighlightRowState()
@@ -422,6 +489,11 @@ func (erow *ERow) parseToolbarVars() {
 					erow.terminalOpt.filter = true
 				case "k":
 					erow.terminalOpt.keyEvents = true
+				case "p":
+					// pty implies the screen and the keys
+					erow.terminalOpt.pty = true
+					erow.terminalOpt.filter = true
+					erow.terminalOpt.keyEvents = true
 				}
 			}
 		}
@@ -432,6 +504,12 @@ func (erow *ERow) parseToolbarVars() {
 	if v, ok := vmap["$scrollMode"]; ok {
 		erow.scrollDownMode = v
 	}
+
+	// $execMode: "parallel", otherwise a new cmd cancels the previous
+	erow.execMode = ""
+	if v, ok := vmap["$execMode"]; ok {
+		erow.execMode = v
+	}
 }
 
 // func (erow *ERow) setVarFontTheme(s string) error {
@@ -468,6 +546,9 @@ func (erow *ERow) AppendBytesClearHistory(p []byte) {
 	}
 }
 func (erow *ERow) AppendBytesClearHistory2(p []byte) error {
+	return erow.OverwriteBytesClearHistory2(erow.Row.TextArea.RW().Max(), 0, p)
+}
+func (erow *ERow) OverwriteBytesClearHistory2(i, n int, p []byte) error {
 	ta := erow.Row.TextArea
 
 	scrollDownModeAuto := false
@@ -477,7 +558,7 @@ func (erow *ERow) AppendBytesClearHistory2(p []byte) error {
 		}
 	}
 
-	if err := ta.AppendBytesClearHistory(p); err != nil {
+	if err := ta.OverwriteBytesClearHistory(i, n, p); err != nil {
 		return err
 	}
 
@@ -488,6 +569,30 @@ func (erow *ERow) AppendBytesClearHistory2(p []byte) error {
 	return nil
 }
 
+// Copy of the textarea content, to be read outside of the UI goroutine.
+func (erow *ERow) ContentCopy() (iorw.ReaderAt, error) {
+	b, err := iorw.ReadFullCopy(erow.Row.TextArea.RW())
+	if err != nil {
+		return nil, err
+	}
+	return iorw.NewBytesReadWriterAt(b), nil
+}
+
+// Removes lines from the start to keep the content size under max bytes (ex: long running output).
+func (erow *ERow) TrimBytesClearHistory(max int) error {
+	ta := erow.Row.TextArea
+	rw := ta.RW()
+	n := rw.Max() - max
+	if n <= 0 {
+		return nil
+	}
+	// cut after the line end
+	if i, _, err := iorw.Index(rw, n, []byte("\n"), false); err == nil && i >= 0 {
+		n = i + 1
+	}
+	return ta.DeleteBytesClearHistory(rw.Min(), n-rw.Min())
+}
+
 //----------
 
 func (erow *ERow) TextAreaReadWriteCloser() io.ReadWriteCloser {
@@ -495,11 +600,23 @@ func (erow *ERow) TextAreaReadWriteCloser() io.ReadWriteCloser {
 		return NewTerminalFilter(erow)
 	}
 
+	// colors from escape sequences (ex: "grep --color=always")
+	sgr := &tfSGRStripper{}
+
 	// synced writer to slow down memory usage
 	w := iout.FnWriter(func(b []byte) (int, error) {
+		b2, styles := sgr.strip(b)
 		var err error
 		erow.Ed.UI.WaitRunOnUIGoRoutine(func() {
-			err = erow.AppendBytesClearHistory2(b)
+			ta := erow.Row.TextArea
+			offset := ta.RW().Max()
+			if err = erow.AppendBytesClearHistory2(b2); err != nil {
+				return
+			}
+			if len(styles) > 0 {
+				ops := append(ta.TerminalColorize(), erow.terminalColorizeOps(offset, styles)...)
+				ta.SetTerminalColorize(capColorizeOps(ops))
+			}
 		})
 		return len(b), err
 	})
@@ -622,6 +739,7 @@ func (erow *ERow) SyntaxComments() []*drawutil.SyntaxComment {
 type terminalOpt struct {
 	filter    bool
 	keyEvents bool
+	pty       bool
 }
 
 func (t *terminalOpt) On() bool {
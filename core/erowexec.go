@@ -14,15 +14,15 @@ type ERowExec struct {
 	c    struct { // count
 		sync.Mutex
 		q       int
-		running bool
+		running int
 		cond    *sync.Cond
-		cancel  context.CancelFunc
+		cancels map[int]context.CancelFunc
 	}
 }
 
 func NewERowExec(erow *ERow) *ERowExec {
 	ee := &ERowExec{erow: erow}
-	ee.c.cancel = func() {}
+	ee.c.cancels = map[int]context.CancelFunc{}
 	ee.c.cond = sync.NewCond(&ee.c)
 	return ee
 }
@@ -31,51 +31,57 @@ func NewERowExec(erow *ERow) *ERowExec {
 
 func (ee *ERowExec) RunAsync(fn func(context.Context, io.ReadWriter) error) {
 	// Note: textarea w.close() (textareawriter) could deadlock if runasync() is not on own goroutine. If w.close waits for UI goroutine to finish and runasync() is currently occupying it (w.close called after a runasync(), just that the UI goroutine is not getting released). Launching in a goroutine allows RunAsync() itself to be called from a uigoroutine since this func will return immediately
-	go ee.runAsync2(context.TODO(), nil, fn)
+	go ee.runAsync2(context.TODO(), nil, ee.erow.execMode, fn)
 }
 func (ee *ERowExec) RunAsyncWithCancel(fn func(context.Context, io.ReadWriter) error) (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(ee.erow.ctx)
-	go ee.runAsync2(ctx, cancel, fn)
+	go ee.runAsync2(ctx, cancel, ee.erow.execMode, fn)
 	return ctx, cancel
 }
-func (ee *ERowExec) runAsync2(optCtx context.Context, optCancel context.CancelFunc, fn func(context.Context, io.ReadWriter) error) {
+
+// The exec mode is read by the caller (ui goroutine).
+func (ee *ERowExec) runAsync2(optCtx context.Context, optCancel context.CancelFunc, execMode string, fn func(context.Context, io.ReadWriter) error) {
 	ee.c.Lock()
 	defer ee.c.Unlock()
 
 	ee.c.q++
 	id := ee.c.q
 
-	// cancel and wait for previous if any
-	ee.c.cancel()
-	for ee.c.running {
-		ee.c.cond.Wait()
-	}
+	// parallel: run side by side with the previous (output is mixed)
+	parallel := execMode == "parallel"
+
+	if !parallel {
+		// cancel and wait for previous if any
+		ee.cancelAll()
+		for ee.c.running > 0 {
+			ee.c.cond.Wait()
+		}
 
-	// there is another request after this one, don't start since the next one would cancel this one
-	if id != ee.c.q {
-		return
+		// there is another request after this one, don't start since the next one would cancel this one
+		if id != ee.c.q {
+			return
+		}
 	}
 
 	// new context
 	ctx, cancel := context.WithCancel(ee.erow.ctx)
-	ee.c.cancel = cancel
+	ee.c.cancels[id] = cancel
 
 	rwc := ee.erow.TextAreaReadWriteCloser()
 
-	ee.c.running = true
-	go func() {
-		defer func() {
-			ee.c.Lock()
-			defer ee.c.Unlock()
-			ee.c.running = false
-			ee.c.cond.Broadcast()
-		}()
+	// keep the output of the others running
+	clear := ee.c.running == 0
 
+	ee.c.running++
+	go func() {
 		// indicate the row is running
 		ee.erow.Ed.UI.RunOnUIGoRoutine(func() {
 			ee.erow.Row.SetState(ui.RowStateExecuting, true)
-			ee.erow.Row.TextArea.SetStrClearHistory("")
-			ee.erow.Row.TextArea.ClearPos()
+			if clear {
+				ee.erow.Row.TextArea.SetStrClearHistory("")
+				ee.erow.Row.TextArea.ClearPos()
+				ee.erow.Row.TextArea.SetTerminalColorize(nil)
+			}
 		})
 
 		err := fn(ctx, rwc)
@@ -92,12 +98,27 @@ func (ee *ERowExec) runAsync2(optCtx context.Context, optCancel context.CancelFu
 			ee.erow.Ed.Error(err)
 		}
 
+		ee.c.Lock()
+		defer ee.c.Unlock()
+		ee.c.running--
+		delete(ee.c.cancels, id)
+		ee.c.cond.Broadcast()
+
+		// queued while locked to keep the order with other runs
+		executing := ee.c.running > 0
 		ee.erow.Ed.UI.RunOnUIGoRoutine(func() {
-			ee.erow.Row.SetState(ui.RowStateExecuting, false)
+			ee.erow.Row.SetState(ui.RowStateExecuting, executing)
 		})
 	}()
 }
 
+// Locked by caller.
+func (ee *ERowExec) cancelAll() {
+	for _, cancel := range ee.c.cancels {
+		cancel()
+	}
+}
+
 //----------
 
 func (ee *ERowExec) Stop() {
@@ -106,7 +127,5 @@ func (ee *ERowExec) Stop() {
 
 	ee.c.q++ // if this was issued after another cmd, that cmd is not going to start
 
-	if ee.c.cancel != nil {
-		ee.c.cancel()
-	}
+	ee.cancelAll()
 }
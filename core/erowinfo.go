@@ -318,7 +318,7 @@ func (info *ERowInfo) SaveFile() error {
 		defer cancel3()
 
 		rd := iorw.NewStringReaderAt(string(b))
-		err := info.Ed.LSProtoMan.SyncText(ctx3, info.Name(), rd)
+		err := info.Ed.LSProtoMan.TextDocumentDidSave(ctx3, info.Name(), rd)
 		if err != nil {
 			// commented: best effort
 			//info.Ed.Error(err)
@@ -510,6 +510,10 @@ func (info *ERowInfo) HandleRWEvWrite2(erow *ERow, ev *iorw.RWEvWrite2) {
 		e.Row.TextArea.HandleRWWrite2(ev)
 	}
 
+	if ev.Changed {
+		info.Ed.LSProtoMan.DidChangeDocument(info.Name(), erow.Row.TextArea.RW(), &ev.RWEvWrite)
+	}
+
 	info.UpdateEditedRowState()
 }
 
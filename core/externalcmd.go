@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/friedelschoen/editor/core/toolbarparser"
@@ -57,21 +58,55 @@ func externalCmdFromDir(erow *ERow, cargs []string, fend func(error), env []stri
 
 func externalCmdDir2(ctx context.Context, erow *ERow, cargs []string, env []string, rw io.ReadWriter) error {
 
+	pty := erow.terminalOpt.pty
+
 	printPid := func(c osutil.CmdI) {
 		//argsStr := strings.Join(c.Cmd().Args, " ")
 		argsStr := strings.Join(cargs, " ")
-		fmt.Fprintf(rw, "# pid %d: %s\n", c.Cmd().Process.Pid, argsStr)
+		nl := "\n"
+		if pty {
+			nl = "\r\n" // the filter doesn't add the carriage return with a pty
+		}
+		fmt.Fprintf(rw, "# pid %d: %s%s", c.Cmd().Process.Pid, argsStr, nl)
 	}
 
 	c := osutil.NewCmdI2(cargs)
-	c = osutil.NewNoHangPipeCmd(c)
+	if pty {
+		cols, rows := erow.terminalSize()
+		pc := osutil.NewPtyCmd(c, cols, rows)
+		c = pc
+		// forward row resizes
+		if tf, ok := rw.(*TerminalFilter); ok {
+			tf.SetOnResize(pc.Resize)
+			defer tf.SetOnResize(nil)
+		}
+	} else {
+		c = osutil.NewNoHangPipeCmd(c)
+	}
 	c = osutil.NewCtxCmd(ctx, c)
 	c = osutil.NewShellCmd(c, true)
-	c = osutil.NewPausedWritersCmd(c, printPid)
+	var job *Job
+	onStart := func(c osutil.CmdI) {
+		printPid(c)
+		job = erow.Ed.Jobs.add(erow, cargs, env, c.Cmd())
+	}
+	c = osutil.NewPausedWritersCmd(c, onStart)
+
+	// the job keeps the original env (restart)
+	cenv := slices.Clone(env)
+	switch {
+	case pty:
+		// the size is known from the tty (and follows resizes)
+		cenv = append(cenv, "TERM=xterm")
+	case erow.terminalOpt.filter:
+		// programs without a tty use these to know the screen size
+		cols, rows := erow.terminalSize()
+		cenv = append(cenv, "TERM=xterm", fmt.Sprintf("COLUMNS=%d", cols), fmt.Sprintf("LINES=%d", rows))
+	}
 
 	cmd := c.Cmd()
 	cmd.Dir = erow.Info.Name()
-	cmd.Env = env
+	cmd.Env = cenv
 	cmd.Stdin = rw
 	cmd.Stdout = rw
 	cmd.Stderr = rw
@@ -79,7 +114,9 @@ func externalCmdDir2(ctx context.Context, erow *ERow, cargs []string, env []stri
 	if err := c.Start(); err != nil {
 		return err
 	}
-	return c.Wait()
+	err := c.Wait()
+	erow.Ed.Jobs.finish(job, err)
+	return err
 }
 
 //----------
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+// Shows inlay hints (lsproto) inline in the text of file rows (ex: parameter names, inferred types). The hints are only drawn, they are not part of the content.
+type InlayHints struct {
+	ed   *Editor
+	rows map[*ERow]*inlayHintsRow
+}
+
+func NewInlayHints(ed *Editor) *InlayHints {
+	ih := &InlayHints{ed: ed}
+	ih.rows = map[*ERow]*inlayHintsRow{}
+	return ih
+}
+
+//----------
+
+// Called on new file rows.
+func (ih *InlayHints) Init(erow *ERow) {
+	if !erow.Info.IsFileButNotDir() {
+		return
+	}
+	if _, err := ih.ed.LSProtoMan.LangManager(erow.Info.Name()); err != nil {
+		return
+	}
+	ih.rows[erow] = &inlayHintsRow{}
+	ih.schedule(erow, 0)
+}
+
+// Called on row close.
+func (ih *InlayHints) Close(erow *ERow) {
+	r, ok := ih.rows[erow]
+	if !ok {
+		return
+	}
+	r.stop()
+	delete(ih.rows, erow)
+}
+
+// Keeps the current hints in place and refreshes after a delay. Duplicate rows share the content, but only the written row gets the event: all are updated.
+func (ih *InlayHints) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	if !ev.Changed {
+		return
+	}
+	for _, e := range erow.Info.ERows {
+		r, ok := ih.rows[e]
+		if !ok {
+			continue
+		}
+		r.version++
+		ih.setEntries(e, r, shiftInlayHints(r.entries, ev))
+		ih.schedule(e, inlayHintsDelay)
+	}
+}
+
+// Refreshes if the visible range is not covered by the last request (ex: scroll).
+func (ih *InlayHints) OnLayout(erow *ERow) {
+	r, ok := ih.rows[erow]
+	if !ok || r.timer != nil {
+		return
+	}
+	o, n := inlayHintsVisible(erow)
+	if r.requested && o >= r.offset && o+n <= r.offset+r.n {
+		return
+	}
+	ih.schedule(erow, inlayHintsDelay)
+}
+
+//----------
+
+func (ih *InlayHints) schedule(erow *ERow, delay time.Duration) {
+	r := ih.rows[erow]
+	open := func() bool { return ih.rows[erow] == r }
+	r.schedule(ih.ed, erow, delay, 8*time.Second, open, func() lsprotoRowReqFn {
+		return ih.request(erow, r)
+	})
+}
+
+func (ih *InlayHints) request(erow *ERow, r *inlayHintsRow) lsprotoRowReqFn {
+	// visible range with one page of margin before and after
+	o, n := inlayHintsVisible(erow)
+	rd0 := erow.Row.TextArea.RW()
+	s := max(o-n, rd0.Min())
+	e := min(o+n+n, rd0.Max())
+	r.offset, r.n, r.requested = s, e-s, true
+	filename := erow.Info.Name()
+
+	return func(ctx context.Context, rd iorw.ReaderAt) func() {
+		hints, err := ih.ed.LSProtoMan.TextDocumentInlayHint(ctx, filename, rd, s, e-s)
+		if err != nil {
+			return nil // best effort, don't bother the user
+		}
+		entries := []*drawutil.InlayHint{}
+		for _, h := range hints {
+			offset, err := lsproto.InlayHintOffset(rd, h)
+			if err != nil {
+				continue
+			}
+			s := lsproto.InlayHintLabel(h)
+			entries = append(entries, &drawutil.InlayHint{Offset: offset, Bytes: []byte(s)})
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Offset < entries[j].Offset
+		})
+		return func() { ih.setEntries(erow, r, entries) }
+	}
+}
+
+//----------
+
+func (ih *InlayHints) hints(erow *ERow) []*drawutil.InlayHint {
+	if r, ok := ih.rows[erow]; ok {
+		return r.entries
+	}
+	return nil
+}
+
+func (ih *InlayHints) setEntries(erow *ERow, r *inlayHintsRow, entries []*drawutil.InlayHint) {
+	if len(entries) == 0 && len(r.entries) == 0 {
+		return
+	}
+	r.entries = entries
+	updateDrawerInlayHints(ih.ed, erow)
+}
+
+// The drawer hints are shared by the code lenses (lines), the breakpoint markers and the inlay hints. At the same offset, the lens line goes first, then the marker.
+func updateDrawerInlayHints(ed *Editor, erow *ERow) {
+	w := mergeInlayHints(ed.CodeLens.hints(erow), ed.Debugger.hints(erow))
+	w = mergeInlayHints(w, ed.InlayHints.hints(erow))
+
+	ta := erow.Row.TextArea
+	d := ta.Drawer
+	if len(w) == 0 && len(d.Opt.InlayHints.Entries) == 0 {
+		return
+	}
+	d.Opt.InlayHints.On = len(w) > 0
+	d.Opt.InlayHints.Entries = w
+	ta.MarkNeedsLayoutAndPaint()
+}
+
+// Merges entries ordered by offset; at equal offsets, the entries of a go first.
+func mergeInlayHints(a, b []*drawutil.InlayHint) []*drawutil.InlayHint {
+	w := make([]*drawutil.InlayHint, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		if len(b) == 0 || len(a) > 0 && a[0].Offset <= b[0].Offset {
+			w, a = append(w, a[0]), a[1:]
+		} else {
+			w, b = append(w, b[0]), b[1:]
+		}
+	}
+	return w
+}
+
+//----------
+
+const inlayHintsDelay = 300 * time.Millisecond
+
+type inlayHintsRow struct {
+	lsprotoRowReq
+	requested bool // offset/n are set
+	offset, n int  // last requested range
+	entries   []*drawutil.InlayHint
+}
+
+//----------
+
+// Visible range, limited to the content.
+func inlayHintsVisible(erow *ERow) (int, int) {
+	ta := erow.Row.TextArea
+	rd := ta.RW()
+	o := ta.Drawer.ScrollOffset().Y
+	n := ta.Drawer.ScrollViewSize().Y
+	o = max(min(o, rd.Max()), rd.Min())
+	n = max(min(n, rd.Max()-o), 0)
+	return o, n
+}
+
+// Returns new entries (the drawer entries are not modified) with offsets after the write updated. Hints inside a deleted range are dropped.
+func shiftInlayHints(entries []*drawutil.InlayHint, ev *iorw.RWEvWrite2) []*drawutil.InlayHint {
+	if len(entries) == 0 {
+		return nil
+	}
+	w := make([]*drawutil.InlayHint, 0, len(entries))
+	for _, e := range entries {
+		switch {
+		case e.Offset <= ev.Index:
+			w = append(w, e)
+		case e.Offset < ev.Index+ev.Dn:
+			// deleted
+		default:
+			e2 := *e
+			e2.Offset += ev.In - ev.Dn
+			w = append(w, &e2)
+		}
+	}
+	return w
+}
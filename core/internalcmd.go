@@ -146,6 +146,11 @@ func internalOrExternalCmd(ed *Editor, part *toolbarparser.Part, optERow *ERow)
 	//}
 	//ed.RunAsyncBusyCursor(node, func() {
 
+	// internal cmds without args are not worth keeping (ex: "Stop")
+	if _, ok := InternalCmds[part.Args[0].UnquotedString()]; !ok || len(part.Args) > 1 {
+		ed.CmdHistory.Add(optERow, part)
+	}
+
 	if err := internalOrExternalCmd2(ed, part, optERow); err != nil {
 		arg0 := part.Args[0].UnquotedString()
 		ed.Errorf("%s: %w", arg0, err)
@@ -0,0 +1,91 @@
+package internalcmds
+
+import (
+	"fmt"
+
+	"github.com/friedelschoen/editor/core"
+)
+
+func DAPStart(args *core.InternalCmdArgs) error {
+	erow, err := dapFileERow(args)
+	if err != nil {
+		return err
+	}
+	args.Ed.Debugger.Start(erow)
+	return nil
+}
+func DAPStop(args *core.InternalCmdArgs) error {
+	if !args.Ed.DAPMan.Running() {
+		return fmt.Errorf("no debug session")
+	}
+	erow, _ := args.ERow() // optional
+	args.Ed.Debugger.Stop(erow)
+	return nil
+}
+
+func DAPContinue(args *core.InternalCmdArgs) error {
+	erow, err := dapFileERow(args)
+	if err != nil {
+		return err
+	}
+	args.Ed.Debugger.Continue(erow)
+	return nil
+}
+func DAPNext(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+	args.Ed.Debugger.Next(erow)
+	return nil
+}
+func DAPStepIn(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+	args.Ed.Debugger.StepIn(erow)
+	return nil
+}
+func DAPStepOut(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+	args.Ed.Debugger.StepOut(erow)
+	return nil
+}
+
+func DAPBreakpoint(args *core.InternalCmdArgs) error {
+	erow, err := dapFileERow(args)
+	if err != nil {
+		return err
+	}
+	args.Ed.Debugger.ToggleBreakpoint(erow)
+	return nil
+}
+
+func DAPStack(args *core.InternalCmdArgs) error {
+	args.Ed.Debugger.ShowStack(true)
+	return nil
+}
+func DAPVariables(args *core.InternalCmdArgs) error {
+	args.Ed.Debugger.ShowVariables(true)
+	return nil
+}
+
+//----------
+
+func dapFileERow(args *core.InternalCmdArgs) (*core.ERow, error) {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return nil, err
+	}
+	if !erow.Info.IsFileButNotDir() {
+		return nil, fmt.Errorf("not a file")
+	}
+	if _, err := args.Ed.DAPMan.Registration(erow.Info.Name()); err != nil {
+		return nil, err
+	}
+	return erow, nil
+}
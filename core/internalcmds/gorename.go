@@ -1,10 +1,17 @@
 package internalcmds
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os/exec"
+	"strings"
 
 	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
 	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/osutil"
 )
 
 func GoRename(args *core.InternalCmdArgs) error {
@@ -17,10 +24,6 @@ func GoRename(args *core.InternalCmdArgs) error {
 		return fmt.Errorf("not a file")
 	}
 
-	if erow.Row.HasState(ui.RowStateEdited | ui.RowStateFsDiffer) {
-		return fmt.Errorf("row has edits, save first")
-	}
-
 	part := args.Part
 	args2 := part.Args[1:]
 	if len(args2) < 1 {
@@ -33,42 +36,84 @@ func GoRename(args *core.InternalCmdArgs) error {
 		isF = true
 		args2 = args2[1:]
 	}
+	if len(args2) < 1 {
+		return fmt.Errorf("expecting new name argument")
+	}
 
 	// new name argument "to"
 	to := args2[len(args2)-1].UnquotedString()
 
+	// all paths show a preview, applied to the rows as undoable edits (rows can have edits)
+	ed := args.Ed
+	if !isF {
+		// registered lsp instance (ex: gopls), or a temporary gopls instance
+		if _, err := ed.LSProtoMan.LangManager(erow.Info.Name()); err == nil {
+			return lsprotoRenamePreview(ed, erow, to, ed.LSProtoMan.TextDocumentRename)
+		}
+		return lsprotoRenamePreview(ed, erow, to, goplsRename(ed))
+	}
+
 	// allow other args
 	otherArgs := []string{}
 	for i := 0; i < len(args2)-1; i++ {
 		otherArgs = append(otherArgs, args2[i].UnquotedString())
 	}
+	return lsprotoRenamePreview(ed, erow, to, gorenameDiff(ed, otherArgs))
+}
 
-	// id offset to rename "from"
-	offset := erow.Row.TextArea.CursorIndex()
+//----------
 
-	// command
-	offsetStr := fmt.Sprintf("%v:#%v", erow.Info.Name(), offset)
-	var cargs []string
-	if isF {
-		cargs = []string{"gorename", "-offset", offsetStr, "-to", to}
-		cargs = append(cargs, otherArgs...)
-	} else {
-		cargs = append([]string{"gopls", "rename"}, append(otherArgs, []string{"-w", offsetStr, to}...)...)
+// Renames with a gopls instance that is stopped at the end.
+func goplsRename(ed *core.Editor) renameFunc {
+	return func(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, to string) (*lsproto.WorkspaceEdit, error) {
+		man := lsproto.NewManager(ed.Message)
+		reg := &lsproto.Registration{Language: "go", Exts: []string{".go"}, Network: "stdio", Cmd: "gopls serve"}
+		if err := man.Register(reg); err != nil {
+			return nil, err
+		}
+		defer man.Stop()
+		return man.TextDocumentRename(ctx, filename, rd, offset, to)
 	}
+}
 
-	// TODO: reload all changed files (check stdout)
-
-	reloadOnNoErr := func(err error) {
-		if err == nil {
-			erow.Ed.UI.RunOnUIGoRoutine(func() {
-				if err := erow.Reload(); err != nil {
-					erow.Ed.Error(err)
-				}
-			})
+// Renames with "gorename -d" (diff output). The go files with edits in rows are given as modified files. UI goroutine (the returned func is not).
+func gorenameDiff(ed *core.Editor, otherArgs []string) renameFunc {
+	overlay := goModifiedArchive(ed)
+	return func(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, to string) (*lsproto.WorkspaceEdit, error) {
+		offsetStr := fmt.Sprintf("%v:#%v", filename, offset)
+		cargs := []string{"-d", "-modified", "-offset", offsetStr, "-to", to}
+		cargs = append(cargs, otherArgs...)
+		cmd := exec.CommandContext(ctx, osutil.ExecName("gorename"), cargs...)
+		cmd.Stdin = bytes.NewReader(overlay)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		out, err := cmd.Output()
+		if err != nil && len(out) == 0 {
+			if s := strings.TrimSpace(stderr.String()); s != "" {
+				return nil, fmt.Errorf("gorename: %w: %v", err, s)
+			}
+			return nil, fmt.Errorf("gorename: %w", err)
 		}
+		return lsproto.UnifiedDiffToWorkspaceEdit(out)
 	}
+}
 
-	core.ExternalCmdFromArgs(erow, cargs, reloadOnNoErr, nil)
-
-	return nil
+// Archive of modified files (format: filename, size, content), as read by the "-modified" flag of the go tools.
+func goModifiedArchive(ed *core.Editor) []byte {
+	buf := &bytes.Buffer{}
+	for _, info := range ed.ERowInfos() {
+		if !info.IsFileButNotDir() || !strings.HasSuffix(info.Name(), ".go") || len(info.ERows) == 0 {
+			continue
+		}
+		if !info.HasRowState(ui.RowStateEdited) {
+			continue
+		}
+		b, err := iorw.ReadFullCopy(info.ERows[0].Row.TextArea.RW())
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(buf, "%s\n%d\n", info.Name(), len(b))
+		buf.Write(b)
+	}
+	return buf.Bytes()
 }
@@ -0,0 +1,12 @@
+package internalcmds
+
+import (
+	"github.com/friedelschoen/editor/core"
+)
+
+func History(args *core.InternalCmdArgs) error {
+	erow, _ := args.ERow()
+	query := args.Part.FromArgString(1)
+	args.Ed.CmdHistory.Show(erow, query)
+	return nil
+}
@@ -56,14 +56,47 @@ func init() {
 	cmd(GoRename, "GoRename") // TODO: deprecate
 
 	cmd(LSProtoCloseAll, "LsprotoCloseAll", "LSProtoCloseAll") // TODO: deprecate LSProtoCloseAll
+	cmd(LSProtoStatus, "LsprotoStatus")
+	cmd(LSProtoTrace, "LsprotoTrace")
 	cmd(LSProtoRename, "LsprotoRename")
+	cmd(RenameApply, "RenameApply")
+	cmd(LSProtoFormat, "LsprotoFormat")
+	cmd(LSProtoCodeActions, "LsprotoCodeActions")
+	cmd(LSProtoOutline, "LsprotoOutline")
+	cmd(LSProtoTypeDefinition, "LsprotoTypeDefinition")
+	cmd(LSProtoDeclaration, "LsprotoDeclaration")
 	cmd(LSProtoReferences, "LsprotoReferences")
+	cmd(LSProtoDiagnostics, "LsprotoDiagnostics")
+	cmd(LSProtoSymbols, "LsprotoSymbols")
 	cmd(LSProtoCallHierarchyIncomingCalls, "LsprotoCallers", "LsprotoCallHierarchyIncomingCalls")
 	cmd(LSProtoCallHierarchyOutgoingCalls, "LsprotoCallees", "LsprotoCallHierarchyOutgoingCalls")
+	cmd(LSProtoSupertypes, "LsprotoSupertypes")
+	cmd(LSProtoSubtypes, "LsprotoSubtypes")
+
+	cmd(History, "History")
+
+	cmd(Jobs, "Jobs")
+	cmd(JobsFocus, "JobsFocus")
+	cmd(JobsInt, "JobsInt")
+	cmd(JobsTerm, "JobsTerm")
+	cmd(JobsKill, "JobsKill")
+	cmd(JobsRestart, "JobsRestart")
+
+	cmd(DAPStart, "DapStart")
+	cmd(DAPStop, "DapStop")
+	cmd(DAPContinue, "DapContinue")
+	cmd(DAPNext, "DapNext")
+	cmd(DAPStepIn, "DapStepIn")
+	cmd(DAPStepOut, "DapStepOut")
+	cmd(DAPBreakpoint, "DapBreakpoint")
+	cmd(DAPStack, "DapStack")
+	cmd(DAPVariables, "DapVariables")
 
 	cmd(ColorTheme, "ColorTheme")
 
 	cmd(CtxutilCallsState, "CtxutilCallsState")
 
 	cmd(sortTextLines, "SortTextLines")
+	cmd(ExpandSelection, "ExpandSelection")
+	cmd(ShrinkSelection, "ShrinkSelection")
 }
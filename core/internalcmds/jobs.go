@@ -0,0 +1,105 @@
+package internalcmds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+func Jobs(args *core.InternalCmdArgs) error {
+	args.Ed.Jobs.Show(true)
+	return nil
+}
+
+func JobsFocus(args *core.InternalCmdArgs) error {
+	job, err := jobArg(args)
+	if err != nil {
+		return err
+	}
+	return args.Ed.Jobs.Focus(job)
+}
+
+func JobsInt(args *core.InternalCmdArgs) error {
+	return jobsSignal(args, syscall.SIGINT)
+}
+func JobsTerm(args *core.InternalCmdArgs) error {
+	return jobsSignal(args, syscall.SIGTERM)
+}
+func JobsKill(args *core.InternalCmdArgs) error {
+	return jobsSignal(args, syscall.SIGKILL)
+}
+
+func JobsRestart(args *core.InternalCmdArgs) error {
+	job, err := jobArg(args)
+	if err != nil {
+		return err
+	}
+	args.Ed.Jobs.Restart(job)
+	return nil
+}
+
+//----------
+
+func jobsSignal(args *core.InternalCmdArgs, sig syscall.Signal) error {
+	job, err := jobArg(args)
+	if err != nil {
+		return err
+	}
+	return args.Ed.Jobs.Signal(job, sig)
+}
+
+// Job from the id argument, or from the cursor line in the jobs row, or the last job running in the row.
+func jobArg(args *core.InternalCmdArgs) (*core.Job, error) {
+	jobs := args.Ed.Jobs
+	args2 := args.Part.Args[1:]
+	if len(args2) > 1 {
+		return nil, fmt.Errorf("expecting at most 1 argument")
+	}
+	if len(args2) == 1 {
+		id, err := strconv.Atoi(args2[0].String())
+		if err != nil {
+			return nil, err
+		}
+		job, ok := jobs.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("job not found: %v", id)
+		}
+		return job, nil
+	}
+
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return nil, err
+	}
+	if erow.Info.IsSpecial() && erow.Info.Name() == core.JobsRowName {
+		ta := erow.Row.TextArea
+		rd := ta.RW()
+		s, e, _, err := iorw.LinesIndexes(rd, ta.CursorIndex(), ta.CursorIndex())
+		if err != nil {
+			return nil, err
+		}
+		b, err := rd.ReadFastAt(s, e-s)
+		if err != nil {
+			return nil, err
+		}
+		u := strings.SplitN(string(b), "\t", 2)
+		id, err := strconv.Atoi(u[0])
+		if err != nil {
+			return nil, fmt.Errorf("no job at the cursor line")
+		}
+		job, ok := jobs.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("job not found: %v", id)
+		}
+		return job, nil
+	}
+	job, ok := jobs.RunningInERow(erow)
+	if !ok {
+		return nil, fmt.Errorf("no job running in the row")
+	}
+	return job, nil
+}
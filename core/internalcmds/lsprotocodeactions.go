@@ -0,0 +1,61 @@
+package internalcmds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/friedelschoen/editor/core"
+)
+
+func LSProtoCodeActions(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	// actions for the selection, or at the cursor
+	ta := erow.Row.TextArea
+	offset, n := ta.CursorIndex(), 0
+	if a, b, ok := ta.Cursor().SelectionIndexes(); ok {
+		offset, n = a, b-a
+	}
+
+	// timeout for the cmd to run
+	ctx, cancel := context.WithTimeout(args.Ctx, 8*time.Second)
+	defer cancel()
+
+	filename := erow.Info.Name()
+	actions, err := args.Ed.LSProtoMan.TextDocumentCodeAction(ctx, filename, ta.RW(), offset, n)
+	if err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		return fmt.Errorf("no code actions")
+	}
+
+	items := []string{}
+	for _, ca := range actions {
+		s := ca.Title
+		if ca.Kind != "" {
+			s += fmt.Sprintf(" (%v)", ca.Kind)
+		}
+		if ca.DisabledReason != "" {
+			s += fmt.Sprintf(" [disabled: %v]", ca.DisabledReason)
+		}
+		items = append(items, s)
+	}
+
+	// choose action in the context float box
+	args.Ed.ShowInfoFloatBoxChoices(ta, items, func(i int) {
+		if i < 0 || i >= len(actions) {
+			return
+		}
+		args.Ed.LSProtoApplyCodeActionAsync(erow, actions[i])
+	})
+	return nil
+}
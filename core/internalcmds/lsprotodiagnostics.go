@@ -0,0 +1,58 @@
+package internalcmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+func LSProtoDiagnostics(args *core.InternalCmdArgs) error {
+	ed := args.Ed
+
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	// create new erow to run on
+	dir := filepath.Dir(erow.Info.Name())
+	info := erow.Ed.ReadERowInfo(dir)
+	erow2 := core.NewBasicERow(info, erow.Row.PosBelow())
+	iorw.Append(erow2.Row.Toolbar.RW(), []byte(" | Stop"))
+	erow2.Flash()
+
+	rd, err := erow.ContentCopy()
+	if err != nil {
+		return err
+	}
+	filename := erow.Info.Name()
+
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+
+		// starts the servers of the file (diagnostics are published asynchronously)
+		if err := ed.LSProtoMan.SyncText(ctx, filename, rd); err != nil {
+			return err
+		}
+
+		diags := ed.LSProtoMan.Diagnostics(filename)
+		fmt.Fprintf(rw, "lsproto diagnostics:")
+		if len(diags) == 0 {
+			fmt.Fprintf(rw, " no results\n")
+			return nil
+		}
+		fmt.Fprintf(rw, "\n%v", lsproto.DiagnosticsToString(filename, diags, erow2.Info.Dir()))
+		return nil
+	})
+
+	return nil
+}
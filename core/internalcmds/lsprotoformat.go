@@ -0,0 +1,46 @@
+package internalcmds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
+)
+
+func LSProtoFormat(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	// timeout for the cmd to run
+	ctx, cancel := context.WithTimeout(args.Ctx, 8*time.Second)
+	defer cancel()
+
+	// format the selection if there is one, otherwise the whole file
+	ta := erow.Row.TextArea
+	filename := erow.Info.Name()
+	edits := []*lsproto.TextEdit{}
+	if a, b, ok := ta.Cursor().SelectionIndexes(); ok {
+		edits, err = args.Ed.LSProtoMan.TextDocumentRangeFormatting(ctx, filename, ta.RW(), a, b-a)
+	} else {
+		edits, err = args.Ed.LSProtoMan.TextDocumentFormatting(ctx, filename, ta.RW())
+	}
+	if err != nil {
+		return err
+	}
+	if len(edits) == 0 { // already formatted
+		return nil
+	}
+
+	// single undo group
+	ta.BeginUndoGroup()
+	defer ta.EndUndoGroup()
+	return lsproto.PatchTextEditsRW(ta.RW(), edits)
+}
@@ -0,0 +1,83 @@
+package internalcmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/parseutil"
+)
+
+func LSProtoTypeDefinition(args *core.InternalCmdArgs) error {
+	return lsprotoGoTo(args, args.Ed.LSProtoMan.TextDocumentTypeDefinition)
+}
+func LSProtoDeclaration(args *core.InternalCmdArgs) error {
+	return lsprotoGoTo(args, args.Ed.LSProtoMan.TextDocumentDeclaration)
+}
+
+type lsprotoGoToFn func(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, *lsproto.Range, error)
+
+// Opens the location returned by fn (at the cursor index) under the row, with flash.
+func lsprotoGoTo(args *core.InternalCmdArgs, fn lsprotoGoToFn) error {
+	ed := args.Ed
+
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	// timeout for the cmd to run
+	ctx, cancel := context.WithTimeout(args.Ctx, 8*time.Second)
+	defer cancel()
+
+	ta := erow.Row.TextArea
+	filename, rang, err := fn(ctx, erow.Info.Name(), ta.RW(), ta.CursorIndex())
+	if err != nil {
+		return err
+	}
+
+	// content reader
+	var rd iorw.ReaderAt
+	if info, ok := ed.ERowInfo(filename); ok {
+		// file is in memory already
+		if erow0, ok := info.FirstERow(); ok {
+			rd = erow0.Row.TextArea.RW()
+		}
+	}
+	if rd == nil {
+		// read file
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		rd = iorw.NewBytesReadWriterAt(b)
+	}
+
+	// translate range
+	offset, length, err := lsproto.RangeToOffsetLen(rd, rang)
+	if err != nil {
+		return err
+	}
+
+	conf := &core.OpenFileERowConfig{
+		FilePos: &parseutil.FilePos{
+			Filename: filename,
+			Offset:   offset,
+			Len:      length,
+		},
+		RowPos:                erow.Row.PosBelow(), // place the file under the calling row
+		FlashVisibleOffsets:   true,
+		NewIfNotExistent:      true,
+		NewIfOffsetNotVisible: true,
+	}
+	core.OpenFileERow(ed, conf)
+	return nil
+}
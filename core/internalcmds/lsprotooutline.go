@@ -0,0 +1,51 @@
+package internalcmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
+)
+
+func LSProtoOutline(args *core.InternalCmdArgs) error {
+	ed := args.Ed
+
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	// special row to run on (single instance, reused)
+	erow2, isNew := core.ExistingERowOrNewBasic(ed, "+Outline")
+	if isNew {
+		erow2.ToolbarSetStrAfterNameClearHistory(" | Stop")
+	}
+	erow2.Flash()
+
+	filename := erow.Info.Name()
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+
+		ta := erow.Row.TextArea
+		syms, err := ed.LSProtoMan.TextDocumentDocumentSymbol(ctx, filename, ta.RW())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(rw, "lsproto outline: %v", filename)
+		if len(syms) == 0 {
+			fmt.Fprintf(rw, ": no results\n")
+			return nil
+		}
+		fmt.Fprintf(rw, "\n%v", lsproto.DocumentSymbolsToString(syms, filename))
+		return nil
+	})
+
+	return nil
+}
@@ -1,11 +1,14 @@
 package internalcmds
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/friedelschoen/editor/core"
 	"github.com/friedelschoen/editor/core/lsproto"
-	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/iout/iorw"
 )
 
 func LSProtoRename(args *core.InternalCmdArgs) error {
@@ -18,10 +21,6 @@ func LSProtoRename(args *core.InternalCmdArgs) error {
 		return fmt.Errorf("not a file")
 	}
 
-	if erow.Row.HasState(ui.RowStateEdited | ui.RowStateFsDiffer) {
-		return fmt.Errorf("row has edits, save first")
-	}
-
 	args2 := args.Part.Args[1:]
 	if len(args2) < 1 {
 		return fmt.Errorf("expecting at least 1 argument")
@@ -30,39 +29,48 @@ func LSProtoRename(args *core.InternalCmdArgs) error {
 	// new name argument "to"
 	to := args2[len(args2)-1].UnquotedString()
 
-	// before patching, check all affected files are not edited
-	prePatchFn := func(wecs []*lsproto.WorkspaceEditChange) error {
-		for _, wec := range wecs {
-			info, ok := args.Ed.ERowInfo(wec.Filename)
-			if !ok { // erow not open
-				continue
-			}
-			if info.HasRowState(ui.RowStateEdited | ui.RowStateFsDiffer) {
-				return fmt.Errorf("row has edits, save first: %v", info.Name())
-			}
-		}
-		return nil
+	return lsprotoRenamePreview(args.Ed, erow, to, args.Ed.LSProtoMan.TextDocumentRename)
+}
+
+// Shows the changes in the "+RenamePreview" row, to be applied with RenameApply. The rename func runs outside the UI goroutine.
+func lsprotoRenamePreview(ed *core.Editor, erow *core.ERow, to string, renameFn renameFunc) error {
+	erow2, isNew := core.ExistingERowOrNewBasic(ed, "+RenamePreview")
+	if isNew {
+		erow2.ToolbarSetStrAfterNameClearHistory(" | Stop | RenameApply")
 	}
+	erow2.Flash()
 
 	// id offset to rename "from"
+	filename := erow.Info.Name()
 	ta := erow.Row.TextArea
-	wecs, err := args.Ed.LSProtoMan.TextDocumentRenameAndPatch(args.Ctx, erow.Info.Name(), ta.RW(), ta.CursorIndex(), to, prePatchFn)
+	offset := ta.CursorIndex()
+	rd, err := erow.ContentCopy()
 	if err != nil {
 		return err
 	}
 
-	// reload filenames
-	args.Ed.UI.RunOnUIGoRoutine(func() {
-		for _, wec := range wecs {
-			info, ok := args.Ed.ERowInfo(wec.Filename)
-			if !ok { // erow not open
-				continue
-			}
-			if err := info.ReloadFile(); err != nil {
-				args.Ed.Error(err)
-			}
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+
+		we, err := renameFn(ctx, filename, rd, offset, to)
+		if err != nil {
+			return err
 		}
+		buf := &bytes.Buffer{}
+		if err := ed.LSProtoPreviewWorkspaceEdit(we, buf, erow2.Info.Dir()); err != nil {
+			return err
+		}
+		fmt.Fprintf(rw, "rename preview: %q (apply with RenameApply)\n%s", to, buf.Bytes())
+		return nil
 	})
 
 	return nil
 }
+
+type renameFunc func(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, to string) (*lsproto.WorkspaceEdit, error)
+
+//----------
+
+func RenameApply(args *core.InternalCmdArgs) error {
+	return args.Ed.LSProtoApplyPreview()
+}
@@ -0,0 +1,87 @@
+package internalcmds
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/parseutil"
+)
+
+func LSProtoSymbols(args *core.InternalCmdArgs) error {
+	ed := args.Ed
+
+	// setup flagset
+	fs := flag.NewFlagSet("LsprotoSymbols", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // don't output to stderr
+	langFlag := fs.String("lang", "", "language of the lsp instance to query; defaults to the language of the row file")
+	if err := parseFlagSetHandleUsage(args, fs); err != nil {
+		return err
+	}
+
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	// unquote args
+	w := []string{}
+	for _, arg := range fs.Args() {
+		if u, err := parseutil.UnquoteStringBs(arg); err == nil {
+			arg = u
+		}
+		w = append(w, arg)
+	}
+	query := strings.Join(w, " ")
+
+	language := *langFlag
+	erow2 := erow
+	if erow.Info.IsFileButNotDir() {
+		if language == "" {
+			lang, err := ed.LSProtoMan.LangManager(erow.Info.Name())
+			if err != nil {
+				return err
+			}
+			language = lang.Reg.Language
+		}
+
+		// create new erow to run on; the query can be refined from its toolbar
+		dir := filepath.Dir(erow.Info.Name())
+		info := erow.Ed.ReadERowInfo(dir)
+		erow2 = core.NewBasicERow(info, erow.Row.PosBelow())
+		s := fmt.Sprintf(" | Stop | LsprotoSymbols -lang=%s %s", language, query)
+		iorw.Append(erow2.Row.Toolbar.RW(), []byte(s))
+	} else if language == "" {
+		return fmt.Errorf("not a file: missing -lang")
+	}
+	erow2.Flash()
+
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+
+		syms, err := ed.LSProtoMan.WorkspaceSymbol(ctx, language, query)
+		if err != nil {
+			return err
+		}
+
+		str, err := lsproto.SymbolInformationsToString(syms, erow2.Info.Dir())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(rw, "lsproto symbols: %q", query)
+		if len(syms) == 0 {
+			fmt.Fprintf(rw, ": no results\n")
+			return nil
+		}
+		fmt.Fprintf(rw, "\n%v", str)
+		return nil
+	})
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package internalcmds
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/util/iout"
+)
+
+// max size of the trace row content, older messages are removed
+const lsprotoTraceMaxSize = 1024 * 1024
+
+func LSProtoTrace(args *core.InternalCmdArgs) error {
+	ed := args.Ed
+
+	// setup flagset
+	fs := flag.NewFlagSet("LsprotoTrace", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // don't output to stderr
+	langFlag := fs.String("lang", "", "language of the lsp instance to trace; defaults to the language of the row file")
+	if err := parseFlagSetHandleUsage(args, fs); err != nil {
+		return err
+	}
+
+	language := *langFlag
+	if language == "" {
+		erow, err := args.ERowOrErr()
+		if err != nil {
+			return err
+		}
+		if !erow.Info.IsFileButNotDir() {
+			return fmt.Errorf("not a file: missing -lang")
+		}
+		lang, err := ed.LSProtoMan.LangManager(erow.Info.Name())
+		if err != nil {
+			return err
+		}
+		language = lang.Reg.Language
+	}
+
+	// special row to run on (one per language, reused)
+	erow2, isNew := core.ExistingERowOrNewBasic(ed, "+LsprotoTrace-"+language)
+	if isNew {
+		erow2.ToolbarSetStrAfterNameClearHistory(" | Stop")
+	}
+	erow2.Flash()
+
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+
+		// keep the row size capped
+		w := iout.FnWriter(func(b []byte) (int, error) {
+			n, err := rw.Write(b)
+			ed.UI.RunOnUIGoRoutine(func() {
+				if err := erow2.TrimBytesClearHistory(lsprotoTraceMaxSize); err != nil {
+					ed.Error(err)
+				}
+			})
+			return n, err
+		})
+
+		stop, err := ed.LSProtoMan.Trace(language, w)
+		if err != nil {
+			return err
+		}
+		defer stop()
+
+		fmt.Fprintf(rw, "lsproto trace: %v (stop to disable)\n", language)
+		<-ctx.Done()
+		return nil
+	})
+
+	return nil
+}
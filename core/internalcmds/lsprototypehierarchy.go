@@ -0,0 +1,57 @@
+package internalcmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/friedelschoen/editor/core"
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+func LSProtoSupertypes(args *core.InternalCmdArgs) error {
+	return lsprotoTypeHierarchy(args, lsproto.SupertypesTht)
+}
+func LSProtoSubtypes(args *core.InternalCmdArgs) error {
+	return lsprotoTypeHierarchy(args, lsproto.SubtypesTht)
+}
+
+func lsprotoTypeHierarchy(args *core.InternalCmdArgs, typ lsproto.TypeHierarchyType) error {
+	ed := args.Ed
+
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		return fmt.Errorf("not a file")
+	}
+
+	// create new erow to run on
+	dir := filepath.Dir(erow.Info.Name())
+	info := erow.Ed.ReadERowInfo(dir)
+	erow2 := core.NewBasicERow(info, erow.Row.PosBelow())
+	iorw.Append(erow2.Row.Toolbar.RW(), []byte(" | Stop"))
+	erow2.Flash()
+
+	erow2.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		// NOTE: not running in UI goroutine here
+
+		ta := erow.Row.TextArea
+		nodes, err := ed.LSProtoMan.TypeHierarchy(ctx, erow.Info.Name(), ta.RW(), ta.CursorIndex(), typ)
+		if err != nil {
+			return err
+		}
+		str, err := lsproto.ManagerTypeHierarchyToString(nodes, typ, erow2.Info.Dir())
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(rw, str)
+		return nil
+	})
+
+	return nil
+}
@@ -239,6 +239,14 @@ func LSProtoCloseAll(args *core.InternalCmdArgs) error {
 	args.Ed.LSProtoMan.Stop()
 	return nil
 }
+func LSProtoStatus(args *core.InternalCmdArgs) error {
+	s := args.Ed.LSProtoMan.Status()
+	if s == "" {
+		return fmt.Errorf("no registrations")
+	}
+	args.Ed.Messagef("lsproto status:\n%s", s)
+	return nil
+}
 func CtxutilCallsState(args *core.InternalCmdArgs) error {
 	s := ctxutil.CallsState()
 	args.Ed.Messagef("%s", s)
@@ -0,0 +1,23 @@
+package internalcmds
+
+import (
+	"github.com/friedelschoen/editor/core"
+)
+
+func ExpandSelection(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+	args.Ed.SelectionRange.Expand(erow)
+	return nil
+}
+
+func ShrinkSelection(args *core.InternalCmdArgs) error {
+	erow, err := args.ERowOrErr()
+	if err != nil {
+		return err
+	}
+	args.Ed.SelectionRange.Shrink(erow)
+	return nil
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/friedelschoen/editor/util/osutil"
+)
+
+// External commands run in rows (running and recently finished). Jobs are added/finished from the cmds goroutines, the rest is used in the UI goroutine.
+type Jobs struct {
+	ed *Editor
+	mu struct {
+		sync.Mutex
+		q    int // ids
+		jobs []*Job
+	}
+}
+
+func NewJobs(ed *Editor) *Jobs {
+	return &Jobs{ed: ed}
+}
+
+//----------
+
+func (jobs *Jobs) add(erow *ERow, args, env []string, cmd *exec.Cmd) *Job {
+	jobs.mu.Lock()
+	jobs.mu.q++
+	job := &Job{
+		Id:    jobs.mu.q,
+		Pid:   cmd.Process.Pid,
+		ERow:  erow,
+		Dir:   erow.Info.Name(),
+		Args:  args,
+		Start: time.Now(),
+		env:   env,
+		cmd:   cmd,
+	}
+	jobs.mu.jobs = append(jobs.mu.jobs, job)
+	jobs.mu.Unlock()
+
+	jobs.updateRow()
+	return job
+}
+
+func (jobs *Jobs) finish(job *Job, err error) {
+	jobs.mu.Lock()
+	job.end = time.Now()
+	job.err = err
+	job.done = true
+	jobs.prune()
+	jobs.mu.Unlock()
+
+	jobs.updateRow()
+}
+
+// Keeps only the recently finished jobs. Locked by caller.
+func (jobs *Jobs) prune() {
+	n := 0
+	for i := len(jobs.mu.jobs) - 1; i >= 0; i-- {
+		if jobs.mu.jobs[i].done {
+			n++
+			if n > maxFinishedJobs {
+				jobs.mu.jobs = slices.Delete(jobs.mu.jobs, i, i+1)
+			}
+		}
+	}
+}
+
+//----------
+
+func (jobs *Jobs) Get(id int) (*Job, bool) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	for _, job := range jobs.mu.jobs {
+		if job.Id == id {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+// Last job started in the row that is still running.
+func (jobs *Jobs) RunningInERow(erow *ERow) (*Job, bool) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	for i := len(jobs.mu.jobs) - 1; i >= 0; i-- {
+		job := jobs.mu.jobs[i]
+		if job.ERow == erow && !job.done {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+//----------
+
+// Sends the signal to the job process group.
+func (jobs *Jobs) Signal(job *Job, sig syscall.Signal) error {
+	jobs.mu.Lock()
+	done := job.done
+	jobs.mu.Unlock()
+	if done {
+		return fmt.Errorf("job %d is not running", job.Id)
+	}
+	return osutil.SignalExecCmd(job.cmd, sig)
+}
+
+// Runs the job cmd again in its row (a new row is created if the row was closed). The job is stopped first, unless the row runs cmds in parallel.
+func (jobs *Jobs) Restart(job *Job) {
+	erow := job.ERow
+	if erow.ctx.Err() != nil { // row was closed
+		info := jobs.ed.ReadERowInfo(job.Dir)
+		if !info.IsDir() {
+			jobs.ed.Errorf("job %d: not a directory: %v", job.Id, job.Dir)
+			return
+		}
+		erow = NewBasicERow(info, jobs.ed.GoodRowPos())
+	}
+	externalCmdFromDir(erow, job.Args, nil, job.env)
+	erow.Flash()
+}
+
+// Shows the job row.
+func (jobs *Jobs) Focus(job *Job) error {
+	erow := job.ERow
+	if erow.ctx.Err() != nil {
+		return fmt.Errorf("job %d: row was closed", job.Id)
+	}
+	erow.Row.EnsureTextAreaMinimumHeight()
+	erow.Row.EnsureOneToolbarLineYVisible()
+	erow.Flash()
+	return nil
+}
+
+//----------
+
+func (jobs *Jobs) String() string {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	sb := &strings.Builder{}
+	now := time.Now()
+	for _, job := range jobs.mu.jobs {
+		fmt.Fprintf(sb, "%d\t%s\tpid %d\t%s\t%s: %s\n", job.Id, job.status(now), job.Pid, job.Start.Format("15:04:05"), job.Dir, strings.Join(job.Args, " "))
+	}
+	return sb.String()
+}
+
+//----------
+
+// Lists the jobs in the "+Jobs" row. If create is false, only updates an existing row.
+func (jobs *Jobs) Show(create bool) {
+	if !create {
+		if _, ok := jobs.ed.ReadERowInfo(JobsRowName).FirstERow(); !ok {
+			return
+		}
+	}
+	erow, isNew := ExistingERowOrNewBasic(jobs.ed, JobsRowName)
+	if isNew {
+		erow.ToolbarSetStrAfterNameClearHistory(" | Reload | JobsFocus | JobsInt | JobsTerm | JobsKill | JobsRestart")
+	}
+	if create {
+		erow.Flash()
+	}
+	erow.Exec.RunAsync(func(ctx context.Context, rw io.ReadWriter) error {
+		_, err := fmt.Fprintf(rw, "jobs:\n%s", jobs.String())
+		return err
+	})
+}
+
+// Safe to use concurrently.
+func (jobs *Jobs) updateRow() {
+	jobs.ed.UI.RunOnUIGoRoutine(func() {
+		jobs.Show(false)
+	})
+}
+
+//----------
+
+const JobsRowName = "+Jobs" // row listing the jobs
+const maxFinishedJobs = 20
+
+//----------
+
+type Job struct {
+	Id    int
+	Pid   int
+	ERow  *ERow
+	Dir   string
+	Args  []string
+	Start time.Time
+
+	env []string
+	cmd *exec.Cmd
+
+	// locked by jobs
+	end  time.Time
+	err  error
+	done bool
+}
+
+func (job *Job) status(now time.Time) string {
+	if !job.done {
+		return fmt.Sprintf("running %v", now.Sub(job.Start).Round(time.Second))
+	}
+	dur := job.end.Sub(job.Start).Round(time.Millisecond)
+	if job.err == nil {
+		return fmt.Sprintf("done %v", dur)
+	}
+	ee := &exec.ExitError{}
+	if errors.As(job.err, &ee) {
+		return fmt.Sprintf("%v %v", ee.ProcessState, dur) // ex: "exit status 1", "signal: killed"
+	}
+	return fmt.Sprintf("error %v: %v", dur, job.err)
+}
@@ -7,24 +7,25 @@ import (
 	"io"
 	"net"
 	"net/rpc"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/friedelschoen/editor/util/ctxutil"
 	"github.com/friedelschoen/editor/util/iout"
-	"github.com/friedelschoen/editor/util/iout/iorw"
 )
 
 type Client struct {
 	rcli         *rpc.Client
+	cc           *JsonCodec
 	li           *LangInstance
 	readLoopDone chan error
 
 	lock struct {
 		sync.Mutex
 		fversions map[string]int
-		//folders   []*WorkspaceFolder
+		folders   map[string]bool // workspace folders dirs known by the server
 	}
 
 	serverCapabilities struct {
@@ -32,7 +33,19 @@ type Client struct {
 			folders bool
 			symbol  bool
 		}
-		rename bool
+		rename            bool
+		inlayHint         bool
+		documentHighlight bool
+		selectionRange    bool
+		codeLensResolve   bool
+		codeActionResolve bool
+		features          map[string]bool // see featureProviders
+		sync              struct {
+			openClose bool
+			change    TextDocumentSyncKind
+			save      bool
+			saveText  bool
+		}
 	}
 }
 
@@ -53,11 +66,15 @@ func NewClientTCP(ctx context.Context, addr string, li *LangInstance) (*Client,
 func NewClientIO(ctx context.Context, rwc io.ReadWriteCloser, li *LangInstance) *Client {
 	cli := &Client{li: li}
 	cli.lock.fversions = map[string]int{}
+	cli.lock.folders = map[string]bool{}
 
 	cc := NewJsonCodec(rwc)
 	cc.OnNotificationMessage = cli.onNotificationMessage
 	cc.OnUnexpectedServerReply = cli.onUnexpectedServerReply
+	cc.OnServerRequest = cli.onServerRequest
+	cc.OnTraceMessage = li.lang.traceMessage
 
+	cli.cc = cc
 	cli.rcli = rpc.NewClientWithCodec(cc)
 
 	// wait for the codec readloop
@@ -162,6 +179,16 @@ func (cli *Client) onNotificationMessage(msg *NotificationMessage) {
 				cli.li.lang.PrintWrapError(err)
 			}
 		}
+	case "textDocument/publishDiagnostics":
+		opt := &PublishDiagnosticsParams{}
+		if err := decodeJsonRaw(msg.Params.raw, opt); err != nil {
+			return
+		}
+		filename, err := UrlToAbsFilename(string(opt.Uri))
+		if err != nil {
+			return
+		}
+		cli.li.lang.man.setDiagnostics(cli.li.lang, filename, opt.Diagnostics)
 	}
 }
 
@@ -185,6 +212,56 @@ func (cli *Client) onUnexpectedServerReply(resp *Response) {
 	}
 }
 
+func (cli *Client) onServerRequest(resp *Response) {
+	// run async: this runs in the codec read loop, and handling the request might need other msgs from the server (ex: applyedit doing a didopen)
+	go func() {
+		result, rerr := cli.handleServerRequest(resp.Method, resp.NotificationMessage.Params.raw)
+		if err := cli.cc.WriteServerReply(resp.Id, result, rerr); err != nil {
+			err = fmt.Errorf("server request reply: %v: %w", resp.Method, err)
+			cli.li.lang.PrintWrapError(err)
+		}
+	}()
+}
+func (cli *Client) handleServerRequest(method string, params json.RawMessage) (any, *ResponseError) {
+	switch method {
+	case "workspace/applyEdit":
+		// https://microsoft.github.io/language-server-protocol/specification#workspace_applyEdit
+		opt := &ApplyWorkspaceEditParams{}
+		if err := decodeJsonRaw(params, opt); err != nil {
+			return nil, &ResponseError{Code: -32602, Message: err.Error()} // invalid params
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		result := &ApplyWorkspaceEditResult{Applied: true}
+		if err := cli.li.lang.man.applyWorkspaceEdit(ctx, &opt.Edit); err != nil {
+			result.Applied = false
+			result.FailureReason = err.Error()
+		}
+		return result, nil
+	case "workspace/workspaceFolders":
+		// https://microsoft.github.io/language-server-protocol/specification#workspace_workspaceFolders
+		folders, err := workspaceFolders(cli.folderDirs())
+		if err != nil {
+			return nil, &ResponseError{Code: -32603, Message: err.Error()} // internal error
+		}
+		return folders, nil
+	case "workspace/configuration":
+		// no configuration: reply with a null entry per requested item
+		opt := &struct {
+			Items []any `json:"items"`
+		}{}
+		_ = decodeJsonRaw(params, opt)
+		return make([]any, len(opt.Items)), nil
+	case "client/registerCapability",
+		"client/unregisterCapability",
+		"window/workDoneProgress/create",
+		"workspace/codeLens/refresh":
+		return nil, nil
+	default:
+		return nil, &ResponseError{Code: -32601, Message: "method not found: " + method}
+	}
+}
+
 //----------
 
 func (cli *Client) Initialize(ctx context.Context) error {
@@ -208,56 +285,41 @@ func (cli *Client) Initialize(ctx context.Context) error {
 }
 
 func (cli *Client) initializeParams() (json.RawMessage, error) {
-	opt := []string{"\"capabilities\":{}"}
-
-	//	rootUri, err := cli.rootUri()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	_ = rootUri
-
-	//	// workspace folders
-	//	cli.folders = []*WorkspaceFolder{{Uri: rootUri}}
-	//	foldersBytes, err := encodeJson(cli.folders)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-
-	//	// other capabilities
-	//	//"capabilities":{
-	//	//	"workspace":{
-	//	//		"configuration":true,
-	//	//		"workspaceFolders":true
-	//	//	},
-	//	//	"textDocument":{
-	//	//		"publishDiagnostics":{
-	//	//			"relatedInformation":true
-	//	//		}
-	//	//	}
-	//	//}
-
-	//	raw := json.RawMessage("{" +
-	//		// TODO: gopls is not allowing rooturi=null at the moment...
-	//		fmt.Sprintf("%q:%q", "rootUri", rootUri) + "," +
-	//		// set workspace folders to use the later as "remove" value
-	//		fmt.Sprintf("%q:%s", "workspaceFolders", foldersBytes) +
-	//		"}")
+	opt := []string{"\"capabilities\":{\"workspace\":{\"applyEdit\":true,\"workspaceFolders\":true},\"textDocument\":{\"completion\":{\"completionItem\":{\"snippetSupport\":true,\"documentationFormat\":[\"plaintext\"]}},\"codeAction\":{\"dataSupport\":true,\"resolveSupport\":{\"properties\":[\"edit\"]}}}}"}
+
+	// workspace folders, the first is the root
+	if dirs := cli.li.initFolders; len(dirs) > 0 {
+		folders, err := workspaceFolders(dirs)
+		if err != nil {
+			return nil, err
+		}
+		b, err := encodeJson(folders)
+		if err != nil {
+			return nil, err
+		}
+		opt = append(opt,
+			fmt.Sprintf("%q:%q", "rootUri", folders[0].Uri),
+			fmt.Sprintf("%q:%s", "workspaceFolders", b))
+
+		cli.lock.Lock()
+		for _, dir := range dirs {
+			cli.lock.folders[dir] = true
+		}
+		cli.lock.Unlock()
+	}
+
+	// server specific (ex: gopls has inlay hints disabled by default)
+	if b := cli.li.lang.Reg.InitOptions; len(b) > 0 {
+		if !json.Valid(b) {
+			return nil, fmt.Errorf("invalid initializationOptions: %s", b)
+		}
+		opt = append(opt, fmt.Sprintf("%q:%s", "initializationOptions", b))
+	}
 
 	raw := "{" + strings.Join(opt, ",") + "}"
 	return json.RawMessage(raw), nil
 }
 
-//func (cli *Client) rootUri() (DocumentUri, error) {
-//	// using a non-existent dir to prevent an lsp server to start scanning the user disk doesn't work well (ex: gopls gives "no views in the session" after the cache is gone)
-//	// use initial request file
-//	dir := filepath.Dir(cli.li.lang.InstanceReqFilename)
-//	rootUrl, err := absFilenameToUrl(dir)
-//	if err != nil {
-//		return "", err
-//	}
-//	return DocumentUri(rootUrl), nil
-//}
-
 func (cli *Client) readServerCapabilities(caps any) {
 	path := "capabilities.workspace.workspaceFolders.supported"
 	v, err := JsonGetPath(caps, path)
@@ -282,6 +344,135 @@ func (cli *Client) readServerCapabilities(caps any) {
 			cli.serverCapabilities.rename = true
 		}
 	}
+
+	// can be: boolean | InlayHintOptions | InlayHintRegistrationOptions
+	path = "capabilities.inlayHintProvider"
+	v, err = JsonGetPath(caps, path)
+	if err == nil {
+		cli.serverCapabilities.inlayHint = jsonBoolOrOptions(v)
+	}
+
+	// can be: boolean | DocumentHighlightOptions
+	path = "capabilities.documentHighlightProvider"
+	v, err = JsonGetPath(caps, path)
+	if err == nil {
+		cli.serverCapabilities.documentHighlight = jsonBoolOrOptions(v)
+	}
+
+	// can be: boolean | SelectionRangeOptions | SelectionRangeRegistrationOptions
+	path = "capabilities.selectionRangeProvider"
+	v, err = JsonGetPath(caps, path)
+	if err == nil {
+		cli.serverCapabilities.selectionRange = jsonBoolOrOptions(v)
+	}
+
+	// CodeLensOptions
+	path = "capabilities.codeLensProvider.resolveProvider"
+	v, err = JsonGetPath(caps, path)
+	if err == nil {
+		if b, ok := v.(bool); ok && b {
+			cli.serverCapabilities.codeLensResolve = true
+		}
+	}
+
+	// CodeActionOptions
+	path = "capabilities.codeActionProvider.resolveProvider"
+	v, err = JsonGetPath(caps, path)
+	if err == nil {
+		if b, ok := v.(bool); ok && b {
+			cli.serverCapabilities.codeActionResolve = true
+		}
+	}
+
+	cli.readServerSyncCapabilities(caps)
+	cli.readServerFeatures(caps)
+}
+
+func (cli *Client) readServerSyncCapabilities(caps any) {
+	sc := &cli.serverCapabilities.sync
+
+	// not present: open/close with full content (the spec default is none, but most servers read the open documents)
+	sc.openClose = true
+	sc.change = TdskFull
+
+	// can be: TextDocumentSyncKind | TextDocumentSyncOptions
+	path := "capabilities.textDocumentSync"
+	v, err := JsonGetPath(caps, path)
+	if err != nil {
+		return
+	}
+	switch t := v.(type) {
+	case float64:
+		sc.change = TextDocumentSyncKind(t)
+		sc.openClose = sc.change != TdskNone
+	case map[string]any:
+		// options fields default to false/none
+		sc.openClose, _ = t["openClose"].(bool)
+		sc.change = TdskNone
+		if f, ok := t["change"].(float64); ok {
+			sc.change = TextDocumentSyncKind(f)
+		}
+		// can be: boolean | SaveOptions
+		switch t2 := t["save"].(type) {
+		case bool:
+			sc.save = t2
+		case map[string]any:
+			sc.save = true
+			sc.saveText, _ = t2["includeText"].(bool)
+		}
+	}
+}
+
+//----------
+
+// Sends only the changes not yet known by the server. Does nothing if the server doesn't support workspace folders.
+func (cli *Client) WorkspaceDidChangeWorkspaceFolders(ctx context.Context, added, removed []string) error {
+	// https://microsoft.github.io/language-server-protocol/specification#workspace_didChangeWorkspaceFolders
+
+	if !cli.serverCapabilities.workspace.folders {
+		return nil
+	}
+
+	cli.lock.Lock()
+	added2, removed2 := []string{}, []string{}
+	for _, dir := range added {
+		if !cli.lock.folders[dir] {
+			cli.lock.folders[dir] = true
+			added2 = append(added2, dir)
+		}
+	}
+	for _, dir := range removed {
+		if cli.lock.folders[dir] {
+			delete(cli.lock.folders, dir)
+			removed2 = append(removed2, dir)
+		}
+	}
+	cli.lock.Unlock()
+	if len(added2) == 0 && len(removed2) == 0 {
+		return nil
+	}
+
+	ev := &WorkspaceFoldersChangeEvent{}
+	var err error
+	if ev.Added, err = workspaceFolders(added2); err != nil {
+		return err
+	}
+	if ev.Removed, err = workspaceFolders(removed2); err != nil {
+		return err
+	}
+	opt := &DidChangeWorkspaceFoldersParams{Event: ev}
+	return cli.Call(ctx, "noreply:workspace/didChangeWorkspaceFolders", opt, nil)
+}
+
+func (cli *Client) folderDirs() []string {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+	w := []string{}
+	for dir := range cli.lock.folders {
+		w = append(w, dir)
+	}
+	sort.Strings(w)
+	return w
 }
 
 //----------
@@ -341,7 +532,7 @@ func (cli *Client) TextDocumentDidClose(ctx context.Context, filename string) er
 	return cli.Call(ctx, "noreply:textDocument/didClose", opt, nil)
 }
 
-func (cli *Client) TextDocumentDidChange(ctx context.Context, filename, text string, version int) error {
+func (cli *Client) TextDocumentDidChange(ctx context.Context, filename string, version int, changes []*TextDocumentContentChangeEvent) error {
 	// https://microsoft.github.io/language-server-protocol/specification#textDocument_didChange
 
 	opt := &DidChangeTextDocumentParams{}
@@ -351,25 +542,7 @@ func (cli *Client) TextDocumentDidChange(ctx context.Context, filename, text str
 		return err
 	}
 	opt.TextDocument.Uri = DocumentUri(url)
-
-	// text end line/column
-	rd := iorw.NewStringReaderAt(text)
-	pos, err := OffsetToPosition(rd, len(text))
-	if err != nil {
-		return err
-	}
-
-	// changes
-	opt.ContentChanges = []*TextDocumentContentChangeEvent{
-		{
-			Range: Range{
-				Start: Position{0, 0},
-				End:   pos,
-			},
-			//RangeLength: len(text), // TODO: not working?
-			Text: text,
-		},
-	}
+	opt.ContentChanges = changes
 	return cli.Call(ctx, "noreply:textDocument/didChange", opt, nil)
 }
 
@@ -389,32 +562,28 @@ func (cli *Client) TextDocumentDidSave(ctx context.Context, filename string, tex
 
 //----------
 
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_definition
 func (cli *Client) TextDocumentDefinition(ctx context.Context, filename string, pos Position) (*Location, error) {
-	// https://microsoft.github.io/language-server-protocol/specification#textDocument_definition
-
-	opt := &TextDocumentPositionParams{}
-	opt.Position = pos
-	url, err := AbsFilenameToUrl(filename)
-	if err != nil {
-		return nil, err
-	}
-	opt.TextDocument.Uri = DocumentUri(url)
+	return cli.textDocumentLocation(ctx, "textDocument/definition", filename, pos)
+}
 
-	result := []*Location{}
-	if err := cli.Call(ctx, "textDocument/definition", opt, &result); err != nil {
-		return nil, err
-	}
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no results")
-	}
-	return result[0], nil // first result only
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_implementation
+func (cli *Client) TextDocumentImplementation(ctx context.Context, filename string, pos Position) (*Location, error) {
+	return cli.textDocumentLocation(ctx, "textDocument/implementation", filename, pos)
 }
 
-//----------
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_typeDefinition
+func (cli *Client) TextDocumentTypeDefinition(ctx context.Context, filename string, pos Position) (*Location, error) {
+	return cli.textDocumentLocation(ctx, "textDocument/typeDefinition", filename, pos)
+}
 
-func (cli *Client) TextDocumentImplementation(ctx context.Context, filename string, pos Position) (*Location, error) {
-	// https://microsoft.github.io/language-server-protocol/specification#textDocument_implementation
+// https://microsoft.github.io/language-server-protocol/specification#textDocument_declaration
+func (cli *Client) TextDocumentDeclaration(ctx context.Context, filename string, pos Position) (*Location, error) {
+	return cli.textDocumentLocation(ctx, "textDocument/declaration", filename, pos)
+}
 
+// Position request answered with locations (ex: definition). Returns the first location.
+func (cli *Client) textDocumentLocation(ctx context.Context, method, filename string, pos Position) (*Location, error) {
 	opt := &TextDocumentPositionParams{}
 	opt.Position = pos
 	url, err := AbsFilenameToUrl(filename)
@@ -424,7 +593,7 @@ func (cli *Client) TextDocumentImplementation(ctx context.Context, filename stri
 	opt.TextDocument.Uri = DocumentUri(url)
 
 	result := []*Location{}
-	if err := cli.Call(ctx, "textDocument/implementation", opt, &result); err != nil {
+	if err := cli.Call(ctx, method, opt, &result); err != nil {
 		return nil, err
 	}
 	if len(result) == 0 {
@@ -458,8 +627,19 @@ func (cli *Client) TextDocumentCompletion(ctx context.Context, filename string,
 //----------
 
 func (cli *Client) TextDocumentDidOpenVersion(ctx context.Context, filename string, b []byte) error {
+	v := cli.nextVersion(filename)
+	return cli.TextDocumentDidOpen(ctx, filename, string(b), v)
+}
+
+func (cli *Client) TextDocumentDidChangeVersion(ctx context.Context, filename string, changes []*TextDocumentContentChangeEvent) error {
+	v := cli.nextVersion(filename)
+	return cli.TextDocumentDidChange(ctx, filename, v, changes)
+}
 
+// Versions keep increasing, even after a document is closed and opened again.
+func (cli *Client) nextVersion(filename string) int {
 	cli.lock.Lock()
+	defer cli.lock.Unlock()
 	v, ok := cli.lock.fversions[filename]
 	if !ok {
 		v = 1
@@ -467,9 +647,7 @@ func (cli *Client) TextDocumentDidOpenVersion(ctx context.Context, filename stri
 		v++
 	}
 	cli.lock.fversions[filename] = v
-	cli.lock.Unlock()
-
-	return cli.TextDocumentDidOpen(ctx, filename, string(b), v)
+	return v
 }
 
 //----------
@@ -568,6 +746,39 @@ func (cli *Client) CallHierarchyCalls(ctx context.Context, typ CallHierarchyCall
 
 //----------
 
+func (cli *Client) TextDocumentPrepareTypeHierarchy(ctx context.Context, filename string, pos Position) ([]*TypeHierarchyItem, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_prepareTypeHierarchy
+
+	opt := &TypeHierarchyPrepareParams{}
+	opt.Position = pos
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+	result := []*TypeHierarchyItem{}
+	err = cli.Call(ctx, "textDocument/prepareTypeHierarchy", opt, &result)
+	return result, err
+}
+func (cli *Client) TypeHierarchyTypes(ctx context.Context, typ TypeHierarchyType, item *TypeHierarchyItem) ([]*TypeHierarchyItem, error) {
+	method := ""
+	switch typ {
+	case SupertypesTht:
+		method = "typeHierarchy/supertypes"
+	case SubtypesTht:
+		method = "typeHierarchy/subtypes"
+	default:
+		panic("bad type")
+	}
+	opt := &TypeHierarchyParams{}
+	opt.Item = item
+	result := []*TypeHierarchyItem{}
+	err := cli.Call(ctx, method, opt, &result)
+	return result, err
+}
+
+//----------
+
 func (cli *Client) TextDocumentReferences(ctx context.Context, filename string, pos Position) ([]*Location, error) {
 	opt := &ReferenceParams{}
 	opt.Context.IncludeDeclaration = true
@@ -581,3 +792,293 @@ func (cli *Client) TextDocumentReferences(ctx context.Context, filename string,
 	err = cli.Call(ctx, "textDocument/references", opt, &result)
 	return result, err
 }
+
+//----------
+
+func (cli *Client) TextDocumentHover(ctx context.Context, filename string, pos Position) (*Hover, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_hover
+
+	opt := &HoverParams{}
+	opt.Position = pos
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := (*Hover)(nil)
+	if err := cli.Call(ctx, "textDocument/hover", opt, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("no results")
+	}
+	return result, nil
+}
+
+//----------
+
+func (cli *Client) TextDocumentSignatureHelp(ctx context.Context, filename string, pos Position) (*SignatureHelp, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_signatureHelp
+
+	opt := &SignatureHelpParams{}
+	opt.Position = pos
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := (*SignatureHelp)(nil)
+	if err := cli.Call(ctx, "textDocument/signatureHelp", opt, &result); err != nil {
+		return nil, err
+	}
+	if result == nil { // not inside a call
+		result = &SignatureHelp{}
+	}
+	return result, nil
+}
+
+//----------
+
+func (cli *Client) TextDocumentInlayHint(ctx context.Context, filename string, rang Range) ([]*InlayHint, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_inlayHint
+
+	if !cli.serverCapabilities.inlayHint {
+		return nil, nil // no error, the server just has no hints
+	}
+
+	opt := &InlayHintParams{}
+	opt.Range = rang
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*InlayHint{}
+	err = cli.Call(ctx, "textDocument/inlayHint", opt, &result)
+	return result, err
+}
+
+//----------
+
+func (cli *Client) TextDocumentDocumentHighlight(ctx context.Context, filename string, pos Position) ([]*DocumentHighlight, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_documentHighlight
+
+	if !cli.serverCapabilities.documentHighlight {
+		return nil, fmt.Errorf("document highlight not supported")
+	}
+
+	opt := &TextDocumentPositionParams{}
+	opt.Position = pos
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*DocumentHighlight{}
+	err = cli.Call(ctx, "textDocument/documentHighlight", opt, &result)
+	return result, err
+}
+
+//----------
+
+func (cli *Client) TextDocumentSelectionRange(ctx context.Context, filename string, pos Position) (*SelectionRange, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_selectionRange
+
+	if !cli.serverCapabilities.selectionRange {
+		return nil, fmt.Errorf("selection range not supported")
+	}
+
+	opt := &SelectionRangeParams{}
+	opt.Positions = []Position{pos}
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*SelectionRange{}
+	if err := cli.Call(ctx, "textDocument/selectionRange", opt, &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 || result[0] == nil {
+		return nil, fmt.Errorf("no results")
+	}
+	return result[0], nil // one position requested
+}
+
+//----------
+
+func (cli *Client) TextDocumentFormatting(ctx context.Context, filename string) ([]*TextEdit, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_formatting
+
+	opt := &DocumentFormattingParams{}
+	opt.Options = defaultFormattingOptions()
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*TextEdit{}
+	err = cli.Call(ctx, "textDocument/formatting", opt, &result)
+	return result, err
+}
+func (cli *Client) TextDocumentRangeFormatting(ctx context.Context, filename string, rang Range) ([]*TextEdit, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_rangeFormatting
+
+	opt := &DocumentRangeFormattingParams{}
+	opt.Range = rang
+	opt.Options = defaultFormattingOptions()
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*TextEdit{}
+	err = cli.Call(ctx, "textDocument/rangeFormatting", opt, &result)
+	return result, err
+}
+func defaultFormattingOptions() FormattingOptions {
+	return FormattingOptions{TabSize: 8, InsertSpaces: false}
+}
+
+//----------
+
+// The diagnostics are the ones overlapping the range (used by the server for quick fixes).
+func (cli *Client) TextDocumentCodeAction(ctx context.Context, filename string, rang Range, diags []*Diagnostic) ([]*CodeAction, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_codeAction
+
+	opt := &CodeActionParams{}
+	opt.Range = rang
+	opt.Context.Diagnostics = diags
+	if opt.Context.Diagnostics == nil {
+		opt.Context.Diagnostics = []*Diagnostic{} // must not be null
+	}
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*CodeAction{}
+	err = cli.Call(ctx, "textDocument/codeAction", opt, &result)
+	return result, err
+}
+
+// Returns the action if the server doesn't resolve actions (the action only carries data).
+func (cli *Client) CodeActionResolve(ctx context.Context, ca *CodeAction) (*CodeAction, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#codeAction_resolve
+
+	if !cli.serverCapabilities.codeActionResolve {
+		return ca, nil
+	}
+	result := &CodeAction{}
+	err := cli.Call(ctx, "codeAction/resolve", ca, result)
+	return result, err
+}
+
+func (cli *Client) WorkspaceExecuteCommand(ctx context.Context, cmd *Command) error {
+	// https://microsoft.github.io/language-server-protocol/specification#workspace_executeCommand
+
+	opt := &ExecuteCommandParams{}
+	opt.Command = cmd.Command
+	opt.Arguments = cmd.Arguments
+
+	result := (any)(nil) // ignored
+	return cli.Call(ctx, "workspace/executeCommand", opt, &result)
+}
+
+//----------
+
+func (cli *Client) TextDocumentCodeLens(ctx context.Context, filename string) ([]*CodeLens, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_codeLens
+
+	opt := &CodeLensParams{}
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*CodeLens{}
+	err = cli.Call(ctx, "textDocument/codeLens", opt, &result)
+	return result, err
+}
+
+// Returns the lens if the server doesn't resolve lenses.
+func (cli *Client) CodeLensResolve(ctx context.Context, cl *CodeLens) (*CodeLens, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#codeLens_resolve
+
+	if !cli.serverCapabilities.codeLensResolve {
+		return cl, nil
+	}
+	result := &CodeLens{}
+	err := cli.Call(ctx, "codeLens/resolve", cl, result)
+	return result, err
+}
+
+//----------
+
+// Returns the hierarchical form; the flat form (symbolinformation) is converted.
+func (cli *Client) TextDocumentDocumentSymbol(ctx context.Context, filename string) ([]*DocumentSymbol, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#textDocument_documentSymbol
+
+	opt := &DocumentSymbolParams{}
+	url, err := AbsFilenameToUrl(filename)
+	if err != nil {
+		return nil, err
+	}
+	opt.TextDocument.Uri = DocumentUri(url)
+
+	result := []*_documentSymbolResult{}
+	if err := cli.Call(ctx, "textDocument/documentSymbol", opt, &result); err != nil {
+		return nil, err
+	}
+
+	// hierarchical form
+	if len(result) == 0 || result[0].Location == nil {
+		w := []*DocumentSymbol{}
+		for _, r := range result {
+			w = append(w, &r.DocumentSymbol)
+		}
+		return w, nil
+	}
+
+	// flat form: build hierarchy with the container names
+	w := []*DocumentSymbol{}
+	containers := map[string]*DocumentSymbol{}
+	for _, r := range result {
+		ds := &r.DocumentSymbol
+		ds.Range = r.Location.Range
+		ds.SelectionRange = r.Location.Range
+		if c, ok := containers[r.ContainerName]; ok && r.ContainerName != "" {
+			c.Children = append(c.Children, ds)
+		} else {
+			w = append(w, ds)
+		}
+		if _, ok := containers[ds.Name]; !ok {
+			containers[ds.Name] = ds
+		}
+	}
+	return w, nil
+}
+
+//----------
+
+func (cli *Client) WorkspaceSymbol(ctx context.Context, query string) ([]*SymbolInformation, error) {
+	// https://microsoft.github.io/language-server-protocol/specification#workspace_symbol
+
+	opt := &WorkspaceSymbolParams{Query: query}
+
+	// result can also be WorkspaceSymbol[] (location might not have a range)
+	result := []*SymbolInformation{}
+	if err := cli.Call(ctx, "workspace/symbol", opt, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -2,6 +2,7 @@ package lsproto
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math"
@@ -19,6 +20,8 @@ import (
 type JsonCodec struct {
 	OnNotificationMessage   func(*NotificationMessage)
 	OnUnexpectedServerReply func(*Response)
+	OnServerRequest         func(*Response)            // should reply with WriteServerReply
+	OnTraceMessage          func(write bool, b []byte) // runs in the read/write paths: must not block
 
 	rwc           io.ReadWriteCloser
 	responses     chan any
@@ -30,6 +33,7 @@ type JsonCodec struct {
 		sync.Mutex
 		closed bool
 	}
+	wmu sync.Mutex // writes from the rpc client and server replies
 }
 
 // Needs a call to ReadLoop() to start reading.
@@ -74,6 +78,34 @@ func (c *JsonCodec) WriteRequest(req *rpc.Request, data any) error {
 	}
 	//logPrintf("write req -->: %v(%v)", msg.Method, msg.Id)
 
+	if err := c.writeMsg(msg); err != nil {
+		return err
+	}
+
+	// simulate a response (noreply) with the seq if there is no err writing the msg
+	if noreply {
+		c.responses <- req.Seq
+	}
+	return nil
+}
+
+// Reply to a request that was initiated by the server (ex: "workspace/applyEdit").
+func (c *JsonCodec) WriteServerReply(id int, result any, rerr *ResponseError) error {
+	m := &ResponseMessage{Id: id}
+	m.Message = MakeMessage()
+	if rerr != nil {
+		m.Error = rerr
+	} else {
+		b, err := encodeJson(result) // "null" if nil
+		if err != nil {
+			return err
+		}
+		m.Result = json.RawMessage(b)
+	}
+	return c.writeMsg(m)
+}
+
+func (c *JsonCodec) writeMsg(msg any) error {
 	b, err := encodeJson(msg)
 	if err != nil {
 		return err
@@ -85,18 +117,15 @@ func (c *JsonCodec) WriteRequest(req *rpc.Request, data any) error {
 	copy(buf, []byte(h))  // header
 	copy(buf[len(h):], b) // body
 
-	logPrintf("write req -->: %T, %T, %s%s", msg, data, h, string(b))
-
-	_, err = c.rwc.Write(buf)
-	if err != nil {
-		return err
+	logPrintf("write msg -->: %T, %s%s", msg, h, string(b))
+	if c.OnTraceMessage != nil {
+		c.OnTraceMessage(true, b)
 	}
 
-	// simulate a response (noreply) with the seq if there is no err writing the msg
-	if noreply {
-		c.responses <- req.Seq
-	}
-	return nil
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err = c.rwc.Write(buf)
+	return err
 }
 
 //----------
@@ -112,6 +141,9 @@ func (c *JsonCodec) ReadLoop() error {
 			return err
 		}
 		logPrintf("read resp <--: %s\n", b)
+		if c.OnTraceMessage != nil {
+			c.OnTraceMessage(false, b)
+		}
 		c.responses <- b
 	}
 }
@@ -162,6 +194,11 @@ func (c *JsonCodec) ReadResponseBody(reply any) error {
 		if reply != nil {
 			return fmt.Errorf("jsoncodec: server push with reply expecting data: %v", reply)
 		}
+		// server request (has id), expects a reply
+		if c.readData.resp.IsServerRequest() && c.OnServerRequest != nil {
+			c.OnServerRequest(c.readData.resp)
+			return nil
+		}
 		// run callback
 		if c.OnNotificationMessage != nil {
 			c.OnNotificationMessage(c.readData.resp.NotificationMessage)
@@ -0,0 +1,283 @@
+package lsproto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+// Documents open in a lang instance (server).
+// Documents of files kept open by the editor (rows) stay open until closed, and edits are sent as changes (didChange). Other documents are opened and closed around each request.
+type documents struct {
+	li     *LangInstance
+	sendMu sync.Mutex // keeps the order of the notifications
+	mu     struct {
+		sync.Mutex
+		m map[string]*document
+	}
+}
+
+func newDocuments(li *LangInstance) *documents {
+	docs := &documents{li: li}
+	docs.mu.m = map[string]*document{}
+	return docs
+}
+
+//----------
+
+// Opens the document if not open yet, or sends the pending changes. If the content differs from the one known by the server, the full content is sent. If the document is not kept, the returned func closes it (always call it).
+func (docs *documents) open(ctx context.Context, filename string, rd iorw.ReaderAt, keep bool) (func(), error) {
+	noop := func() {}
+
+	cli := docs.li.cli
+	if !cli.serverCapabilities.sync.openClose {
+		return noop, nil // server not interested in open documents
+	}
+
+	b, err := iorw.ReadFullCopy(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	docs.sendMu.Lock()
+	defer docs.sendMu.Unlock()
+
+	// already open: send pending changes
+	docs.mu.Lock()
+	d, ok := docs.mu.m[filename]
+	if ok && !bytes.Equal(d.text, b) {
+		d.setFull(b)
+	}
+	docs.mu.Unlock()
+	if ok {
+		return noop, docs.send(ctx, filename)
+	}
+
+	if err := cli.TextDocumentDidOpenVersion(ctx, filename, b); err != nil {
+		return nil, err
+	}
+
+	if keep {
+		docs.mu.Lock()
+		docs.mu.m[filename] = &document{text: b}
+		docs.mu.Unlock()
+		return noop, nil
+	}
+
+	// ISSUE: file1 src is sent to the server (didopen). Assume now that the request that follows (ex: lsprotoCallers) takes too long such that the ctx expires. The usual "defer didclose" will fail since the context is no longer valid. And so the server stays with the version that might have compile errors. The user corrects the errors without asking anything else from the lspserver. Later on, on another file2, asks for the lspserver to assist with something. This could fail since the lspserver still has the file1 cached with errors.
+	// solution: if the didopen was successful, return a func to always run the didClose with defer even if the ctx is no longer valid.
+	didCloseFn := func() {
+		docs.sendMu.Lock()
+		defer docs.sendMu.Unlock()
+		ctx2 := context.Background()                 // don't use a possible canceled ctx
+		_ = cli.TextDocumentDidClose(ctx2, filename) // best effort, ignore error
+	}
+	return didCloseFn, nil
+}
+
+// Records an edit already applied to rd. The changes are sent after a short delay, or before the next request.
+// Called from the editor on every write, must be fast.
+func (docs *documents) change(filename string, rd iorw.ReaderAt, ev *iorw.RWEvWrite) {
+	docs.mu.Lock()
+	defer docs.mu.Unlock()
+
+	d, ok := docs.mu.m[filename]
+	if !ok {
+		return
+	}
+
+	kind := docs.li.cli.serverCapabilities.sync.change
+	if err := d.applyChange(rd, ev, kind); err != nil {
+		// out of sync, send the full content
+		b, err := iorw.ReadFullCopy(rd)
+		if err != nil {
+			delete(docs.mu.m, filename) // will be opened again
+			return
+		}
+		d.setFull(b)
+	}
+
+	// server doesn't want changes, only sent (reopen) on the next request
+	if kind == TdskNone {
+		return
+	}
+
+	if d.timer == nil {
+		d.timer = time.AfterFunc(documentChangeDelay, func() {
+			docs.sendMu.Lock()
+			defer docs.sendMu.Unlock()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = docs.send(ctx, filename) // best effort, ignore error
+		})
+	}
+}
+
+func (docs *documents) close(ctx context.Context, filename string) error {
+	docs.sendMu.Lock()
+	defer docs.sendMu.Unlock()
+
+	docs.mu.Lock()
+	d, ok := docs.mu.m[filename]
+	if ok {
+		d.stopTimer()
+		delete(docs.mu.m, filename)
+	}
+	docs.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	return docs.li.cli.TextDocumentDidClose(ctx, filename)
+}
+
+// Content of the open documents (includes pending changes).
+func (docs *documents) texts() map[string][]byte {
+	docs.mu.Lock()
+	defer docs.mu.Unlock()
+	m := map[string][]byte{}
+	for filename, d := range docs.mu.m {
+		d.stopTimer()
+		m[filename] = slices.Clone(d.text) // the text is edited in place
+	}
+	return m
+}
+
+// Opens documents (ex: that were open before a restart), if still kept.
+func (docs *documents) reopen(ctx context.Context, texts map[string][]byte, keep func(string) bool) {
+	if !docs.li.cli.serverCapabilities.sync.openClose {
+		return
+	}
+
+	docs.sendMu.Lock()
+	defer docs.sendMu.Unlock()
+
+	for filename, b := range texts {
+		if !keep(filename) {
+			continue
+		}
+		if err := docs.li.cli.TextDocumentDidOpenVersion(ctx, filename, b); err != nil {
+			docs.li.lang.PrintWrapError(err)
+			continue
+		}
+		docs.mu.Lock()
+		docs.mu.m[filename] = &document{text: b}
+		docs.mu.Unlock()
+	}
+}
+
+// Sends the pending changes. Caller should have sendMu locked.
+func (docs *documents) send(ctx context.Context, filename string) error {
+	docs.mu.Lock()
+	d, ok := docs.mu.m[filename]
+	if !ok {
+		docs.mu.Unlock()
+		return nil
+	}
+	d.stopTimer()
+	changes, full := d.changes, d.full
+	d.changes, d.full = nil, false
+	text := ""
+	if full {
+		text = string(d.text)
+	}
+	docs.mu.Unlock()
+
+	cli := docs.li.cli
+	if full {
+		if cli.serverCapabilities.sync.change == TdskNone {
+			// reopen to give the new content
+			if err := cli.TextDocumentDidClose(ctx, filename); err != nil {
+				return err
+			}
+			return cli.TextDocumentDidOpenVersion(ctx, filename, []byte(text))
+		}
+		changes = []*TextDocumentContentChangeEvent{{Text: text}}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return cli.TextDocumentDidChangeVersion(ctx, filename, changes)
+}
+
+//----------
+
+const documentChangeDelay = 250 * time.Millisecond
+
+type document struct {
+	text    []byte                            // content known by the server (includes pending changes)
+	changes []*TextDocumentContentChangeEvent // pending incremental changes
+	full    bool                              // pending full content change
+	timer   *time.Timer
+
+	// known line start (ex: from the last change), positions are computed from here
+	mark struct{ offset, line int }
+}
+
+func (d *document) setFull(b []byte) {
+	d.text = b
+	d.changes = nil
+	d.full = true
+	d.mark.offset, d.mark.line = 0, 0
+}
+
+func (d *document) applyChange(rd iorw.ReaderAt, ev *iorw.RWEvWrite, kind TextDocumentSyncKind) error {
+	// check the known content can be the content before the edit
+	n := rd.Max() - rd.Min()
+	if ev.Index < 0 || ev.Index+ev.Dn > len(d.text) || len(d.text)-ev.Dn+ev.In != n {
+		return fmt.Errorf("document out of sync")
+	}
+
+	p, err := rd.ReadFastAt(ev.Index, ev.In)
+	if err != nil {
+		return err
+	}
+	if len(p) != ev.In {
+		return fmt.Errorf("short read")
+	}
+
+	if kind == TdskIncremental && !d.full {
+		// the start line is before the edit, and stays valid after it
+		start := d.position(ev.Index)
+		d.mark.offset = bytes.LastIndexByte(d.text[:ev.Index], '\n') + 1
+		d.mark.line = start.Line
+		end := d.position(ev.Index + ev.Dn)
+		cc := &TextDocumentContentChangeEvent{Range: &Range{start, end}, Text: string(p)}
+		d.changes = append(d.changes, cc)
+	} else {
+		d.full = true
+	}
+
+	// update known content
+	d.text = slices.Replace(d.text, ev.Index, ev.Index+ev.Dn, p...)
+	return nil
+}
+
+// Position of the offset in the text, counting lines from the known line start.
+func (d *document) position(offset int) Position {
+	lo, line := d.mark.offset, d.mark.line
+	if lo > len(d.text) {
+		lo, line = 0, 0
+	}
+	if offset >= lo {
+		line += bytes.Count(d.text[lo:offset], []byte("\n"))
+	} else {
+		line -= bytes.Count(d.text[offset:lo], []byte("\n"))
+	}
+	ls := bytes.LastIndexByte(d.text[:offset], '\n') + 1
+	col := len(utf16.Encode([]rune(string(d.text[ls:offset])))) // utf16 column
+	return Position{Line: line, Character: col}
+}
+
+func (d *document) stopTimer() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
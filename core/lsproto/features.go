@@ -0,0 +1,47 @@
+package lsproto
+
+// Registration features, with the server capability that enables them. Requests are routed to the first registration (of the file extension) that serves the feature and whose server has the capability.
+var featureProviders = map[string]string{
+	"completion":     "completionProvider",
+	"hover":          "hoverProvider",
+	"signaturehelp":  "signatureHelpProvider",
+	"definition":     "definitionProvider",
+	"typedefinition": "typeDefinitionProvider",
+	"declaration":    "declarationProvider",
+	"implementation": "implementationProvider",
+	"references":     "referencesProvider",
+	"rename":         "renameProvider",
+	"format":         "documentFormattingProvider",
+	"codeaction":     "codeActionProvider",
+	"symbols":        "documentSymbolProvider",
+	"callhierarchy":  "callHierarchyProvider",
+	"typehierarchy":  "typeHierarchyProvider",
+	"inlayhint":      "inlayHintProvider",
+	"highlight":      "documentHighlightProvider",
+	"selectionrange": "selectionRangeProvider",
+	"codelens":       "codeLensProvider",
+	"diagnostics":    "", // pushed by the server (publishDiagnostics)
+}
+
+func (cli *Client) readServerFeatures(caps any) {
+	cli.serverCapabilities.features = map[string]bool{}
+	for f, p := range featureProviders {
+		if p == "" {
+			cli.serverCapabilities.features[f] = true
+			continue
+		}
+		// can be: boolean | XOptions | XRegistrationOptions
+		v, err := JsonGetPath(caps, "capabilities."+p)
+		if err == nil {
+			cli.serverCapabilities.features[f] = jsonBoolOrOptions(v)
+		}
+	}
+}
+
+// Unknown features are assumed to be supported.
+func (cli *Client) supports(feature string) bool {
+	if _, ok := featureProviders[feature]; !ok {
+		return true
+	}
+	return cli.serverCapabilities.features[feature]
+}
@@ -15,10 +15,15 @@ type LangInstance struct {
 	cli       *Client
 	sw        *ServerWrap // might be nil: "tcpclient" option
 	cancelCtx context.CancelFunc
+	docs      *documents
+
+	initFolders []string // workspace folders dirs at initialization
 }
 
-func NewLangInstance(ctx context.Context, lang *LangManager) (*LangInstance, error) {
-	li := &LangInstance{lang: lang}
+// The dirs are the initial workspace folders.
+func NewLangInstance(ctx context.Context, lang *LangManager, dirs []string) (*LangInstance, error) {
+	li := &LangInstance{lang: lang, initFolders: dirs}
+	li.docs = newDocuments(li)
 
 	ctx2, cancel := context.WithCancel(ctx)
 	li.cancelCtx = cancel
@@ -3,7 +3,11 @@ package lsproto
 import (
 	"context"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 type LangManager struct {
@@ -13,14 +17,33 @@ type LangManager struct {
 		sync.Mutex
 		li     *LangInstance
 		cancel context.CancelFunc
+
+		// supervision
+		started       time.Time
+		restarts      int
+		failures      int // consecutive, used for the backoff
+		lastErr       error
+		restarting    bool
+		failed        bool              // gave up restarting
+		reopen        map[string][]byte // documents to reopen on the next start
+		cancelRestart context.CancelFunc
+	}
+	trace struct {
+		sync.Mutex
+		tr *tracer
 	}
+	folders *folders // workspace folders
 }
 
 func NewLangManager(man *Manager, reg *Registration) *LangManager {
-	return &LangManager{Reg: reg, man: man}
+	lang := &LangManager{Reg: reg, man: man}
+	lang.folders = newFolders()
+	lang.li.cancelRestart = func() {} // avoid nil call
+	return lang
 }
 
-func (lang *LangManager) instance(startCtx context.Context) (*LangInstance, error) {
+// The root dir (can be empty) is added to the workspace folders of a new instance.
+func (lang *LangManager) instance(startCtx context.Context, root string) (*LangInstance, error) {
 	lang.li.Lock()
 	defer lang.li.Unlock()
 
@@ -37,45 +60,151 @@ func (lang *LangManager) instance(startCtx context.Context) (*LangInstance, erro
 	stop := context.AfterFunc(startCtx, cancel)
 	defer stop()
 
-	li, err := NewLangInstance(ctx, lang)
+	// the requesting root first (server root), the other open projects are only workspace folders
+	dirs := lang.folders.dirs()
+	if root != "" {
+		dirs = slices.DeleteFunc(dirs, func(d string) bool { return d == root })
+		dirs = slices.Insert(dirs, 0, root)
+	}
+	li, err := NewLangInstance(ctx, lang, dirs)
 	if err != nil {
 		cancel()
 		err = lang.WrapError(err)
+		lang.li.lastErr = err
 		return nil, err
 	}
 	lang.li.li = li
 	lang.li.cancel = cancel
+	lang.li.started = time.Now()
+	lang.li.failed = false
+	stop()                  // the instance ctx no longer depends on startCtx
+	lang.li.cancelRestart() // started before the scheduled restart (or by it)
+	lang.li.restarting = false
+
+	// documents that were open before a crash
+	if len(lang.li.reopen) > 0 {
+		ctx2, cancel2 := context.WithTimeout(ctx, 5*time.Second)
+		li.docs.reopen(ctx2, lang.li.reopen, lang.man.isKept)
+		cancel2()
+		lang.li.reopen = nil
+	}
 
 	// clear instance var on exit
 	go func() {
 		defer cancel()
-		if err := li.Wait(); err != nil { // err ex: "signal: killed"
+		err := li.Wait()
+		if err != nil { // err ex: "signal: killed"
 			lang.PrintWrapError(err)
 		}
-		// ensure correct instance is cleared
-		lang.li.Lock()
-		defer lang.li.Unlock()
-		if lang.li.li == li {
-			lang.li.li = nil
-		}
+		lang.instanceExited(li, err)
 	}()
 
 	return li, nil
 }
 
+// Restarts the instance if it was not stopped with stopInstance.
+func (lang *LangManager) instanceExited(li *LangInstance, err error) {
+	lang.li.Lock()
+	defer lang.li.Unlock()
+
+	// ensure correct instance is cleared (if not, it was stopped)
+	if lang.li.li != li {
+		return
+	}
+	lang.li.li = nil
+
+	if err == nil {
+		err = fmt.Errorf("exited")
+	}
+	lang.li.lastErr = lang.WrapError(err)
+
+	// running for a while: not a consecutive failure
+	if time.Since(lang.li.started) > time.Minute {
+		lang.li.failures = 0
+	}
+
+	lang.li.reopen = li.docs.texts()
+	lang.scheduleRestart()
+}
+
+// Caller should have the lock.
+func (lang *LangManager) scheduleRestart() {
+	lang.li.failures++
+	if lang.li.failures > maxRestartFailures {
+		lang.li.restarting = false
+		lang.li.failed = true
+		lang.li.reopen = nil
+		lang.man.Message(lang.WrapMsg(fmt.Sprintf("not restarting after %d failures", maxRestartFailures)))
+		return
+	}
+
+	// exponential backoff
+	delay := restartBaseDelay << (lang.li.failures - 1)
+	delay = min(delay, restartMaxDelay)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lang.li.cancelRestart = cancel
+	lang.li.restarting = true
+	lang.man.Message(lang.WrapMsg(fmt.Sprintf("restarting in %v", delay)))
+
+	go func() {
+		defer cancel()
+		select {
+		case <-ctx.Done():
+			return // stopped, or started by a request
+		case <-time.After(delay):
+		}
+
+		ctx2, cancel2 := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel2()
+		if _, err := lang.instance(ctx2, ""); err != nil {
+			lang.man.Error(err) // already wrapped
+
+			lang.li.Lock()
+			defer lang.li.Unlock()
+			if lang.li.li == nil && ctx.Err() == nil {
+				lang.scheduleRestart()
+			}
+			return
+		}
+
+		lang.li.Lock()
+		lang.li.restarts++
+		lang.li.Unlock()
+		lang.man.Message(lang.WrapMsg("restarted"))
+	}()
+}
+
+//----------
+
 func (lang *LangManager) hasInstance() bool {
 	lang.li.Lock()
 	defer lang.li.Unlock()
 	return lang.li.li != nil
 }
 
+// Doesn't start a new instance.
+func (lang *LangManager) runningInstance() (*LangInstance, bool) {
+	lang.li.Lock()
+	defer lang.li.Unlock()
+	return lang.li.li, lang.li.li != nil
+}
+
 // returns true if the instance was running
 func (lang *LangManager) stopInstance() bool {
 	lang.li.Lock()
 	defer lang.li.Unlock()
+
+	// stop supervision
+	lang.li.cancelRestart()
+	lang.li.restarting = false
+	lang.li.reopen = nil
+	lang.li.failures = 0
+
 	if lang.li.li != nil {
 		lang.li.cancel()
 		lang.li.li = nil
+		lang.man.clearDiagnostics(lang, "")
 		return true
 	}
 	return false
@@ -83,6 +212,71 @@ func (lang *LangManager) stopInstance() bool {
 
 //----------
 
+// State, pid, uptime, restarts count and last error.
+func (lang *LangManager) Status() string {
+	lang.li.Lock()
+	defer lang.li.Unlock()
+
+	w := []string{}
+	switch {
+	case lang.li.li != nil:
+		w = append(w, "running")
+		pid := "-" // "tcpclient" option has no process
+		if sw := lang.li.li.sw; sw != nil {
+			if p := sw.Cmd.Cmd().Process; p != nil {
+				pid = fmt.Sprintf("%d", p.Pid)
+			}
+		}
+		w = append(w, "pid="+pid)
+		uptime := time.Since(lang.li.started).Round(time.Second)
+		w = append(w, fmt.Sprintf("uptime=%v", uptime))
+	case lang.li.failed:
+		w = append(w, "failed")
+	case lang.li.restarting:
+		w = append(w, "restarting")
+	default:
+		w = append(w, "stopped")
+	}
+	w = append(w, fmt.Sprintf("restarts=%d", lang.li.restarts))
+	if lang.li.lastErr != nil {
+		w = append(w, fmt.Sprintf("lasterror=%q", lang.li.lastErr.Error()))
+	}
+	return fmt.Sprintf("%s: %s", lang.Reg.Language, strings.Join(w, " "))
+}
+
+//----------
+
+// Traces the messages of current and future instances until the returned func is called. Replaces a previous trace.
+func (lang *LangManager) Trace(w io.Writer) func() {
+	tr := newTracer(w)
+	lang.trace.Lock()
+	prev := lang.trace.tr
+	lang.trace.tr = tr
+	lang.trace.Unlock()
+	if prev != nil {
+		prev.close()
+	}
+	return func() {
+		lang.trace.Lock()
+		if lang.trace.tr == tr {
+			lang.trace.tr = nil
+		}
+		lang.trace.Unlock()
+		tr.close()
+	}
+}
+
+func (lang *LangManager) traceMessage(write bool, b []byte) {
+	lang.trace.Lock()
+	tr := lang.trace.tr
+	lang.trace.Unlock()
+	if tr != nil {
+		tr.message(write, b)
+	}
+}
+
+//----------
+
 func (lang *LangManager) PrintWrapError(err error) {
 	lang.man.Error(lang.WrapError(err))
 }
@@ -94,3 +288,11 @@ func (lang *LangManager) WrapError(err error) error {
 func (lang *LangManager) WrapMsg(s string) string {
 	return fmt.Sprintf("lsproto(%s): %v", lang.Reg.Language, s)
 }
+
+//----------
+
+const (
+	maxRestartFailures = 6
+	restartBaseDelay   = 500 * time.Millisecond
+	restartMaxDelay    = 30 * time.Second
+)
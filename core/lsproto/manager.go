@@ -1,11 +1,17 @@
 package lsproto
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/friedelschoen/editor/util/iout/iorw"
 )
@@ -18,14 +24,32 @@ import (
 // - Client handles client connection to the lsp server
 // - ServerWrap, if used, runs the lsp server process
 type Manager struct {
-	langs []*LangManager
-	msgFn func(string)
+	langs       []*LangManager
+	msgFn       func(string)
+	applyEditFn ApplyEditFn // server initiated edits (can be nil)
+
+	keep struct { // documents kept open (files with rows in the editor)
+		sync.Mutex
+		m map[string]string // filename -> project root
+	}
+	diags struct { // published diagnostics
+		sync.Mutex
+		m map[string]map[*LangManager][]*Diagnostic // filename -> lang -> diagnostics
+	}
 
 	serverWrapW io.Writer // test purposes only
 }
 
 func NewManager(msgFn func(string)) *Manager {
-	return &Manager{msgFn: msgFn}
+	man := &Manager{msgFn: msgFn}
+	man.keep.m = map[string]string{}
+	man.diags.m = map[string]map[*LangManager][]*Diagnostic{}
+	return man
+}
+
+// Sets the func that handles edits requested by the server (ex: after executing a command). If not set, the edits are patched directly on the files.
+func (man *Manager) SetApplyEditFn(fn ApplyEditFn) {
+	man.applyEditFn = fn
 }
 
 //----------
@@ -43,6 +67,11 @@ func (man *Manager) Message(s string) {
 //----------
 
 func (man *Manager) Register(reg *Registration) error {
+	for _, f := range reg.Features {
+		if _, ok := featureProviders[f]; !ok {
+			return fmt.Errorf("lsproto(%s): unknown feature: %q", reg.Language, f)
+		}
+	}
 	lang := NewLangManager(man, reg)
 	// replace if already exists
 	for i, lang2 := range man.langs {
@@ -58,28 +87,97 @@ func (man *Manager) Register(reg *Registration) error {
 
 //----------
 
+// First registration of the file extension.
 func (man *Manager) LangManager(filename string) (*LangManager, error) {
+	langs, err := man.LangManagers(filename)
+	if err != nil {
+		return nil, err
+	}
+	return langs[0], nil
+}
+
+// All registrations of the file extension, in registration order.
+func (man *Manager) LangManagers(filename string) ([]*LangManager, error) {
 	ext := filepath.Ext(filename)
+	w := []*LangManager{}
 	for _, lang := range man.langs {
-		for _, ext2 := range lang.Reg.Exts {
-			if ext2 == ext {
-				return lang, nil
-			}
+		if slices.Contains(lang.Reg.Exts, ext) {
+			w = append(w, lang)
 		}
 	}
-	return nil, fmt.Errorf("no lsproto for file ext: %q", ext)
+	if len(w) == 0 {
+		return nil, fmt.Errorf("no lsproto for file ext: %q", ext)
+	}
+	return w, nil
 }
 
-func (man *Manager) langInstanceClient(ctx context.Context, filename string) (*Client, *LangInstance, error) {
-	lang, err := man.LangManager(filename)
+// Registrations of the file extension that serve the feature.
+func (man *Manager) featureLangManagers(filename, feature string) ([]*LangManager, error) {
+	langs, err := man.LangManagers(filename)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	w := []*LangManager{}
+	for _, lang := range langs {
+		if lang.Reg.HasFeature(feature) {
+			w = append(w, lang)
+		}
+	}
+	if len(w) == 0 {
+		return nil, fmt.Errorf("no lsproto serves %q for file ext: %q", feature, filepath.Ext(filename))
+	}
+	return w, nil
+}
+
+func (man *Manager) LangManagerByLanguage(language string) (*LangManager, error) {
+	for _, lang := range man.langs {
+		if strings.EqualFold(lang.Reg.Language, language) {
+			return lang, nil
+		}
 	}
-	li, err := lang.instance(ctx)
+	return nil, fmt.Errorf("no lsproto for language: %q", language)
+}
+
+// Client of the first registration that serves the feature and has the server capability. Starts the servers as needed. If no server has the capability, the first one is used (the request fails there with the server error).
+func (man *Manager) langInstanceClient(ctx context.Context, filename, feature string) (*Client, *LangInstance, error) {
+	langs, err := man.featureLangManagers(filename, feature)
 	if err != nil {
 		return nil, nil, err
 	}
-	return li.cli, li, nil
+	var first *LangInstance
+	var firstErr error
+	for _, lang := range langs {
+		li, err := man.langInstance(ctx, lang, filename)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if li.cli.supports(feature) {
+			return li.cli, li, nil
+		}
+		if first == nil {
+			first = li
+		}
+	}
+	if first == nil {
+		return nil, nil, firstErr
+	}
+	return first.cli, first, nil
+}
+
+func (man *Manager) langInstance(ctx context.Context, lang *LangManager, filename string) (*LangInstance, error) {
+	root := man.projectRoot(filename)
+	li, err := lang.instance(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	// file from a project not known by the server
+	if err := li.cli.WorkspaceDidChangeWorkspaceFolders(ctx, []string{root}, nil); err != nil {
+		return nil, err
+	}
+	return li, nil
 }
 
 //----------
@@ -94,6 +192,24 @@ func (man *Manager) NInstances() int {
 	return c
 }
 
+// One line per registered language with the instance state.
+func (man *Manager) Status() string {
+	w := []string{}
+	for _, lang := range man.langs {
+		w = append(w, lang.Status())
+	}
+	return strings.Join(w, "\n")
+}
+
+// Writes the json-rpc messages of the language instances to w until the returned func is called.
+func (man *Manager) Trace(language string, w io.Writer) (func(), error) {
+	lang, err := man.LangManagerByLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	return lang.Trace(w), nil
+}
+
 func (man *Manager) Stop() {
 	for _, lang := range man.langs {
 		if ok := lang.stopInstance(); ok {
@@ -105,7 +221,21 @@ func (man *Manager) Stop() {
 //----------
 
 func (man *Manager) TextDocumentImplementation(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, *Range, error) {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+	return man.textDocumentLocation(ctx, "implementation", "textDocument/implementation", filename, rd, offset)
+}
+func (man *Manager) TextDocumentDefinition(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, *Range, error) {
+	return man.textDocumentLocation(ctx, "definition", "textDocument/definition", filename, rd, offset)
+}
+func (man *Manager) TextDocumentTypeDefinition(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, *Range, error) {
+	return man.textDocumentLocation(ctx, "typedefinition", "textDocument/typeDefinition", filename, rd, offset)
+}
+func (man *Manager) TextDocumentDeclaration(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, *Range, error) {
+	return man.textDocumentLocation(ctx, "declaration", "textDocument/declaration", filename, rd, offset)
+}
+
+// Location requests at the offset (ex: definition). Returns the target filename and range.
+func (man *Manager) textDocumentLocation(ctx context.Context, feature, method, filename string, rd iorw.ReaderAt, offset int) (string, *Range, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, feature)
 	if err != nil {
 		return "", nil, err
 	}
@@ -121,7 +251,7 @@ func (man *Manager) TextDocumentImplementation(ctx context.Context, filename str
 		return "", nil, err
 	}
 
-	loc, err := cli.TextDocumentImplementation(ctx, filename, pos)
+	loc, err := cli.textDocumentLocation(ctx, method, filename, pos)
 	if err != nil {
 		return "", nil, err
 	}
@@ -137,41 +267,244 @@ func (man *Manager) TextDocumentImplementation(ctx context.Context, filename str
 
 //----------
 
-func (man *Manager) TextDocumentDefinition(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, *Range, error) {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+// Merges the items of all the registrations that serve completion (in registration order). Fails only if all fail.
+func (man *Manager) TextDocumentCompletion(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (*CompletionList, error) {
+	langs, err := man.featureLangManagers(filename, "completion")
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+	if len(langs) == 1 {
+		cli, _, err := man.langInstanceClient(ctx, filename, "completion")
+		if err != nil {
+			return nil, err
+		}
+		return man.textDocumentCompletion(ctx, cli, filename, rd, offset)
 	}
 
+	type result struct {
+		clist *CompletionList
+		err   error
+	}
+	res := make([]result, len(langs))
+	var wg sync.WaitGroup
+	for i, lang := range langs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			li, err := man.langInstance(ctx, lang, filename)
+			if err != nil {
+				res[i].err = err
+				return
+			}
+			if !li.cli.supports("completion") {
+				res[i].clist = &CompletionList{}
+				return
+			}
+			res[i].clist, res[i].err = man.textDocumentCompletion(ctx, li.cli, filename, rd, offset)
+		}()
+	}
+	wg.Wait()
+
+	clist := &CompletionList{}
+	var firstErr error
+	ok := false
+	for _, r := range res {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		ok = true
+		clist.IsIncomplete = clist.IsIncomplete || r.clist.IsIncomplete
+		clist.Items = append(clist.Items, r.clist.Items...)
+	}
+	if !ok {
+		return nil, firstErr
+	}
+	return clist, nil
+}
+
+func (man *Manager) textDocumentCompletion(ctx context.Context, cli *Client, filename string, rd iorw.ReaderAt, offset int) (*CompletionList, error) {
 	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	defer didCloseFn()
 
 	pos, err := OffsetToPosition(rd, offset)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
-	loc, err := cli.TextDocumentDefinition(ctx, filename, pos)
+	return cli.TextDocumentCompletion(ctx, filename, pos)
+}
+
+func (man *Manager) TextDocumentCompletionDetailStrings(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) ([]string, error) {
+	clist, err := man.TextDocumentCompletion(ctx, filename, rd, offset)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
+	w := CompletionListToString(clist)
+	return w, nil
+}
 
-	// target filename
-	filename2, err := UrlToAbsFilename(string(loc.Uri))
+//----------
+
+func (man *Manager) didOpen(ctx context.Context, cli *Client, filename string, rd iorw.ReaderAt) (func(), error) {
+	return cli.li.docs.open(ctx, filename, rd, man.isKept(filename))
+}
+
+//----------
+
+// The document stays open in the server (once opened by a request) until DidCloseDocument is called. Edits should be given with DidChangeDocument.
+// Also adds the file project root to the workspace folders. Doesn't start a server.
+func (man *Manager) KeepDocumentOpen(filename string) {
+	langs, err := man.LangManagers(filename)
 	if err != nil {
-		return "", nil, err
+		return
 	}
 
-	return filename2, loc.Range, nil
+	man.keep.Lock()
+	_, ok := man.keep.m[filename]
+	if ok {
+		man.keep.Unlock()
+		return
+	}
+	root := ProjectRoot(filename)
+	man.keep.m[filename] = root
+	man.keep.Unlock()
+
+	for _, lang := range langs {
+		if lang.folders.add(root) {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				lang.sendFoldersChange(ctx, []string{root}, nil)
+			}()
+		}
+	}
+}
+
+func (man *Manager) isKept(filename string) bool {
+	man.keep.Lock()
+	defer man.keep.Unlock()
+	_, ok := man.keep.m[filename]
+	return ok
+}
+
+func (man *Manager) projectRoot(filename string) string {
+	man.keep.Lock()
+	root, ok := man.keep.m[filename]
+	man.keep.Unlock()
+	if ok {
+		return root
+	}
+	return ProjectRoot(filename)
+}
+
+// Records an edit (already written to rd) of a kept document. Doesn't start a server. Fast, can be called on every write.
+func (man *Manager) DidChangeDocument(filename string, rd iorw.ReaderAt, ev *iorw.RWEvWrite) {
+	langs, err := man.LangManagers(filename)
+	if err != nil {
+		return
+	}
+	for _, lang := range langs {
+		if li, ok := lang.runningInstance(); ok {
+			li.docs.change(filename, rd, ev)
+		}
+	}
+}
+
+// Closes a kept document (ex: last row of the file was closed). Doesn't start a server.
+// Also removes the file project root from the workspace folders if there are no more documents kept from it.
+func (man *Manager) DidCloseDocument(ctx context.Context, filename string) error {
+	man.keep.Lock()
+	root, kept := man.keep.m[filename]
+	delete(man.keep.m, filename)
+	man.keep.Unlock()
+
+	langs, err := man.LangManagers(filename)
+	if err != nil {
+		return nil
+	}
+	man.clearDiagnostics(nil, filename)
+	var firstErr error
+	for _, lang := range langs {
+		if li, ok := lang.runningInstance(); ok {
+			if err := li.docs.close(ctx, filename); err != nil {
+				firstErr = cmp.Or(firstErr, err)
+				continue
+			}
+		}
+		if kept && lang.folders.remove(root) {
+			lang.sendFoldersChange(ctx, nil, []string{root})
+		}
+	}
+	return firstErr
 }
 
 //----------
 
-func (man *Manager) TextDocumentCompletion(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (*CompletionList, error) {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+// Syncs the saved content, and sends didSave if the server asks for it. Sent to all the registrations of the file (ex: a linter server reports on save).
+func (man *Manager) TextDocumentDidSave(ctx context.Context, filename string, rd iorw.ReaderAt) error {
+	return man.eachLangInstance(ctx, filename, func(cli *Client) error {
+		didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+		if err != nil {
+			return err
+		}
+		defer didCloseFn()
+
+		sc := &cli.serverCapabilities.sync
+		if !sc.save {
+			return nil
+		}
+		var b []byte
+		if sc.saveText {
+			b, err = iorw.ReadFastFull(rd)
+			if err != nil {
+				return err
+			}
+		}
+		return cli.TextDocumentDidSave(ctx, filename, b)
+	})
+}
+
+//----------
+
+func (man *Manager) SyncText(ctx context.Context, filename string, rd iorw.ReaderAt) error {
+	return man.eachLangInstance(ctx, filename, func(cli *Client) error {
+		// kept documents get the pending changes (or the full content if it differs), others are opened/closed
+		didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+		if err != nil {
+			return err
+		}
+		defer didCloseFn()
+		return nil
+	})
+}
+
+// Runs fn with the client of each registration of the file, starting the servers as needed. Returns the first error.
+func (man *Manager) eachLangInstance(ctx context.Context, filename string, fn func(*Client) error) error {
+	langs, err := man.LangManagers(filename)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, lang := range langs {
+		li, err := man.langInstance(ctx, lang, filename)
+		if err == nil {
+			err = fn(li.cli)
+		}
+		firstErr = cmp.Or(firstErr, err)
+	}
+	return firstErr
+}
+
+//----------
+
+func (man *Manager) TextDocumentRename(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, newName string) (*WorkspaceEdit, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "rename")
 	if err != nil {
 		return nil, err
 	}
@@ -187,79 +520,154 @@ func (man *Manager) TextDocumentCompletion(ctx context.Context, filename string,
 		return nil, err
 	}
 
-	return cli.TextDocumentCompletion(ctx, filename, pos)
+	return cli.TextDocumentRename(ctx, filename, pos, newName)
 }
 
-func (man *Manager) TextDocumentCompletionDetailStrings(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) ([]string, error) {
-	clist, err := man.TextDocumentCompletion(ctx, filename, rd, offset)
+func (man *Manager) TextDocumentRenameAndPatch(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, newName string, prePatchFn func([]*WorkspaceEditChange) error) ([]*WorkspaceEditChange, error) {
+
+	we, err := man.TextDocumentRename(ctx, filename, rd, offset, newName)
 	if err != nil {
 		return nil, err
 	}
-	w := CompletionListToString(clist)
-	return w, nil
+	return man.PatchWorkspaceEdit(ctx, we, prePatchFn)
 }
 
 //----------
 
-func (man *Manager) didOpen(ctx context.Context, cli *Client, filename string, rd iorw.ReaderAt) (func(), error) {
-	b, err := iorw.ReadFastFull(rd)
+func (man *Manager) CallHierarchyCalls(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, typ CallHierarchyCallType) ([]*ManagerCallHierarchyCalls, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "callhierarchy")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
 	if err != nil {
 		return nil, err
 	}
-	if err := cli.TextDocumentDidOpenVersion(ctx, filename, b); err != nil {
+	defer didCloseFn()
+
+	pos, err := OffsetToPosition(rd, offset)
+	if err != nil {
 		return nil, err
 	}
 
-	// ISSUE: file1 src is sent to the server (didopen). Assume now that the request that follows (ex: lsprotoCallers) takes too long such that the ctx expires. The usual "defer didclose" will fail since the context is no longer valid. And so the server stays with the version that might have compile errors. The user corrects the errors without asking anything else from the lspserver. Later on, on another file2, asks for the lspserver to assist with something. This could fail since the lspserver still has the file1 cached with errors.
-	// solution: if the didopen was successful, return a func to always run the didClose with defer even if the ctx is no longer valid.
-	didCloseFn := func() {
-		ctx2 := context.Background()                 // don't use a possible canceled ctx
-		_ = cli.TextDocumentDidClose(ctx2, filename) // best effort, ignore error
+	items, err := cli.TextDocumentPrepareCallHierarchy(ctx, filename, pos)
+	if err != nil {
+		return nil, err
 	}
-	return didCloseFn, nil
+	if len(items) == 0 {
+		return nil, fmt.Errorf("preparecallhierarchy returned no items")
+	}
+
+	res := []*ManagerCallHierarchyCalls{}
+	for _, item := range items {
+		calls, err := cli.CallHierarchyCalls(ctx, typ, item)
+		if err != nil {
+			return nil, err
+		}
+		u := &ManagerCallHierarchyCalls{item, calls}
+		res = append(res, u)
+	}
+
+	return res, nil
 }
 
 //----------
 
-//func (man *Manager) DidSave(ctx context.Context, filename string, text []byte) error {
-//	// no error if there is no lang registered
-//	_, err := man.lang(filename)
-//	if err != nil {
-//		return nil
-//	}
-//	return man.TextDocumentDidSave(ctx, filename, text)
-//}
-
-//func (man *Manager) TextDocumentDidSave(ctx context.Context, filename string, text []byte) error {
-//	cli, _, err := man.langInstanceClient(ctx, filename)
-//	if err != nil {
-//		return err
-//	}
-//	return cli.TextDocumentDidSave(ctx, filename, text)
-//}
+// Type hierarchy trees of the type at the offset. Each level is a request to the server, up to a max depth.
+func (man *Manager) TypeHierarchy(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, typ TypeHierarchyType) ([]*ManagerTypeHierarchyNode, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "typehierarchy")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	pos, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := cli.TextDocumentPrepareTypeHierarchy(ctx, filename, pos)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("preparetypehierarchy returned no items")
+	}
+
+	visited := map[string]bool{}
+	var build func(item *TypeHierarchyItem, depth int) (*ManagerTypeHierarchyNode, error)
+	build = func(item *TypeHierarchyItem, depth int) (*ManagerTypeHierarchyNode, error) {
+		node := &ManagerTypeHierarchyNode{item: item}
+
+		// avoid cycles
+		key := string(item.Uri)
+		if r := item.SelectionRange; r != nil {
+			key += fmt.Sprintf(":%d:%d", r.Start.Line, r.Start.Character)
+		}
+		if visited[key] || depth >= typeHierarchyMaxDepth {
+			return node, nil
+		}
+		visited[key] = true
+
+		items, err := cli.TypeHierarchyTypes(ctx, typ, item)
+		if err != nil {
+			return nil, err
+		}
+		for _, item2 := range items {
+			u, err := build(item2, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, u)
+		}
+		return node, nil
+	}
+
+	res := []*ManagerTypeHierarchyNode{}
+	for _, item := range items {
+		u, err := build(item, 0)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, nil
+}
+
+const typeHierarchyMaxDepth = 8
 
 //----------
 
-func (man *Manager) SyncText(ctx context.Context, filename string, rd iorw.ReaderAt) error {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+func (man *Manager) TextDocumentReferences(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) ([]*Location, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "references")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// opening/closing is enough to give the content to the server (using a didsave/didchange would just make it slower since our strategy is to open/close for every change)
 	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer didCloseFn()
 
-	return nil
+	pos, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.TextDocumentReferences(ctx, filename, pos)
 }
 
 //----------
 
-func (man *Manager) TextDocumentRename(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, newName string) (*WorkspaceEdit, error) {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+func (man *Manager) TextDocumentHover(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (*Hover, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "hover")
 	if err != nil {
 		return nil, err
 	}
@@ -275,16 +683,179 @@ func (man *Manager) TextDocumentRename(ctx context.Context, filename string, rd
 		return nil, err
 	}
 
-	return cli.TextDocumentRename(ctx, filename, pos, newName)
+	return cli.TextDocumentHover(ctx, filename, pos)
+}
+func (man *Manager) TextDocumentHoverString(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (string, error) {
+	h, err := man.TextDocumentHover(ctx, filename, rd, offset)
+	if err != nil {
+		return "", err
+	}
+	return HoverToString(h), nil
 }
 
-func (man *Manager) TextDocumentRenameAndPatch(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, newName string, prePatchFn func([]*WorkspaceEditChange) error) ([]*WorkspaceEditChange, error) {
+//----------
 
-	we, err := man.TextDocumentRename(ctx, filename, rd, offset, newName)
+func (man *Manager) TextDocumentSignatureHelp(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) (*SignatureHelp, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "signaturehelp")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	pos, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.TextDocumentSignatureHelp(ctx, filename, pos)
+}
+
+//----------
+
+func (man *Manager) TextDocumentDocumentHighlight(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) ([]*DocumentHighlight, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "highlight")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	pos, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.TextDocumentDocumentHighlight(ctx, filename, pos)
+}
+
+//----------
+
+// Ranges enclosing the offset, from the innermost to the outermost, as offset/length pairs.
+func (man *Manager) TextDocumentSelectionRange(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) ([][2]int, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "selectionrange")
 	if err != nil {
 		return nil, err
 	}
 
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	pos, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	sr, err := cli.TextDocumentSelectionRange(ctx, filename, pos)
+	if err != nil {
+		return nil, err
+	}
+	w := [][2]int{}
+	for ; sr != nil; sr = sr.Parent {
+		o, n, err := RangeToOffsetLen(rd, &sr.Range)
+		if err != nil {
+			return nil, err
+		}
+		w = append(w, [2]int{o, n})
+	}
+	return w, nil
+}
+
+//----------
+
+// Hints for the content in [offset,offset+n).
+func (man *Manager) TextDocumentInlayHint(ctx context.Context, filename string, rd iorw.ReaderAt, offset, n int) ([]*InlayHint, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "inlayhint")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	start, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+	end, err := OffsetToPosition(rd, offset+n)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.TextDocumentInlayHint(ctx, filename, Range{start, end})
+}
+
+//----------
+
+func (man *Manager) TextDocumentFormatting(ctx context.Context, filename string, rd iorw.ReaderAt) ([]*TextEdit, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "format")
+	if err != nil {
+		return nil, err
+	}
+	return man.textDocumentFormatting(ctx, cli, filename, rd)
+}
+
+// Formats with the server of the registration (ex: the one with the "format" flag).
+func (man *Manager) LangTextDocumentFormatting(ctx context.Context, lang *LangManager, filename string, rd iorw.ReaderAt) ([]*TextEdit, error) {
+	li, err := man.langInstance(ctx, lang, filename)
+	if err != nil {
+		return nil, err
+	}
+	return man.textDocumentFormatting(ctx, li.cli, filename, rd)
+}
+
+func (man *Manager) textDocumentFormatting(ctx context.Context, cli *Client, filename string, rd iorw.ReaderAt) ([]*TextEdit, error) {
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	return cli.TextDocumentFormatting(ctx, filename)
+}
+func (man *Manager) TextDocumentRangeFormatting(ctx context.Context, filename string, rd iorw.ReaderAt, offset, n int) ([]*TextEdit, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "format")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	pos1, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+	pos2, err := OffsetToPosition(rd, offset+n)
+	if err != nil {
+		return nil, err
+	}
+	rang := Range{Start: pos1, End: pos2}
+
+	return cli.TextDocumentRangeFormatting(ctx, filename, rang)
+}
+
+//----------
+
+// Patches the edits directly on the files.
+func (man *Manager) PatchWorkspaceEdit(ctx context.Context, we *WorkspaceEdit, prePatchFn func([]*WorkspaceEditChange) error) ([]*WorkspaceEditChange, error) {
 	wecs, err := we.GetChanges()
 	if err != nil {
 		return nil, err
@@ -310,7 +881,8 @@ func (man *Manager) TextDocumentRenameAndPatch(ctx context.Context, filename str
 		if err := os.WriteFile(filename, res, 0o644); err != nil {
 			return nil, err
 		}
-		if err := man.SyncText(ctx, filename, rd); err != nil {
+		rd2 := iorw.NewBytesReadWriterAt(res)
+		if err := man.SyncText(ctx, filename, rd2); err != nil {
 			return nil, err
 		}
 	}
@@ -318,10 +890,18 @@ func (man *Manager) TextDocumentRenameAndPatch(ctx context.Context, filename str
 	return wecs, nil
 }
 
+func (man *Manager) applyWorkspaceEdit(ctx context.Context, we *WorkspaceEdit) error {
+	if man.applyEditFn != nil {
+		return man.applyEditFn(ctx, we)
+	}
+	_, err := man.PatchWorkspaceEdit(ctx, we, nil)
+	return err
+}
+
 //----------
 
-func (man *Manager) CallHierarchyCalls(ctx context.Context, filename string, rd iorw.ReaderAt, offset int, typ CallHierarchyCallType) ([]*ManagerCallHierarchyCalls, error) {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+func (man *Manager) TextDocumentCodeAction(ctx context.Context, filename string, rd iorw.ReaderAt, offset, n int) ([]*CodeAction, error) {
+	cli, li, err := man.langInstanceClient(ctx, filename, "codeaction")
 	if err != nil {
 		return nil, err
 	}
@@ -332,39 +912,130 @@ func (man *Manager) CallHierarchyCalls(ctx context.Context, filename string, rd
 	}
 	defer didCloseFn()
 
-	pos, err := OffsetToPosition(rd, offset)
+	pos1, err := OffsetToPosition(rd, offset)
+	if err != nil {
+		return nil, err
+	}
+	pos2, err := OffsetToPosition(rd, offset+n)
 	if err != nil {
 		return nil, err
 	}
+	rang := Range{Start: pos1, End: pos2}
+
+	// diagnostics published by the same lang that overlap the range
+	diags := []*Diagnostic{}
+	man.diags.Lock()
+	for _, d := range man.diags.m[filename][li.lang] {
+		if !positionLess(d.Range.End, rang.Start) && !positionLess(rang.End, d.Range.Start) {
+			diags = append(diags, d)
+		}
+	}
+	man.diags.Unlock()
 
-	items, err := cli.TextDocumentPrepareCallHierarchy(ctx, filename, pos)
+	return cli.TextDocumentCodeAction(ctx, filename, rang, diags)
+}
+
+// Resolves an action that only carries data (no edit or command). Filename is used to get the lang instance.
+func (man *Manager) CodeActionResolve(ctx context.Context, filename string, ca *CodeAction) (*CodeAction, error) {
+	if ca.Edit != nil || ca.Command != nil || ca.Data == nil {
+		return ca, nil
+	}
+	cli, _, err := man.langInstanceClient(ctx, filename, "codeaction")
 	if err != nil {
 		return nil, err
 	}
-	if len(items) == 0 {
-		return nil, fmt.Errorf("preparecallhierarchy returned no items")
+	return cli.CodeActionResolve(ctx, ca)
+}
+
+// Filename is used to get the lang instance. The server might request edits (applyedit) before replying.
+func (man *Manager) WorkspaceExecuteCommand(ctx context.Context, filename string, cmd *Command) error {
+	cli, _, err := man.langInstanceClient(ctx, filename, "codeaction")
+	if err != nil {
+		return err
 	}
+	return cli.WorkspaceExecuteCommand(ctx, cmd)
+}
 
-	res := []*ManagerCallHierarchyCalls{}
-	for _, item := range items {
-		calls, err := cli.CallHierarchyCalls(ctx, typ, item)
-		if err != nil {
-			return nil, err
+//----------
+
+func (man *Manager) setDiagnostics(lang *LangManager, filename string, diags []*Diagnostic) {
+	if !lang.Reg.HasFeature("diagnostics") {
+		return
+	}
+	diags = slices.DeleteFunc(diags, func(d *Diagnostic) bool {
+		return d.Range == nil
+	})
+	for _, d := range diags {
+		if d.Source == "" {
+			d.Source = lang.Reg.Language
 		}
-		u := &ManagerCallHierarchyCalls{item, calls}
-		res = append(res, u)
 	}
 
-	return res, nil
+	man.diags.Lock()
+	defer man.diags.Unlock()
+	m, ok := man.diags.m[filename]
+	if !ok {
+		m = map[*LangManager][]*Diagnostic{}
+		man.diags.m[filename] = m
+	}
+	m[lang] = diags
+	if len(diags) == 0 {
+		delete(m, lang)
+		if len(m) == 0 {
+			delete(man.diags.m, filename)
+		}
+	}
+}
+
+// If lang is nil, clears all langs. If filename is empty, clears all files.
+func (man *Manager) clearDiagnostics(lang *LangManager, filename string) {
+	man.diags.Lock()
+	defer man.diags.Unlock()
+	for filename2, m := range man.diags.m {
+		if filename != "" && filename2 != filename {
+			continue
+		}
+		if lang == nil {
+			clear(m)
+		} else {
+			delete(m, lang)
+		}
+		if len(m) == 0 {
+			delete(man.diags.m, filename2)
+		}
+	}
+}
+
+// Diagnostics published by all the registrations of the file, sorted by position (registration order for the same position).
+func (man *Manager) Diagnostics(filename string) []*Diagnostic {
+	man.diags.Lock()
+	m := man.diags.m[filename]
+	w := []*Diagnostic{}
+	for _, lang := range man.langs {
+		w = append(w, m[lang]...)
+	}
+	man.diags.Unlock()
+
+	sort.SliceStable(w, func(a, b int) bool {
+		p1, p2 := w[a].Range.Start, w[b].Range.Start
+		if p1.Line == p2.Line {
+			return p1.Character < p2.Character
+		}
+		return p1.Line < p2.Line
+	})
+	return w
 }
 
 //----------
 
-func (man *Manager) TextDocumentReferences(ctx context.Context, filename string, rd iorw.ReaderAt, offset int) ([]*Location, error) {
-	cli, _, err := man.langInstanceClient(ctx, filename)
+func (man *Manager) TextDocumentCodeLens(ctx context.Context, filename string, rd iorw.ReaderAt) ([]*CodeLens, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "codelens")
 	if err != nil {
 		return nil, err
 	}
+	if !cli.supports("codelens") {
+		return nil, nil
+	}
 
 	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
 	if err != nil {
@@ -372,10 +1043,70 @@ func (man *Manager) TextDocumentReferences(ctx context.Context, filename string,
 	}
 	defer didCloseFn()
 
-	pos, err := OffsetToPosition(rd, offset)
+	return cli.TextDocumentCodeLens(ctx, filename)
+}
+
+// Resolves the lens if it has no command (returned as is if the server doesn't resolve lenses).
+func (man *Manager) CodeLensResolve(ctx context.Context, filename string, cl *CodeLens) (*CodeLens, error) {
+	if cl.Command != nil {
+		return cl, nil
+	}
+	cli, _, err := man.langInstanceClient(ctx, filename, "codelens")
 	if err != nil {
 		return nil, err
 	}
+	return cli.CodeLensResolve(ctx, cl)
+}
 
-	return cli.TextDocumentReferences(ctx, filename, pos)
+// Resolves the lens (if it has no command) and executes its command in the server that provided it. The server might request edits (applyedit) before replying.
+func (man *Manager) CodeLensExecute(ctx context.Context, filename string, cl *CodeLens) error {
+	cli, _, err := man.langInstanceClient(ctx, filename, "codelens")
+	if err != nil {
+		return err
+	}
+	if cl.Command == nil {
+		cl, err = cli.CodeLensResolve(ctx, cl)
+		if err != nil {
+			return err
+		}
+		if cl.Command == nil {
+			return fmt.Errorf("codelens: no command")
+		}
+	}
+	return cli.WorkspaceExecuteCommand(ctx, cl.Command)
+}
+
+//----------
+
+type ApplyEditFn func(context.Context, *WorkspaceEdit) error
+
+//----------
+
+func (man *Manager) TextDocumentDocumentSymbol(ctx context.Context, filename string, rd iorw.ReaderAt) ([]*DocumentSymbol, error) {
+	cli, _, err := man.langInstanceClient(ctx, filename, "symbols")
+	if err != nil {
+		return nil, err
+	}
+
+	didCloseFn, err := man.didOpen(ctx, cli, filename, rd)
+	if err != nil {
+		return nil, err
+	}
+	defer didCloseFn()
+
+	return cli.TextDocumentDocumentSymbol(ctx, filename)
+}
+
+//----------
+
+func (man *Manager) WorkspaceSymbol(ctx context.Context, language, query string) ([]*SymbolInformation, error) {
+	lang, err := man.LangManagerByLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	li, err := lang.instance(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return li.cli.WorkspaceSymbol(ctx, query)
 }
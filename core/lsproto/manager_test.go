@@ -3,6 +3,7 @@ package lsproto
 //godebug:annotatepackage
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -44,6 +45,503 @@ func TestStruct1(t *testing.T) {
 	}
 
 }
+func TestStruct2(t *testing.T) {
+	msgs := []string{
+		`{"contents":{"kind":"markdown","value":"` + "```go\\nfunc f(a_b int)\\n```\\n\\n**F** does [x](http://a)" + `"}}`,
+		`{"contents":[{"language":"go","value":"func f(a_b int)"},"**F** does [x](http://a)"]}`,
+	}
+	for _, msg := range msgs {
+		h := &Hover{}
+		if err := json.Unmarshal([]byte(msg), h); err != nil {
+			t.Fatal(err)
+		}
+		s := HoverToString(h)
+		exp := "func f(a_b int)\n\nF does x (http://a)"
+		if s != exp {
+			t.Fatalf("got %q, expected %q", s, exp)
+		}
+	}
+}
+func TestMarkdownToPlainText1(t *testing.T) {
+	ins := [][2]string{
+		{"*a* and **b**", "a and b"},
+		{"_a_ and __b__", "a and b"},
+		{"a * b", "a * b"},
+		{"*T pointer", "*T pointer"},
+		{"func(p *T) *T", "func(p *T) *T"},
+		{"a_b_c", "a_b_c"},
+		{"**a `*b*`**", "a *b*"},
+	}
+	for _, in := range ins {
+		s := MarkdownToPlainText(in[0])
+		if s != in[1] {
+			t.Fatalf("%q: got %q, expected %q", in[0], s, in[1])
+		}
+	}
+}
+func TestStruct3(t *testing.T) {
+	msgs := []string{
+		`{"signatures":[{"label":"f(a int, b string)","parameters":[{"label":"a int"},{"label":"b string"}]}],"activeParameter":1}`,
+		`{"signatures":[{"label":"f(a int, b string)","parameters":[{"label":[2,7]},{"label":[9,17]}],"activeParameter":1}]}`,
+	}
+	for _, msg := range msgs {
+		sh := &SignatureHelp{}
+		if err := json.Unmarshal([]byte(msg), sh); err != nil {
+			t.Fatal(err)
+		}
+		label, s, e, ok := SignatureHelpActive(sh)
+		if !ok || label[s:e] != "b string" {
+			t.Fatalf("got %v %v %v %v", label, s, e, ok)
+		}
+	}
+}
+func TestStruct4(t *testing.T) {
+	msg := `[{"title":"t1","command":"cmd1","arguments":[1]},{"title":"t2","kind":"quickfix","command":{"title":"t2","command":"cmd2"}},{"title":"t3","disabled":{"reason":"r3"},"edit":{"changes":{}}}]`
+	cas := []*CodeAction{}
+	if err := json.Unmarshal([]byte(msg), &cas); err != nil {
+		t.Fatal(err)
+	}
+	if cas[0].Command == nil || cas[0].Command.Command != "cmd1" || len(cas[0].Command.Arguments) != 1 {
+		t.Fatalf("%+v", cas[0])
+	}
+	if cas[1].Command == nil || cas[1].Command.Command != "cmd2" || cas[1].Kind != "quickfix" {
+		t.Fatalf("%+v", cas[1])
+	}
+	if cas[2].DisabledReason != "r3" || cas[2].Edit == nil {
+		t.Fatalf("%+v", cas[2])
+	}
+}
+func TestStruct5(t *testing.T) {
+	msg := `[{"name":"T","kind":23,"range":{"start":{"line":2,"character":5},"end":{"line":4,"character":1}},"selectionRange":{"start":{"line":2,"character":5},"end":{"line":2,"character":6}},"children":[{"name":"a","detail":"int","kind":8,"range":{"start":{"line":3,"character":1},"end":{"line":3,"character":6}},"selectionRange":{"start":{"line":3,"character":1},"end":{"line":3,"character":2}}}]}]`
+	syms := []*DocumentSymbol{}
+	if err := json.Unmarshal([]byte(msg), &syms); err != nil {
+		t.Fatal(err)
+	}
+	s := DocumentSymbolsToString(syms, "f.go")
+	exp := "\tf.go:3:6: struct T\n\t\tf.go:4:2: field a int\n"
+	if s != exp {
+		t.Fatalf("got %q, expected %q", s, exp)
+	}
+}
+func TestStruct6(t *testing.T) {
+	msg := `[{"name":"Fn","kind":12,"location":{"uri":"file:///a/b/f.go","range":{"start":{"line":9,"character":5},"end":{"line":9,"character":7}}}},{"name":"T","kind":23,"containerName":"pkg","location":{"uri":"file:///a/c.go"}}]`
+	syms := []*SymbolInformation{}
+	if err := json.Unmarshal([]byte(msg), &syms); err != nil {
+		t.Fatal(err)
+	}
+	s, err := SymbolInformationsToString(syms, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "\tb/f.go:10:6: function Fn\n\tc.go:1:1: struct T (pkg)\n"
+	if s != exp {
+		t.Fatalf("got %q, expected %q", s, exp)
+	}
+}
+func TestStruct7(t *testing.T) {
+	msg := `[{"position":{"line":1,"character":2},"label":"x:","kind":2,"paddingRight":true},{"position":{"line":0,"character":2},"label":[{"value":"int"},{"value":"\n32"}],"kind":1,"paddingLeft":true}]`
+	hints := []*InlayHint{}
+	if err := json.Unmarshal([]byte(msg), &hints); err != nil {
+		t.Fatal(err)
+	}
+	rd := iorw.NewStringReaderAt("ab\nf(1)\n")
+	w := []string{}
+	for _, h := range hints {
+		offset, err := InlayHintOffset(rd, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = append(w, fmt.Sprintf("%d:%q", offset, InlayHintLabel(h)))
+	}
+	s := strings.Join(w, ",")
+	exp := `5:"x: ",2:" int 32"`
+	if s != exp {
+		t.Fatalf("got %v, expected %v", s, exp)
+	}
+}
+func TestTextEditsUnifiedDiff1(t *testing.T) {
+	src := "package a\n\nfunc f(a int) int {\n\treturn a\n}\n\n// 1\n// 2\n// 3\n// 4\n// 5\n\nvar v = f(1)\n"
+	r := func(l1, c1, l2, c2 int) *Range {
+		return &Range{Position{l1, c1}, Position{l2, c2}}
+	}
+	edits := []*TextEdit{
+		{Range: r(12, 8, 12, 9), NewText: "g"},
+		{Range: r(2, 5, 2, 6), NewText: "g"},
+	}
+	s, err := TextEditsUnifiedDiff("a.go", []byte(src), edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "--- a/a.go\n+++ b/a.go\n" +
+		"@@ -1,6 +1,6 @@\n package a\n \n-func f(a int) int {\n+func g(a int) int {\n \treturn a\n }\n \n" +
+		"@@ -10,4 +10,4 @@\n // 4\n // 5\n \n-var v = f(1)\n+var v = g(1)\n"
+	if s != exp {
+		t.Fatalf("got:\n%v\nexpected:\n%v", s, exp)
+	}
+}
+func TestTextEditsUnifiedDiff2(t *testing.T) {
+	r := func(l1, c1, l2, c2 int) *Range {
+		return &Range{Position{l1, c1}, Position{l2, c2}}
+	}
+	ins := []struct {
+		src   string
+		edits []*TextEdit
+		exp   string
+	}{
+		{"a\nb", []*TextEdit{{Range: r(1, 0, 1, 1), NewText: "c"}},
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+		{"a\nb\n", []*TextEdit{{Range: r(1, 1, 2, 0), NewText: ""}},
+			"@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n"},
+		{"a\nb\nc\n", []*TextEdit{{Range: r(0, 1, 1, 0), NewText: ""}},
+			"@@ -1,3 +1,2 @@\n-a\n-b\n+ab\n c\n"},
+		{"a\nb", []*TextEdit{{Range: r(1, 1, 1, 1), NewText: "\n"}},
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+	}
+	for _, in := range ins {
+		src := []byte(in.src)
+		s, err := TextEditsUnifiedDiff("x", src, in.edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := "--- a/x\n+++ b/x\n" + in.exp
+		if s != exp {
+			t.Fatalf("%q: got:\n%v\nexpected:\n%v", in.src, s, exp)
+		}
+
+		// parse back
+		s = strings.ReplaceAll(s, " a/x\n", " /x\n")
+		we, err := UnifiedDiffToWorkspaceEdit([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wecs, err := we.GetChanges()
+		if err != nil || len(wecs) != 1 || wecs[0].Filename != "/x" {
+			t.Fatal(wecs, err)
+		}
+		res, err := PatchTextEdits(src, wecs[0].Edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res2, err := PatchTextEdits(src, in.edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != string(res2) {
+			t.Fatalf("%q: got %q, expected %q", in.src, res, res2)
+		}
+	}
+}
+func TestUnifiedDiffToWorkspaceEdit1(t *testing.T) {
+	// "diff -u" output, as used by gorename -d
+	s := "--- /a/a.go\t2024-01-01 00:00:00\n+++ /tmp/x.go\t2024-01-01 00:00:00\n" +
+		"@@ -1,3 +1,4 @@\n package a\n-func f() {}\n+func g() {}\n+\n var v = 1\n" +
+		"@@ -5,0 +7 @@\n+// end\n"
+	we, err := UnifiedDiffToWorkspaceEdit([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wecs, err := we.GetChanges()
+	if err != nil || len(wecs) != 1 || wecs[0].Filename != "/a/a.go" {
+		t.Fatal(wecs, err)
+	}
+	src := "package a\nfunc f() {}\nvar v = 1\n\n// 5\n"
+	res, err := PatchTextEdits([]byte(src), wecs[0].Edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "package a\nfunc g() {}\n\nvar v = 1\n\n// 5\n// end\n"
+	if string(res) != exp {
+		t.Fatalf("got %q, expected %q", res, exp)
+	}
+
+	if _, err := UnifiedDiffToWorkspaceEdit([]byte("--- a/x\n+++ b/x\n")); err == nil {
+		t.Fatal("expecting error on relative filename")
+	}
+}
+func TestProjectRoot1(t *testing.T) {
+	tmp := t.TempDir()
+	mk := func(name string) {
+		fn := filepath.Join(tmp, name)
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fn, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mk("repo/.git")
+	mk("repo/mod1/go.mod")
+	mk("repo/mod1/pkg/a.go")
+	mk("repo/tools/b.go")
+	mk("other/c.go")
+
+	for _, u := range [][2]string{
+		{"repo/mod1/pkg/a.go", "repo/mod1"},
+		{"repo/tools/b.go", "repo"},
+		{"other/c.go", "other"},
+	} {
+		root := ProjectRoot(filepath.Join(tmp, u[0]))
+		if exp := filepath.Join(tmp, u[1]); root != exp {
+			t.Fatalf("%v: got %v, expected %v", u[0], root, exp)
+		}
+	}
+
+	fs := newFolders()
+	if !fs.add("a") || fs.add("a") || !fs.add("b") {
+		t.Fatal()
+	}
+	if fs.remove("a") || !fs.remove("a") || fs.remove("a") {
+		t.Fatal()
+	}
+	if d := fs.dirs(); len(d) != 1 || d[0] != "b" {
+		t.Fatal(d)
+	}
+}
+func TestParseSnippet1(t *testing.T) {
+	s, stops := ParseSnippet("Println(${1:a ...any}, $2)$0 \\$x ${3|b,c|}")
+	if exp := "Println(a ...any, ) $x b"; s != exp {
+		t.Fatalf("got %q, expected %q", s, exp)
+	}
+	exp := []SnippetTabStop{{1, 8, 8}, {2, 18, 0}, {3, 23, 1}, {0, 19, 0}}
+	if len(stops) != len(exp) {
+		t.Fatal(stops)
+	}
+	for i, ts := range stops {
+		if *ts != exp[i] {
+			t.Fatalf("%v: got %v, expected %v", i, *ts, exp[i])
+		}
+	}
+}
+func TestCompletionItemEdits1(t *testing.T) {
+	src := "package a\n\nfunc f() {\n\tfmt.Pr\n}\n"
+	rd := iorw.NewBytesReadWriterAt([]byte(src))
+	offset := strings.Index(src, "Pr") + 2
+	ci := &CompletionItem{
+		Label:            "Println",
+		InsertTextFormat: 2,
+		TextEdit:         &CompletionTextEdit{NewText: "Println(${1:a})", Range: &Range{Position{3, 5}, Position{3, 7}}},
+		AdditionalTextEdits: []*TextEdit{
+			{Range: &Range{Position{1, 0}, Position{1, 0}}, NewText: "import \"fmt\"\n"},
+		},
+	}
+	edits, stops, err := CompletionItemEdits(rd, ci, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PatchTextEditsRW(rd, edits); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := iorw.ReadFullCopy(rd)
+	if exp := "package a\nimport \"fmt\"\n\nfunc f() {\n\tfmt.Println(a)\n}\n"; string(b) != exp {
+		t.Fatalf("got %q, expected %q", b, exp)
+	}
+	if len(stops) != 2 || string(b[stops[0].Offset:stops[0].Offset+stops[0].N]) != "a" || b[stops[1].Offset-1] != ')' {
+		t.Fatal(stops)
+	}
+}
+func TestTypeHierarchyToString1(t *testing.T) {
+	item := func(name string, kind SymbolKind, line int) *TypeHierarchyItem {
+		r := &Range{Position{line, 5}, Position{line, 6}}
+		return &TypeHierarchyItem{Name: name, Kind: kind, Uri: "file:///a/b.go", SelectionRange: r}
+	}
+	nodes := []*ManagerTypeHierarchyNode{
+		{item: item("Reader", 11, 2), children: []*ManagerTypeHierarchyNode{
+			{item: item("T2", 23, 9)},
+			{item: item("T1", 23, 7), children: []*ManagerTypeHierarchyNode{{item: item("T3", 23, 11)}}},
+		}},
+	}
+	s, err := ManagerTypeHierarchyToString(nodes, SubtypesTht, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "lsproto type hierarchy subtypes:\n" +
+		"\tb.go:3:6: Reader (interface)\n" +
+		"\t\tb.go:8:6: T1 (struct)\n" +
+		"\t\t\tb.go:12:6: T3 (struct)\n" +
+		"\t\tb.go:10:6: T2 (struct)"
+	if s != exp {
+		t.Fatalf("got:\n%v\nexpected:\n%v", s, exp)
+	}
+}
+
+func TestManagerDiagnostics1(t *testing.T) {
+	man := NewManager(nil)
+	reg1 := &Registration{Language: "lang1", Exts: []string{".x"}, Features: []string{"completion", "diagnostics"}}
+	reg2 := &Registration{Language: "lang2", Exts: []string{".x"}}
+	reg3 := &Registration{Language: "lang3", Exts: []string{".x"}, Features: []string{"definition"}}
+	for _, reg := range []*Registration{reg1, reg2, reg3} {
+		if err := man.Register(reg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	langs, err := man.LangManagers("a.x")
+	if err != nil || len(langs) != 3 {
+		t.Fatal(langs, err)
+	}
+	flangs, err := man.featureLangManagers("a.x", "definition")
+	if err != nil || len(flangs) != 2 || flangs[0] != langs[1] {
+		t.Fatal(flangs, err)
+	}
+
+	diag := func(line int, msg string) *Diagnostic {
+		return &Diagnostic{Range: &Range{Position{line, 1}, Position{line, 2}}, Message: msg}
+	}
+	man.setDiagnostics(langs[1], "/a/a.x", []*Diagnostic{diag(4, "b1"), diag(1, "b2")})
+	man.setDiagnostics(langs[0], "/a/a.x", []*Diagnostic{diag(4, "a1\nmore")})
+	man.setDiagnostics(langs[2], "/a/a.x", []*Diagnostic{diag(0, "ignored")}) // doesn't serve diagnostics
+
+	s := DiagnosticsToString("/a/a.x", man.Diagnostics("/a/a.x"), "/a")
+	exp := "\ta.x:2:2: error: b2 (lang2)\n" +
+		"\ta.x:5:2: error: a1 more (lang1)\n" +
+		"\ta.x:5:2: error: b1 (lang2)\n"
+	if s != exp {
+		t.Fatalf("got:\n%v\nexpected:\n%v", s, exp)
+	}
+
+	man.clearDiagnostics(langs[1], "")
+	if d := man.Diagnostics("/a/a.x"); len(d) != 1 {
+		t.Fatal(d)
+	}
+}
+func TestManagerRegister1(t *testing.T) {
+	man := NewManager(nil)
+	reg := &Registration{Language: "lang1", Exts: []string{".x"}, Features: []string{"completion", "diagnostic"}}
+	if err := man.Register(reg); err == nil || !strings.Contains(err.Error(), `"diagnostic"`) {
+		t.Fatal(err)
+	}
+	if _, err := man.LangManager("a.x"); err == nil {
+		t.Fatal("expecting not registered")
+	}
+	reg.Features = []string{"completion", "diagnostics"}
+	if err := man.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDocumentChange1(t *testing.T) {
+	d := &document{text: []byte("ab\ncd\nef")}
+
+	// replace "d\ne" with "X"
+	rd := iorw.NewStringReaderAt("ab\ncXf")
+	ev := &iorw.RWEvWrite{Index: 4, Dn: 3, In: 1}
+	if err := d.applyChange(rd, ev, TdskIncremental); err != nil {
+		t.Fatal(err)
+	}
+	if string(d.text) != "ab\ncXf" {
+		t.Fatalf("text: %q", d.text)
+	}
+	b, err := json.Marshal(d.changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `[{"range":{"start":{"line":1,"character":1},"end":{"line":2,"character":1}},"text":"X"}]`
+	if string(b) != exp {
+		t.Fatalf("got %s, expected %s", b, exp)
+	}
+
+	// positions before and after the last change line
+	d.changes = nil
+	rd2 := iorw.NewStringReaderAt("aZ\nb\ncXf")
+	if err := d.applyChange(rd2, &iorw.RWEvWrite{Index: 1, Dn: 0, In: 2}, TdskIncremental); err != nil {
+		t.Fatal(err)
+	}
+	rd3 := iorw.NewStringReaderAt("aZ\nb\ncX")
+	if err := d.applyChange(rd3, &iorw.RWEvWrite{Index: 7, Dn: 1, In: 0}, TdskIncremental); err != nil {
+		t.Fatal(err)
+	}
+	b, err = json.Marshal(d.changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = `[{"range":{"start":{"line":0,"character":1},"end":{"line":0,"character":1}},"text":"Z\n"},{"range":{"start":{"line":2,"character":2},"end":{"line":2,"character":3}},"text":""}]`
+	if string(b) != exp || string(d.text) != "aZ\nb\ncX" {
+		t.Fatalf("got %s %q, expected %s", b, d.text, exp)
+	}
+
+	// out of sync
+	ev2 := &iorw.RWEvWrite{Index: 0, Dn: 0, In: 2}
+	if err := d.applyChange(rd, ev2, TdskIncremental); err == nil {
+		t.Fatal("expecting error")
+	}
+}
+
+func TestServerRequestApplyEdit1(t *testing.T) {
+	man := NewManager(nil)
+	cli := &Client{li: &LangInstance{lang: &LangManager{man: man}}}
+	params := json.RawMessage(`{"edit":{"changes":{"file:///a/a.x":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":1}},"newText":"b"}]}}}`)
+
+	var got *WorkspaceEdit
+	man.SetApplyEditFn(func(ctx context.Context, we *WorkspaceEdit) error {
+		got = we
+		return fmt.Errorf("row has edits")
+	})
+	res, rerr := cli.handleServerRequest("workspace/applyEdit", params)
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	r := res.(*ApplyWorkspaceEditResult)
+	if r.Applied || r.FailureReason != "row has edits" {
+		t.Fatalf("%+v", r)
+	}
+	if wecs, err := got.GetChanges(); err != nil || len(wecs) != 1 || wecs[0].Filename != "/a/a.x" {
+		t.Fatal(wecs, err)
+	}
+
+	man.SetApplyEditFn(func(ctx context.Context, we *WorkspaceEdit) error { return nil })
+	res, _ = cli.handleServerRequest("workspace/applyEdit", params)
+	if r := res.(*ApplyWorkspaceEditResult); !r.Applied {
+		t.Fatalf("%+v", r)
+	}
+
+	// invalid params
+	if _, rerr := cli.handleServerRequest("workspace/applyEdit", json.RawMessage(`[`)); rerr == nil || rerr.Code != -32602 {
+		t.Fatal(rerr)
+	}
+}
+func TestTrace1(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tr := newTracer(buf)
+	tr.message(true, []byte(`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"a":1}}`))
+	tr.message(false, []byte(`{"jsonrpc":"2.0","id":3,"result":null}`))
+	tr.message(false, []byte(`{"jsonrpc":"2.0","method":"window/logMessage","params":{}}`))
+	tr.close()
+
+	lines := strings.Split(buf.String(), "\n")
+	exp := []string{
+		"--> request textDocument/hover id=3",
+		"\t{",
+		"\t\t\"a\": 1",
+		"\t}",
+		"<-- response textDocument/hover id=3 ",
+		"\tnull",
+		"<-- notification window/logMessage",
+		"\t{}",
+	}
+	for i, e := range exp {
+		if i >= len(lines) || !strings.Contains(lines[i], e) {
+			t.Fatalf("line %v: expected %q in:\n%v", i, e, buf.String())
+		}
+	}
+}
+
+func TestTrace2(t *testing.T) {
+	// writer blocked: messages must not block, and are dropped when the queue is full
+	unblock := make(chan struct{})
+	buf := &bytes.Buffer{}
+	w := iout.FnWriter(func(b []byte) (int, error) {
+		<-unblock
+		return buf.Write(b)
+	})
+	tr := newTracer(w)
+	for i := 0; i < traceQueueSize*2; i++ {
+		tr.message(true, []byte(`{"jsonrpc":"2.0","method":"a","params":{}}`))
+	}
+	close(unblock)
+	tr.close()
+	if !strings.Contains(buf.String(), "messages dropped") {
+		t.Fatal(buf.String())
+	}
+}
 
 //----------
 //----------
@@ -449,8 +947,8 @@ func newTestManager(t *testing.T) *Manager {
 		pylspRegistration(true, false),
 
 		// dummy
-		Registration{"dummy1", []string{".dummy1"}, "stdio", "dummy_exec", nil},
-		Registration{"dummy2", []string{".dummy2"}, "tcp", "dummy_exec", nil},
+		Registration{"dummy1", []string{".dummy1"}, "stdio", "dummy_exec", nil, nil, nil},
+		Registration{"dummy2", []string{".dummy2"}, "tcp", "dummy_exec", nil, nil, nil},
 	}
 	for _, reg := range u {
 		if err := man.Register(&reg); err != nil {
@@ -44,6 +44,7 @@ type NotificationMessage struct {
 type _notificationMessageParams struct {
 	lmp *LogMessageParams
 	any any
+	raw json.RawMessage // original params (ex: server requests)
 }
 
 func (nmp *_notificationMessageParams) MarshalJSON() ([]byte, error) {
@@ -53,6 +54,7 @@ func (nmp *_notificationMessageParams) MarshalJSON() ([]byte, error) {
 	return json.Marshal(nmp.any)
 }
 func (nmp *_notificationMessageParams) UnmarshalJSON(b []byte) error {
+	nmp.raw = append(json.RawMessage(nil), b...)
 	if err := json.Unmarshal(b, &nmp.lmp); err == nil {
 		return nil
 	}
@@ -79,6 +81,11 @@ func (res *Response) IsNotification() bool {
 	return res.NotificationMessage != nil
 }
 
+// A request sent from the server to the client (has an id and a method).
+func (res *Response) IsServerRequest() bool {
+	return res.NotificationMessage != nil && res.ResponseMessage != nil && res.Method != ""
+}
+
 //----------
 
 type ResponseMessage struct {
@@ -157,10 +164,31 @@ type CompletionItem struct {
 	Documentation _completionItemDocumentation `json:"documentation,omitempty"`
 	Deprecated    bool                         `json:"deprecated,omitempty"` // deprecated in favor of "tags"
 	Tags          []CompletionItemTag          `json:"tags,omitempty"`
+
+	InsertText          string              `json:"insertText,omitempty"`
+	InsertTextFormat    int                 `json:"insertTextFormat,omitempty"` // 1=plaintext, 2=snippet
+	TextEdit            *CompletionTextEdit `json:"textEdit,omitempty"`
+	AdditionalTextEdits []*TextEdit         `json:"additionalTextEdits,omitempty"`
 }
 type CompletionItemKind int
 type CompletionItemTag int
 
+func (k CompletionItemKind) String() string {
+	names := []string{"text", "method", "function", "constructor", "field", "variable", "class", "interface", "module", "property", "unit", "value", "enum", "keyword", "snippet", "color", "file", "reference", "folder", "enummember", "constant", "struct", "event", "operator", "typeparameter"}
+	if k >= 1 && int(k) <= len(names) {
+		return names[k-1]
+	}
+	return ""
+}
+
+// Can be a TextEdit (range) or an InsertReplaceEdit (insert/replace).
+type CompletionTextEdit struct {
+	NewText string `json:"newText"`
+	Range   *Range `json:"range,omitempty"`
+	Insert  *Range `json:"insert,omitempty"`
+	Replace *Range `json:"replace,omitempty"`
+}
+
 //----------
 
 type _completionItemDocumentation struct {
@@ -178,8 +206,21 @@ func (u *_completionItemDocumentation) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &u.mc); err == nil {
 		return nil
 	}
+	u.mc = nil
 	return json.Unmarshal(b, &u.str)
 }
+func (u *_completionItemDocumentation) String() string {
+	if u.mc != nil {
+		if u.mc.Kind == "markdown" {
+			return MarkdownToPlainText(u.mc.Value)
+		}
+		return u.mc.Value
+	}
+	if u.str != nil {
+		return *u.str
+	}
+	return ""
+}
 
 //----------
 
@@ -191,6 +232,164 @@ type MarkupKind string // ex: plaintext, markup
 
 //----------
 
+type HoverParams struct {
+	TextDocumentPositionParams
+}
+type Hover struct {
+	Contents _hoverContents `json:"contents"`
+	Range    *Range         `json:"range,omitempty"`
+}
+
+//----------
+
+// contents can be: MarkupContent | MarkedString | MarkedString[]
+type _hoverContents struct {
+	mc *MarkupContent
+	ms []*MarkedString
+}
+
+func (u *_hoverContents) UnmarshalJSON(b []byte) error {
+	// markup content (has a "kind" field)
+	mc := &MarkupContent{}
+	if err := json.Unmarshal(b, mc); err == nil && mc.Kind != "" {
+		u.mc = mc
+		return nil
+	}
+	// marked string array
+	if err := json.Unmarshal(b, &u.ms); err == nil {
+		return nil
+	}
+	// marked string
+	ms := &MarkedString{}
+	if err := json.Unmarshal(b, ms); err != nil {
+		return err
+	}
+	u.ms = []*MarkedString{ms}
+	return nil
+}
+
+//----------
+
+// deprecated in favor of markupcontent, still sent by some servers
+type MarkedString struct {
+	Language string `json:"language,omitempty"`
+	Value    string `json:"value"`
+}
+
+func (ms *MarkedString) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &ms.Value); err == nil {
+		return nil
+	}
+	type ms2 MarkedString // avoid recursion
+	return json.Unmarshal(b, (*ms2)(ms))
+}
+
+//----------
+
+type SignatureHelpParams struct {
+	TextDocumentPositionParams
+	Context *SignatureHelpContext `json:"context,omitempty"`
+}
+type SignatureHelpContext struct {
+	TriggerKind      int    `json:"triggerKind"` // 1=invoked, 2=char, 3=content change
+	TriggerCharacter string `json:"triggerCharacter,omitempty"`
+	IsRetrigger      bool   `json:"isRetrigger"`
+}
+type SignatureHelp struct {
+	Signatures      []*SignatureInformation `json:"signatures"`
+	ActiveSignature int                     `json:"activeSignature,omitempty"`
+	ActiveParameter *int                    `json:"activeParameter,omitempty"`
+}
+type SignatureInformation struct {
+	Label           string                       `json:"label"`
+	Documentation   _completionItemDocumentation `json:"documentation,omitempty"`
+	Parameters      []*ParameterInformation      `json:"parameters,omitempty"`
+	ActiveParameter *int                         `json:"activeParameter,omitempty"`
+}
+type ParameterInformation struct {
+	Label         _parameterInformationLabel   `json:"label"`
+	Documentation _completionItemDocumentation `json:"documentation,omitempty"`
+}
+
+//----------
+
+// label can be: string | [uinteger, uinteger] (utf16 offsets into the signature label)
+type _parameterInformationLabel struct {
+	str     *string
+	offsets *[2]int
+}
+
+func (u *_parameterInformationLabel) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &u.str); err == nil {
+		return nil
+	}
+	return json.Unmarshal(b, &u.offsets)
+}
+
+//----------
+
+type DocumentHighlight struct {
+	Range Range `json:"range"`
+	Kind  int   `json:"kind,omitempty"` // 1=text (default), 2=read, 3=write
+}
+
+//----------
+
+type SelectionRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Positions    []Position             `json:"positions"`
+}
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+//----------
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+type InlayHint struct {
+	Position     Position        `json:"position"`
+	Label        _inlayHintLabel `json:"label"`
+	Kind         int             `json:"kind,omitempty"` // 1=type, 2=parameter
+	PaddingLeft  bool            `json:"paddingLeft,omitempty"`
+	PaddingRight bool            `json:"paddingRight,omitempty"`
+}
+type InlayHintLabelPart struct {
+	Value string `json:"value"`
+}
+
+//----------
+
+// label can be: string | InlayHintLabelPart[]
+type _inlayHintLabel struct {
+	str   *string
+	parts []*InlayHintLabelPart
+}
+
+func (u *_inlayHintLabel) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &u.str); err == nil {
+		return nil
+	}
+	u.str = nil // could be allocated by the failed unmarshal
+	return json.Unmarshal(b, &u.parts)
+}
+
+func (u *_inlayHintLabel) String() string {
+	if u.str != nil {
+		return *u.str
+	}
+	sb := &strings.Builder{}
+	for _, p := range u.parts {
+		sb.WriteString(p.Value)
+	}
+	return sb.String()
+}
+
+//----------
+
 type DidOpenTextDocumentParams struct {
 	TextDocument TextDocumentItem `json:"textDocument"`
 }
@@ -216,11 +415,19 @@ type VersionedTextDocumentIdentifier struct {
 	Version *int `json:"version"`
 }
 type TextDocumentContentChangeEvent struct {
-	Range       Range  `json:"range,omitempty"`
+	Range       *Range `json:"range,omitempty"` // nil: full content
 	RangeLength int    `json:"rangeLength,omitempty"`
-	Text        string `json:"text,omitempty"`
+	Text        string `json:"text"`
 }
 
+type TextDocumentSyncKind int
+
+const (
+	TdskNone        TextDocumentSyncKind = 0
+	TdskFull        TextDocumentSyncKind = 1
+	TdskIncremental TextDocumentSyncKind = 2
+)
+
 type DidChangeWorkspaceFoldersParams struct {
 	Event *WorkspaceFoldersChangeEvent `json:"event,omitempty"`
 }
@@ -279,6 +486,146 @@ type TextEdit struct {
 
 //----------
 
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+type DocumentRangeFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Options      FormattingOptions      `json:"options"`
+}
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+//----------
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+type CodeActionContext struct {
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+	Only        []string      `json:"only,omitempty"`
+}
+
+// The result of a codeaction request can be a Command or a CodeAction; a Command is converted to a CodeAction with only the command set.
+type CodeAction struct {
+	Title          string         `json:"title"`
+	Kind           string         `json:"kind,omitempty"` // ex: quickfix, refactor, source
+	IsPreferred    bool           `json:"isPreferred,omitempty"`
+	DisabledReason string         `json:"-"` // not empty if disabled
+	Edit           *WorkspaceEdit `json:"edit,omitempty"`
+	Command        *Command       `json:"command,omitempty"`
+	Data           any            `json:"data,omitempty"`
+}
+
+func (ca *CodeAction) UnmarshalJSON(b []byte) error {
+	type ca0 CodeAction // avoid recursion
+	type ca2 struct {
+		ca0
+		Disabled *struct {
+			Reason string `json:"reason"`
+		} `json:"disabled,omitempty"`
+		Command   json.RawMessage `json:"command,omitempty"` // string if the result is a Command
+		Arguments []any           `json:"arguments,omitempty"`
+	}
+	u := ca2{}
+	if err := json.Unmarshal(b, &u); err != nil {
+		return err
+	}
+	*ca = CodeAction(u.ca0)
+	if u.Disabled != nil {
+		ca.DisabledReason = u.Disabled.Reason
+		if ca.DisabledReason == "" {
+			ca.DisabledReason = "disabled"
+		}
+	}
+	if len(u.Command) != 0 {
+		name := ""
+		if err := json.Unmarshal(u.Command, &name); err == nil {
+			ca.Command = &Command{Title: u.Title, Command: name, Arguments: u.Arguments}
+			return nil
+		}
+		ca.Command = &Command{}
+		return json.Unmarshal(u.Command, ca.Command)
+	}
+	return nil
+}
+
+type Command struct {
+	Title     string `json:"title"`
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+type ExecuteCommandParams struct {
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+//----------
+
+type CodeLensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+type CodeLens struct {
+	Range   *Range   `json:"range"`
+	Command *Command `json:"command,omitempty"` // not present if unresolved
+	Data    any      `json:"data,omitempty"`    // kept for the resolve request
+}
+
+//----------
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// Hierarchical form of the documentsymbol result.
+type DocumentSymbol struct {
+	Name           string            `json:"name"`
+	Detail         string            `json:"detail,omitempty"`
+	Kind           SymbolKind        `json:"kind"`
+	Tags           []SymbolTag       `json:"tags,omitempty"`
+	Range          *Range            `json:"range"`
+	SelectionRange *Range            `json:"selectionRange"`
+	Children       []*DocumentSymbol `json:"children,omitempty"`
+}
+
+// Flat form of the documentsymbol result, also used in workspace/symbol.
+type SymbolInformation struct {
+	Name          string      `json:"name"`
+	Kind          SymbolKind  `json:"kind"`
+	Tags          []SymbolTag `json:"tags,omitempty"`
+	Location      *Location   `json:"location"`
+	ContainerName string      `json:"containerName,omitempty"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// Result can be: DocumentSymbol[] | SymbolInformation[]
+type _documentSymbolResult struct {
+	DocumentSymbol
+	Location      *Location `json:"location,omitempty"`
+	ContainerName string    `json:"containerName,omitempty"`
+}
+
+//----------
+
 type CallHierarchyPrepareParams struct {
 	TextDocumentPositionParams
 }
@@ -330,6 +677,50 @@ type CallHierarchyItem struct {
 
 //----------
 
+type TypeHierarchyPrepareParams struct {
+	TextDocumentPositionParams
+}
+type TypeHierarchyParams struct { // used in supertypes/subtypes
+	Item *TypeHierarchyItem `json:"item"`
+}
+type TypeHierarchyItem struct {
+	Name           string       `json:"name"`
+	Kind           SymbolKind   `json:"kind"`
+	Tags           []*SymbolTag `json:"tags,omitempty"` // optional
+	Detail         string       `json:"detail"`         // optional
+	Uri            DocumentUri  `json:"uri"`
+	Range          *Range       `json:"range"`
+	SelectionRange *Range       `json:"selectionRange"`
+	Data           any          `json:"data,omitempty"` // optional (related to prepare calls)
+}
+
+//----------
+
+type PublishDiagnosticsParams struct {
+	Uri         DocumentUri   `json:"uri"`
+	Version     int           `json:"version,omitempty"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+type Diagnostic struct {
+	Range    *Range             `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     any                `json:"code,omitempty"` // int | string
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+	Data     any                `json:"data,omitempty"` // kept for the codeaction request
+}
+type DiagnosticSeverity int
+
+func (ds DiagnosticSeverity) String() string {
+	names := []string{"error", "warning", "info", "hint"}
+	if i := int(ds) - 1; i >= 0 && i < len(names) {
+		return names[i]
+	}
+	return "error" // not present: client interpretation
+}
+
+//----------
+
 type ReferenceParams struct {
 	TextDocumentPositionParams
 	Context ReferenceContext `json:"context"`
@@ -347,10 +738,27 @@ func (pos *Position) OneBased() (int, int) {
 	return pos.Line + 1, pos.Character + 1
 }
 
+func positionLess(a, b Position) bool {
+	if a.Line == b.Line {
+		return a.Character < b.Character
+	}
+	return a.Line < b.Line
+}
+
 type DocumentUri string
 type SymbolKind int
 type SymbolTag int
 
+func (sk SymbolKind) String() string {
+	names := []string{
+		"file", "module", "namespace", "package", "class", "method", "property", "field", "constructor", "enum", "interface", "function", "variable", "constant", "string", "number", "boolean", "array", "object", "key", "null", "enummember", "struct", "event", "operator", "typeparameter",
+	}
+	if i := int(sk) - 1; i >= 0 && i < len(names) {
+		return names[i]
+	}
+	return fmt.Sprintf("kind%d", int(sk))
+}
+
 //----------
 //----------
 //----------
@@ -365,6 +773,16 @@ const (
 
 //----------
 
+// Not part of the protocol, used to unify/simplify
+type TypeHierarchyType int
+
+const (
+	SupertypesTht TypeHierarchyType = iota
+	SubtypesTht
+)
+
+//----------
+
 // Not part of the protocol, used to unify/simplify
 type WorkspaceEditChange struct {
 	Filename string
@@ -1,7 +1,9 @@
 package lsproto
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/friedelschoen/editor/util/parseutil"
@@ -12,7 +14,10 @@ type Registration struct {
 	Exts     []string `json:"extensions"`
 	Network  string   `json:"transport"` // {stdio,tcpclient,tcp}
 	Cmd      string   `json:"command"`   // template values: {.Addr,.Host,.Port}
-	Optional []string `json:"flags"`     // {stderr,nogotoimpl}
+	Optional []string `json:"flags"`     // {stderr,nogotoimpl,format}
+	Features []string `json:"features"`  // served features, all if empty (ex: {completion,diagnostics})
+
+	InitOptions json.RawMessage `json:"initializationOptions,omitempty"` // sent as is in the initialize request
 }
 
 func (reg *Registration) HasOptional(s string) bool {
@@ -35,6 +40,11 @@ func (reg *Registration) HasOptional(s string) bool {
 	return false
 }
 
+// Several registrations can share file extensions (ex: gopls and a linter server). The features restrict what each one serves. See featureProviders for the names.
+func (reg *Registration) HasFeature(f string) bool {
+	return len(reg.Features) == 0 || slices.Contains(reg.Features, f)
+}
+
 func (reg *Registration) String() string {
 	exts := strings.Join(reg.Exts, " ")
 	if len(reg.Exts) >= 2 {
@@ -60,5 +70,15 @@ func (reg *Registration) String() string {
 		}
 		u = append(u, h)
 	}
+	if len(reg.Features) >= 1 {
+		if len(reg.Optional) == 0 {
+			u = append(u, "")
+		}
+		h := strings.Join(reg.Features, " ")
+		if len(reg.Features) >= 2 {
+			h = fmt.Sprintf("%q", h)
+		}
+		u = append(u, h)
+	}
 	return strings.Join(u, ",")
 }
@@ -0,0 +1,160 @@
+package lsproto
+
+import (
+	"sort"
+	"strings"
+)
+
+// Tab-stop of an expanded snippet. Offset/N are byte positions in the expanded text.
+type SnippetTabStop struct {
+	Num    int // 0 is the final position
+	Offset int
+	N      int // placeholder length
+}
+
+// Expands the snippet placeholders (ex: "$1", "${1:name}", "${1|a,b|}") into plain text. Returns the tab-stops ordered by number, with the final position (0) last. Mirrored tab-stops keep the first occurrence. Variables are replaced by their default value.
+func ParseSnippet(s string) (string, []*SnippetTabStop) {
+	p := &snippetParser{s: s, stops: map[int]*SnippetTabStop{}}
+	p.parse(false)
+
+	w := []*SnippetTabStop{}
+	for _, ts := range p.stops {
+		w = append(w, ts)
+	}
+	sort.Slice(w, func(i, j int) bool {
+		a, b := w[i].Num, w[j].Num
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	// final position at the end if not defined
+	if len(w) == 0 || w[len(w)-1].Num != 0 {
+		w = append(w, &SnippetTabStop{Num: 0, Offset: p.buf.Len()})
+	}
+	return p.buf.String(), w
+}
+
+//----------
+
+type snippetParser struct {
+	s     string
+	i     int
+	buf   strings.Builder
+	stops map[int]*SnippetTabStop
+}
+
+// If inside is true, stops at the closing brace.
+func (p *snippetParser) parse(inside bool) {
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch {
+		case c == '\\' && p.i+1 < len(p.s) && strings.IndexByte("$}\\,|", p.s[p.i+1]) >= 0:
+			p.buf.WriteByte(p.s[p.i+1])
+			p.i += 2
+		case c == '}' && inside:
+			p.i++
+			return
+		case c == '$':
+			p.i++
+			p.dollar()
+		default:
+			p.buf.WriteByte(c)
+			p.i++
+		}
+	}
+}
+
+func (p *snippetParser) dollar() {
+	// "$1"
+	if num, ok := p.number(); ok {
+		p.addStop(num, p.buf.Len(), 0)
+		return
+	}
+	// "$name" (variable)
+	if name := p.name(); name != "" {
+		return
+	}
+	if p.i >= len(p.s) || p.s[p.i] != '{' {
+		p.buf.WriteByte('$')
+		return
+	}
+	p.i++ // '{'
+
+	num, isNum := p.number()
+	if !isNum {
+		p.name() // variable
+	}
+	start := p.buf.Len()
+	if p.i < len(p.s) {
+		switch p.s[p.i] {
+		case ':':
+			p.i++
+			p.parse(true)
+		case '|':
+			p.i++
+			p.choice()
+		case '}':
+			p.i++
+		}
+	}
+	if isNum {
+		p.addStop(num, start, p.buf.Len()-start)
+	}
+}
+
+// Writes the first choice of "a,b|}".
+func (p *snippetParser) choice() {
+	first := true
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch {
+		case c == '\\' && p.i+1 < len(p.s):
+			if first {
+				p.buf.WriteByte(p.s[p.i+1])
+			}
+			p.i += 2
+		case c == ',':
+			first = false
+			p.i++
+		case c == '|':
+			p.i++
+			if p.i < len(p.s) && p.s[p.i] == '}' {
+				p.i++
+			}
+			return
+		default:
+			if first {
+				p.buf.WriteByte(c)
+			}
+			p.i++
+		}
+	}
+}
+
+func (p *snippetParser) number() (int, bool) {
+	k := p.i
+	n := 0
+	for ; p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9'; p.i++ {
+		n = n*10 + int(p.s[p.i]-'0')
+	}
+	return n, p.i > k
+}
+
+func (p *snippetParser) name() string {
+	k := p.i
+	for ; p.i < len(p.s); p.i++ {
+		c := p.s[p.i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || p.i > k && c >= '0' && c <= '9') {
+			break
+		}
+	}
+	return p.s[k:p.i]
+}
+
+func (p *snippetParser) addStop(num, offset, n int) {
+	if _, ok := p.stops[num]; ok {
+		return // mirror
+	}
+	p.stops[num] = &SnippetTabStop{Num: num, Offset: offset, N: n}
+}
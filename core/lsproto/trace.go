@@ -0,0 +1,156 @@
+package lsproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Writes the json-rpc messages of a lang instance in a readable form: direction, kind, method, id, latency of responses, and the indented params/result.
+// Messages are queued and written in their own goroutine: the codec never blocks on the writer (ex: a row that flushes in the UI goroutine). Messages are dropped if the queue is full.
+type tracer struct {
+	w       io.Writer
+	q       chan *traceMsg
+	done    chan struct{}
+	pending map[string]*traceReq // key: direction+id, used by the writer goroutine
+	mu      struct {
+		sync.Mutex
+		closed  bool
+		dropped int
+	}
+}
+
+type traceMsg struct {
+	write   bool
+	b       []byte
+	t       time.Time
+	dropped int // messages dropped before this one
+}
+
+type traceReq struct {
+	method string
+	start  time.Time
+}
+
+func newTracer(w io.Writer) *tracer {
+	tr := &tracer{w: w}
+	tr.q = make(chan *traceMsg, traceQueueSize)
+	tr.done = make(chan struct{})
+	tr.pending = map[string]*traceReq{}
+	go tr.writeLoop()
+	return tr
+}
+
+// Direction: write is client-to-server. Doesn't block.
+func (tr *tracer) message(write bool, b []byte) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.mu.closed {
+		return
+	}
+	m := &traceMsg{write: write, b: b, t: time.Now(), dropped: tr.mu.dropped}
+	select {
+	case tr.q <- m:
+		tr.mu.dropped = 0
+	default:
+		tr.mu.dropped++
+	}
+}
+
+// Waits for the queued messages to be written.
+func (tr *tracer) close() {
+	tr.mu.Lock()
+	if !tr.mu.closed {
+		tr.mu.closed = true
+		close(tr.q)
+	}
+	tr.mu.Unlock()
+	<-tr.done
+}
+
+func (tr *tracer) writeLoop() {
+	defer close(tr.done)
+	for m := range tr.q {
+		if m.dropped > 0 {
+			tr.write(fmt.Sprintf("%s %d messages dropped", m.t.Format("15:04:05.000"), m.dropped), nil)
+		}
+		tr.writeMsg(m)
+	}
+	// dropped after the last queued message
+	tr.mu.Lock()
+	n := tr.mu.dropped
+	tr.mu.Unlock()
+	if n > 0 {
+		tr.write(fmt.Sprintf("%s %d messages dropped", time.Now().Format("15:04:05.000"), n), nil)
+	}
+}
+
+func (tr *tracer) writeMsg(msg *traceMsg) {
+	write := msg.write
+	m := struct {
+		Id     json.RawMessage `json:"id,omitempty"`
+		Method string          `json:"method,omitempty"`
+		Params json.RawMessage `json:"params,omitempty"`
+		Result json.RawMessage `json:"result,omitempty"`
+		Error  json.RawMessage `json:"error,omitempty"`
+	}{}
+	if err := json.Unmarshal(msg.b, &m); err != nil {
+		tr.write(fmt.Sprintf("%s unable to decode: %v", traceDir(write), err), msg.b)
+		return
+	}
+
+	now := msg.t
+	id := string(m.Id)
+	body := m.Params
+	head := ""
+	switch {
+	case m.Method != "" && id != "":
+		head = fmt.Sprintf("request %s id=%s", m.Method, id)
+		tr.pending[traceDir(write)+id] = &traceReq{m.Method, now}
+	case m.Method != "":
+		head = fmt.Sprintf("notification %s", m.Method)
+	default:
+		// the request was in the opposite direction
+		key := traceDir(!write) + id
+		method, latency := "?", ""
+		if req, ok := tr.pending[key]; ok {
+			delete(tr.pending, key)
+			method = req.method
+			latency = " " + now.Sub(req.start).Round(time.Microsecond).String()
+		}
+		head = fmt.Sprintf("response %s id=%s%s", method, id, latency)
+		body = m.Result
+		if len(m.Error) > 0 {
+			head += " error"
+			body = m.Error
+		}
+	}
+	s := fmt.Sprintf("%s %s %s", now.Format("15:04:05.000"), traceDir(write), head)
+	tr.write(s, body)
+}
+
+func (tr *tracer) write(head string, body []byte) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(head)
+	buf.WriteString("\n")
+	if len(body) > 0 {
+		buf.WriteString("\t")
+		if err := json.Indent(buf, body, "\t", "\t"); err != nil {
+			buf.Write(body)
+		}
+		buf.WriteString("\n")
+	}
+	_, _ = tr.w.Write(buf.Bytes()) // best effort
+}
+
+const traceQueueSize = 256
+
+func traceDir(write bool) string {
+	if write {
+		return "-->"
+	}
+	return "<--"
+}
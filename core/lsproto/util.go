@@ -3,6 +3,7 @@ package lsproto
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -166,11 +167,22 @@ func JsonGetPath(v any, path string) (any, error) {
 }
 
 // TODO: incomplete
+// Capabilities that can be a boolean or an options object (enabled).
+func jsonBoolOrOptions(v any) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case map[string]any:
+		return true
+	}
+	return false
+}
+
 func jsonGetPath2(v any, args []string) (any, error) {
 	// handle last arg
 	if len(args) == 0 {
 		switch t := v.(type) {
-		case bool, int, float32, float64:
+		case bool, int, float32, float64, map[string]any:
 			return t, nil
 		}
 		return nil, fmt.Errorf("unhandled last type: %T", v)
@@ -270,6 +282,70 @@ func ManagerCallHierarchyCallsToString(mcalls []*ManagerCallHierarchyCalls, typ
 
 //----------
 
+type ManagerTypeHierarchyNode struct {
+	item     *TypeHierarchyItem
+	children []*ManagerTypeHierarchyNode
+}
+
+func ManagerTypeHierarchyToString(nodes []*ManagerTypeHierarchyNode, typ TypeHierarchyType, baseDir string) (string, error) {
+	res := []string{}
+
+	// build title
+	s1 := "supertypes"
+	if typ == SubtypesTht {
+		s1 = "subtypes"
+	}
+	u := fmt.Sprintf("lsproto type hierarchy %s:", s1)
+	res = append(res, u)
+
+	var add func(node *ManagerTypeHierarchyNode, depth int) error
+	add = func(node *ManagerTypeHierarchyNode, depth int) error {
+		item := node.item
+		filename, err := UrlToAbsFilename(string(item.Uri))
+		if err != nil {
+			return err
+		}
+		// use basedir to output filename
+		if baseDir != "" {
+			if u, err := filepath.Rel(baseDir, filename); err == nil {
+				filename = u
+			}
+		}
+		r := item.SelectionRange
+		if r == nil {
+			r = item.Range
+		}
+		line, col := 1, 1
+		if r != nil {
+			line, col = r.Start.OneBased()
+		}
+		indent := strings.Repeat("\t", depth+1)
+		u := fmt.Sprintf("%s%s:%d:%d: %s (%v)", indent, filename, line, col, item.Name, item.Kind)
+		res = append(res, u)
+
+		// sorted children
+		children := append([]*ManagerTypeHierarchyNode{}, node.children...)
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].item.Name < children[j].item.Name
+		})
+		for _, c := range children {
+			if err := add(c, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, node := range nodes {
+		if err := add(node, 0); err != nil {
+			return "", err
+		}
+	}
+	w := strings.Join(res, "\n")
+	return w, nil
+}
+
+//----------
+
 func LocationsToString(locations []*Location, baseDir string) (string, error) {
 	type loc2 struct { // for sorting
 		a    string
@@ -314,6 +390,26 @@ func LocationsToString(locations []*Location, baseDir string) (string, error) {
 
 //----------
 
+// One line per diagnostic: "file:line:col: severity: message (source)".
+func DiagnosticsToString(filename string, diags []*Diagnostic, baseDir string) string {
+	// use basedir to output filename
+	if baseDir != "" {
+		if u, err := filepath.Rel(baseDir, filename); err == nil {
+			filename = u
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, d := range diags {
+		line, col := d.Range.Start.OneBased()
+		msg := strings.Join(strings.Fields(d.Message), " ")
+		fmt.Fprintf(buf, "\t%v:%v:%v: %v: %v (%v)\n", filename, line, col, d.Severity, msg, d.Source)
+	}
+	return buf.String()
+}
+
+//----------
+
 func CompletionListToString(clist *CompletionList) []string {
 	res := []string{}
 	for _, ci := range clist.Items {
@@ -340,6 +436,83 @@ func CompletionListToString(clist *CompletionList) []string {
 	return res
 }
 
+// Single line with the label, kind and detail of the item.
+func CompletionItemString(ci *CompletionItem) string {
+	u := []string{}
+	if ci.Deprecated {
+		u = append(u, "*deprecated*")
+	}
+	u = append(u, strings.TrimSpace(ci.Label)) // NOTE: clangd is sending with spaces
+	if k := ci.Kind.String(); k != "" {
+		u = append(u, "("+k+")")
+	}
+	if ci.Detail != "" {
+		u = append(u, ci.Detail)
+	}
+	s := strings.Join(u, " ")
+	return strings.Join(strings.Fields(s), " ") // single line
+}
+
+// Edits that apply the item completed at the offset (cursor), including the additional edits. Snippets are expanded. The tab-stops offsets are in the content after the edits are applied.
+func CompletionItemEdits(rd iorw.ReaderAt, ci *CompletionItem, offset int) ([]*TextEdit, []*SnippetTabStop, error) {
+	// main edit
+	text := ci.InsertText
+	if text == "" {
+		text = strings.TrimSpace(ci.Label)
+	}
+	var rang *Range
+	if te := ci.TextEdit; te != nil {
+		text = te.NewText
+		rang = te.Range
+		if rang == nil {
+			rang = te.Insert
+		}
+	}
+	if rang == nil {
+		// replace the word before the offset
+		i0, size, err := iorw.RuneLastIndexFn(rd, offset, false, iorw.IsWordRune)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, nil, err
+		}
+		start, err := OffsetToPosition(rd, i0+size)
+		if err != nil {
+			return nil, nil, err
+		}
+		end, err := OffsetToPosition(rd, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		rang = &Range{Start: start, End: end}
+	}
+	stops := []*SnippetTabStop{{Num: 0, Offset: len(text)}}
+	if ci.InsertTextFormat == 2 {
+		text, stops = ParseSnippet(text)
+	}
+	main := &TextEdit{Range: rang, NewText: text}
+
+	// main edit offset after the additional edits
+	start, _, err := RangeToOffsetLen(rd, rang)
+	if err != nil {
+		return nil, nil, err
+	}
+	start2 := start
+	for _, e := range ci.AdditionalTextEdits {
+		o, n, err := RangeToOffsetLen(rd, e.Range)
+		if err != nil {
+			return nil, nil, err
+		}
+		if o+n <= start {
+			start2 += len(e.NewText) - n
+		}
+	}
+	for _, ts := range stops {
+		ts.Offset += start2
+	}
+
+	edits := append([]*TextEdit{main}, ci.AdditionalTextEdits...)
+	return edits, stops, nil
+}
+
 //----------
 
 func PatchTextEdits(src []byte, edits []*TextEdit) ([]byte, error) {
@@ -360,6 +533,22 @@ func PatchTextEdits(src []byte, edits []*TextEdit) ([]byte, error) {
 	return res.Bytes(), nil
 }
 
+// Patches the edits directly in the rw (ex: a textarea rw with undo history). Edits are applied from the last to the first, so the earlier ranges remain valid.
+func PatchTextEditsRW(rw iorw.ReadWriterAt, edits []*TextEdit) error {
+	sortTextEdits(edits)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		offset, n, err := RangeToOffsetLen(rw, e.Range)
+		if err != nil {
+			return err
+		}
+		if err := rw.OverwriteAt(offset, n, []byte(e.NewText)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func sortTextEdits(edits []*TextEdit) {
 	sort.Slice(edits, func(i, j int) bool {
 		p1, p2 := &edits[i].Range.Start, &edits[j].Range.Start
@@ -369,3 +558,577 @@ func sortTextEdits(edits []*TextEdit) {
 }
 
 //----------
+
+// Unified diff of the edits applied to src, with 3 context lines. The name is used in the diff header.
+func TextEditsUnifiedDiff(name string, src []byte, edits []*TextEdit) (string, error) {
+	sortTextEdits(edits)
+	rd := iorw.NewBytesReadWriterAt(src)
+
+	lineStart := func(i int) int {
+		return bytes.LastIndexByte(src[:i], '\n') + 1
+	}
+	lineEnd := func(i int) int { // after the newline
+		k := bytes.IndexByte(src[i:], '\n')
+		if k < 0 {
+			return len(src)
+		}
+		return i + k + 1
+	}
+
+	// changed regions in whole lines
+	type edit struct {
+		offset, n int
+		text      string
+	}
+	type region struct {
+		s, e  int
+		edits []*edit
+	}
+	rs := []*region{}
+	for _, e := range edits {
+		offset, n, err := RangeToOffsetLen(rd, e.Range)
+		if err != nil {
+			return "", err
+		}
+		ed := &edit{offset, n, e.NewText}
+		s := lineStart(offset)
+		end := offset + n
+		// whole lines, and the next line joins if the newline is replaced
+		if n == 0 || src[end-1] != '\n' || !strings.HasSuffix(e.NewText, "\n") {
+			end = lineEnd(end)
+		}
+		if k := len(rs); k > 0 && s < rs[k-1].e {
+			rs[k-1].e = max(rs[k-1].e, end)
+			rs[k-1].edits = append(rs[k-1].edits, ed)
+			continue
+		}
+		rs = append(rs, &region{s, end, []*edit{ed}})
+	}
+
+	// hunks: regions with context lines, merged if touching
+	type hunk struct {
+		s, e int
+		rs   []*region
+	}
+	hs := []*hunk{}
+	for _, r := range rs {
+		s, e := r.s, r.e
+		for i := 0; i < 3 && s > 0; i++ {
+			s = lineStart(s - 1)
+		}
+		for i := 0; i < 3 && e < len(src); i++ {
+			e = lineEnd(e)
+		}
+		if k := len(hs); k > 0 && s <= hs[k-1].e {
+			hs[k-1].e = e
+			hs[k-1].rs = append(hs[k-1].rs, r)
+			continue
+		}
+		hs = append(hs, &hunk{s, e, []*region{r}})
+	}
+	if len(hs) == 0 {
+		return "", nil
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "--- a/%s\n+++ b/%s\n", name, name)
+	delta := 0 // lines added by previous hunks
+	for _, h := range hs {
+		body := &strings.Builder{}
+		nOld, nNew := 0, 0
+		writeLines := func(prefix string, b []byte) int {
+			lines := diffLines(b)
+			for _, l := range lines {
+				body.WriteString(prefix + l + "\n")
+			}
+			if len(b) > 0 && b[len(b)-1] != '\n' {
+				body.WriteString("\\ No newline at end of file\n")
+			}
+			return len(lines)
+		}
+
+		pos := h.s
+		for _, r := range h.rs {
+			k := writeLines(" ", src[pos:r.s])
+			nOld += k
+			nNew += k
+
+			// new content of the region
+			buf := []byte{}
+			cur := r.s
+			for _, e := range r.edits {
+				if e.offset < cur { // overlapping edits
+					return "", fmt.Errorf("overlapping edits")
+				}
+				buf = append(buf, src[cur:e.offset]...)
+				buf = append(buf, e.text...)
+				cur = e.offset + e.n
+			}
+			buf = append(buf, src[cur:r.e]...)
+
+			nOld += writeLines("-", src[r.s:r.e])
+			nNew += writeLines("+", buf)
+			pos = r.e
+		}
+		k := writeLines(" ", src[pos:h.e])
+		nOld += k
+		nNew += k
+
+		oldStart := 1 + bytes.Count(src[:h.s], []byte("\n"))
+		newStart := oldStart + delta
+		delta += nNew - nOld
+		if nOld == 0 {
+			oldStart--
+		}
+		if nNew == 0 {
+			newStart--
+		}
+		fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, nOld, newStart, nNew)
+		sb.WriteString(body.String())
+	}
+	return sb.String(), nil
+}
+
+// Parses a unified diff (ex: output of "diff -u") into edits of the old files. The old filenames must be absolute (a timestamp after a tab is ignored).
+func UnifiedDiffToWorkspaceEdit(b []byte) (*WorkspaceEdit, error) {
+	lines := strings.Split(string(b), "\n")
+	we := &WorkspaceEdit{}
+	var tde *TextDocumentEdit
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		switch {
+		case strings.HasPrefix(l, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			name, _, _ := strings.Cut(l[4:], "\t")
+			name = strings.TrimSpace(name)
+			if !filepath.IsAbs(name) {
+				return nil, fmt.Errorf("diff: not an absolute filename: %v", name)
+			}
+			url, err := AbsFilenameToUrl(name)
+			if err != nil {
+				return nil, err
+			}
+			tde = &TextDocumentEdit{}
+			tde.TextDocument.Uri = DocumentUri(url)
+			we.DocumentChanges = append(we.DocumentChanges, tde)
+			i += 2
+		case strings.HasPrefix(l, "@@ "):
+			if tde == nil {
+				return nil, fmt.Errorf("diff: hunk without file header: %v", l)
+			}
+			edits, k, err := parseUnifiedDiffHunk(lines[i:])
+			if err != nil {
+				return nil, err
+			}
+			tde.Edits = append(tde.Edits, edits...)
+			i += k
+		default:
+			i++
+		}
+	}
+	return we, nil
+}
+
+// Returns the edits and the number of lines used.
+func parseUnifiedDiffHunk(lines []string) ([]*TextEdit, int, error) {
+	// header: "@@ -l,n +l,n @@", counts default to 1
+	var oldStart, oldN, newN int
+	f := strings.Fields(lines[0])
+	if len(f) < 3 {
+		return nil, 0, fmt.Errorf("diff: bad hunk header: %v", lines[0])
+	}
+	parse := func(s, prefix string) (int, int, error) {
+		a, b, ok := strings.Cut(strings.TrimPrefix(s, prefix), ",")
+		start, n := 0, 1
+		_, err := fmt.Sscan(a, &start)
+		if err == nil && ok {
+			_, err = fmt.Sscan(b, &n)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("diff: bad hunk header: %v", lines[0])
+		}
+		return start, n, nil
+	}
+	oldStart, oldN, err := parse(f[1], "-")
+	if err != nil {
+		return nil, 0, err
+	}
+	_, newN, err = parse(f[2], "+")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	line := oldStart - 1 // zero based
+	if oldN == 0 {       // insertion after the line
+		line = oldStart
+	}
+
+	// pending edit: consecutive removed/added lines
+	type edit struct {
+		start, n         int
+		lastDel          string
+		add              []string
+		noNlOld, noNlNew bool
+	}
+	edits := []*TextEdit{}
+	var e *edit
+	flush := func() {
+		if e == nil {
+			return
+		}
+		end := Position{Line: e.start + e.n}
+		if e.noNlOld && e.n > 0 {
+			end = Position{Line: e.start + e.n - 1, Character: len(utf16.Encode([]rune(e.lastDel)))}
+		}
+		text := strings.Join(e.add, "\n")
+		if len(e.add) > 0 && !e.noNlNew {
+			text += "\n"
+		}
+		edits = append(edits, &TextEdit{Range: &Range{Start: Position{Line: e.start}, End: end}, NewText: text})
+		e = nil
+	}
+	prev := byte(' ')
+	i := 1
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if l == "" { // empty context line (some tools trim the space)
+			l = " "
+		}
+		switch l[0] {
+		case ' ':
+			flush()
+			line++
+			oldN--
+			newN--
+		case '-':
+			if e == nil {
+				e = &edit{start: line}
+			}
+			e.n++
+			e.lastDel = l[1:]
+			line++
+			oldN--
+		case '+':
+			if e == nil {
+				e = &edit{start: line}
+			}
+			e.add = append(e.add, l[1:])
+			newN--
+		case '\\': // "\ No newline at end of file", applies to the previous line
+			if e != nil && prev == '-' {
+				e.noNlOld = true
+			}
+			if e != nil && prev == '+' {
+				e.noNlNew = true
+			}
+		default:
+			flush()
+			return edits, i, nil
+		}
+		prev = l[0]
+		if oldN <= 0 && newN <= 0 && !(i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\\")) {
+			i++
+			break
+		}
+	}
+	flush()
+	return edits, i, nil
+}
+
+func diffLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.Split(s, "\n")
+}
+
+//----------
+
+func HoverToString(h *Hover) string {
+	u := []string{}
+	if mc := h.Contents.mc; mc != nil {
+		s := mc.Value
+		if mc.Kind == "markdown" {
+			s = MarkdownToPlainText(s)
+		}
+		u = append(u, s)
+	}
+	for _, ms := range h.Contents.ms {
+		s := ms.Value
+		if ms.Language == "" { // markdown string
+			s = MarkdownToPlainText(s)
+		}
+		u = append(u, s)
+	}
+	for i, s := range u {
+		u[i] = strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(strings.Join(u, "\n\n"))
+}
+
+//----------
+
+// Reduces markdown to plain text. The content of code blocks is kept as is.
+func MarkdownToPlainText(s string) string {
+	sb := &strings.Builder{}
+	inCode := false
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode // fence line is left empty
+			continue
+		}
+		if inCode {
+			sb.WriteString(line)
+			continue
+		}
+		sb.WriteString(markdownLineToPlainText(line))
+	}
+	// fence lines leave empty lines behind
+	res := sb.String()
+	for strings.Contains(res, "\n\n\n") {
+		res = strings.ReplaceAll(res, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(res)
+}
+func markdownLineToPlainText(line string) string {
+	// headings
+	t := strings.TrimLeft(line, " ")
+	if strings.HasPrefix(t, "#") {
+		t2 := strings.TrimLeft(t, "#")
+		if t2 == "" || t2[0] == ' ' {
+			line = strings.TrimSpace(t2)
+		}
+	}
+	// horizontal rules
+	if t := strings.TrimSpace(line); len(t) >= 3 && strings.Trim(t, "-*_") == "" {
+		return ""
+	}
+
+	sb := &strings.Builder{}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '\\': // escaped char
+			if i+1 < len(line) && strings.IndexByte("\\`*_{}[]()#+-.!<>|~", line[i+1]) >= 0 {
+				i++
+				sb.WriteByte(line[i])
+				continue
+			}
+		case '`': // inline code: keep content as is
+			if k := strings.IndexByte(line[i+1:], '`'); k >= 0 {
+				sb.WriteString(line[i+1 : i+1+k])
+				i += k + 1
+				continue
+			}
+		case '*', '_': // paired emphasis: "*a*", "**a**", "_a_"; keeps "a * b", "*T", "a_b"
+			if inner, k, ok := markdownEmphasis(line, i); ok {
+				sb.WriteString(markdownLineToPlainText(inner))
+				i = k - 1
+				continue
+			}
+		case '[': // link: "[text](url)"
+			if k := strings.Index(line[i:], "]("); k >= 0 {
+				if e := strings.IndexByte(line[i+k:], ')'); e >= 0 {
+					text := markdownLineToPlainText(line[i+1 : i+k])
+					url := line[i+k+2 : i+k+e]
+					fmt.Fprintf(sb, "%v (%v)", text, url)
+					i += k + e
+					continue
+				}
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// Returns the emphasized text that starts at i, and the index after the closing delimiter.
+func markdownEmphasis(line string, i int) (string, int, bool) {
+	c := line[i]
+	if c == '_' && i > 0 && isWordByte(line[i-1]) {
+		return "", 0, false
+	}
+	n := 1
+	if i+1 < len(line) && line[i+1] == c {
+		n = 2
+	}
+	delim := line[i : i+n]
+	s := i + n
+	if s >= len(line) || line[s] == ' ' {
+		return "", 0, false
+	}
+	for k := s + 1; k+n <= len(line); k++ {
+		if line[k:k+n] != delim || line[k-1] == ' ' {
+			continue
+		}
+		e := k + n
+		if c == '_' && e < len(line) && isWordByte(line[e]) {
+			continue
+		}
+		return line[s:k], e, true
+	}
+	return "", 0, false
+}
+func isWordByte(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+//----------
+
+// Single line label of the hint, with the requested padding.
+func InlayHintLabel(h *InlayHint) string {
+	s := h.Label.String()
+	s = strings.Join(strings.Fields(s), " ") // single line
+	if h.PaddingLeft {
+		s = " " + s
+	}
+	if h.PaddingRight {
+		s += " "
+	}
+	return s
+}
+
+// Offset of the hint position.
+func InlayHintOffset(rd iorw.ReaderAt, h *InlayHint) (int, error) {
+	offset, _, err := RangeToOffsetLen(rd, &Range{h.Position, h.Position})
+	return offset, err
+}
+
+//----------
+
+// Returns the active signature label and the byte range of the active parameter in the label (if any).
+func SignatureHelpActive(sh *SignatureHelp) (label string, paramStart, paramEnd int, ok bool) {
+	if len(sh.Signatures) == 0 {
+		return "", 0, 0, false
+	}
+	k := sh.ActiveSignature
+	if k < 0 || k >= len(sh.Signatures) {
+		k = 0
+	}
+	si := sh.Signatures[k]
+	label = si.Label
+
+	active := sh.ActiveParameter
+	if si.ActiveParameter != nil { // has priority
+		active = si.ActiveParameter
+	}
+	if active == nil || *active < 0 || *active >= len(si.Parameters) {
+		return label, 0, 0, true
+	}
+
+	pl := &si.Parameters[*active].Label
+	switch {
+	case pl.offsets != nil:
+		// utf16 offsets
+		u := utf16.Encode([]rune(label))
+		s, e := pl.offsets[0], pl.offsets[1]
+		if s < 0 || s > e || e > len(u) {
+			return label, 0, 0, true
+		}
+		paramStart = len(string(utf16.Decode(u[:s])))
+		paramEnd = len(string(utf16.Decode(u[:e])))
+	case pl.str != nil:
+		i := strings.Index(label, *pl.str)
+		if i < 0 {
+			return label, 0, 0, true
+		}
+		paramStart, paramEnd = i, i+len(*pl.str)
+	}
+	return label, paramStart, paramEnd, true
+}
+
+//----------
+
+// Outputs an indented tree of clickable "filename:line:col: kind name detail" lines.
+func DocumentSymbolsToString(syms []*DocumentSymbol, filename string) string {
+	pos := func(ds *DocumentSymbol) Position {
+		if ds.SelectionRange != nil {
+			return ds.SelectionRange.Start
+		}
+		if ds.Range != nil {
+			return ds.Range.Start
+		}
+		return Position{}
+	}
+	buf := &bytes.Buffer{}
+	var print func([]*DocumentSymbol, int)
+	print = func(syms []*DocumentSymbol, depth int) {
+		sort.SliceStable(syms, func(a, b int) bool {
+			p1, p2 := pos(syms[a]), pos(syms[b])
+			return p1.Line < p2.Line || (p1.Line == p2.Line && p1.Character < p2.Character)
+		})
+		for _, ds := range syms {
+			p := pos(ds)
+			line, col := p.OneBased()
+			indent := strings.Repeat("\t", depth+1)
+			fmt.Fprintf(buf, "%s%s:%d:%d: %v %s", indent, filename, line, col, ds.Kind, ds.Name)
+			if ds.Detail != "" {
+				fmt.Fprintf(buf, " %s", strings.ReplaceAll(ds.Detail, "\n", " "))
+			}
+			fmt.Fprintf(buf, "\n")
+			print(ds.Children, depth+1)
+		}
+	}
+	print(syms, 0)
+	return buf.String()
+}
+
+//----------
+
+func SymbolInformationsToString(syms []*SymbolInformation, baseDir string) (string, error) {
+	type sym2 struct { // for sorting
+		filename  string
+		line, col int
+		si        *SymbolInformation
+	}
+
+	res := []*sym2{}
+	for _, si := range syms {
+		if si.Location == nil {
+			continue
+		}
+		filename, err := UrlToAbsFilename(string(si.Location.Uri))
+		if err != nil {
+			return "", err
+		}
+
+		// use basedir to output filename
+		if baseDir != "" {
+			if u, err := filepath.Rel(baseDir, filename); err == nil {
+				filename = u
+			}
+		}
+
+		// location might have no range (workspacesymbol)
+		line, col := 1, 1
+		if si.Location.Range != nil {
+			line, col = si.Location.Range.Start.OneBased()
+		}
+		res = append(res, &sym2{filename, line, col, si})
+	}
+
+	sort.SliceStable(res, func(a, b int) bool {
+		if res[a].filename == res[b].filename {
+			if res[a].line == res[b].line {
+				return res[a].col < res[b].col
+			}
+			return res[a].line < res[b].line
+		}
+		return res[a].filename < res[b].filename
+	})
+
+	buf := &bytes.Buffer{}
+	for _, r := range res {
+		fmt.Fprintf(buf, "\t%s:%d:%d: %v %s", r.filename, r.line, r.col, r.si.Kind, r.si.Name)
+		if r.si.ContainerName != "" {
+			fmt.Fprintf(buf, " (%s)", r.si.ContainerName)
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,105 @@
+package lsproto
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Files that mark the root directory of a project.
+var projectRootMarkers = []string{"go.mod", "compile_commands.json", ".git"}
+
+// Project root of the file: the closest parent directory with a project root marker. Defaults to the file directory.
+func ProjectRoot(filename string) string {
+	dir0 := filepath.Dir(filename)
+	dir := dir0
+	for {
+		for _, name := range projectRootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir0
+		}
+		dir = parent
+	}
+}
+
+//----------
+
+func workspaceFolders(dirs []string) ([]*WorkspaceFolder, error) {
+	w := []*WorkspaceFolder{}
+	for _, dir := range dirs {
+		url, err := AbsFilenameToUrl(dir)
+		if err != nil {
+			return nil, err
+		}
+		wf := &WorkspaceFolder{Uri: DocumentUri(url), Name: filepath.Base(dir)}
+		w = append(w, wf)
+	}
+	return w, nil
+}
+
+//----------
+
+// Project roots of the documents kept open, per lang manager.
+type folders struct {
+	sync.Mutex
+	m map[string]int // root dir -> number of kept documents
+}
+
+func newFolders() *folders {
+	return &folders{m: map[string]int{}}
+}
+
+// Returns true if the dir is new.
+func (fs *folders) add(dir string) bool {
+	fs.Lock()
+	defer fs.Unlock()
+	fs.m[dir]++
+	return fs.m[dir] == 1
+}
+
+// Returns true if the dir was removed.
+func (fs *folders) remove(dir string) bool {
+	fs.Lock()
+	defer fs.Unlock()
+	n, ok := fs.m[dir]
+	if !ok {
+		return false
+	}
+	if n > 1 {
+		fs.m[dir] = n - 1
+		return false
+	}
+	delete(fs.m, dir)
+	return true
+}
+
+func (fs *folders) dirs() []string {
+	fs.Lock()
+	defer fs.Unlock()
+	w := []string{}
+	for dir := range fs.m {
+		w = append(w, dir)
+	}
+	sort.Strings(w)
+	return w
+}
+
+//----------
+
+// Sends the folder change to the running instance (if any). Doesn't start a server.
+func (lang *LangManager) sendFoldersChange(ctx context.Context, added, removed []string) {
+	li, ok := lang.runningInstance()
+	if !ok {
+		return
+	}
+	if err := li.cli.WorkspaceDidChangeWorkspaceFolders(ctx, added, removed); err != nil {
+		lang.PrintWrapError(err)
+	}
+}
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+// Safe to use concurrently (ex: server requests), but not from the UI goroutine. Files open in rows are patched as undoable edits (saving is up to the user), others are written to disk.
+func (ed *Editor) LSProtoApplyWorkspaceEdit(ctx context.Context, we *lsproto.WorkspaceEdit) error {
+	wecs, err := we.GetChanges()
+	if err != nil {
+		return err
+	}
+
+	files := []*lsproto.WorkspaceEditChange{}
+	ed.UI.WaitRunOnUIGoRoutine(func() {
+		for _, wec := range wecs {
+			patched, err2 := ed.lsprotoPatchRow(wec)
+			if err2 != nil {
+				err = err2
+				return
+			}
+			if !patched {
+				files = append(files, wec)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, wec := range files {
+		b, err := os.ReadFile(wec.Filename)
+		if err != nil {
+			return err
+		}
+		res, err := lsproto.PatchTextEdits(b, wec.Edits)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(wec.Filename, res, 0o644); err != nil {
+			return err
+		}
+		// open rows send their changes on write; sync the written files
+		rd := iorw.NewBytesReadWriterAt(res)
+		if err := ed.LSProtoMan.SyncText(ctx, wec.Filename, rd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Patches the row of the file as a single undoable edit. Returns false if there is no row open for the file. UI goroutine.
+func (ed *Editor) lsprotoPatchRow(wec *lsproto.WorkspaceEditChange) (bool, error) {
+	info, ok := ed.ERowInfo(wec.Filename)
+	if !ok || len(info.ERows) == 0 {
+		return false, nil
+	}
+	// duplicate rows share the content and undo history
+	ta := info.ERows[0].Row.TextArea
+	ta.BeginUndoGroup()
+	defer ta.EndUndoGroup()
+	return true, lsproto.PatchTextEditsRW(ta.RW(), wec.Edits)
+}
+
+// Safe to use concurrently. Filename is used to get the lang instance to execute the action command (if any).
+func (ed *Editor) LSProtoApplyCodeAction(ctx context.Context, filename string, ca *lsproto.CodeAction) error {
+	if ca.DisabledReason != "" {
+		return fmt.Errorf("code action disabled: %v", ca.DisabledReason)
+	}
+	// actions might only carry data to be resolved
+	ca, err := ed.LSProtoMan.CodeActionResolve(ctx, filename, ca)
+	if err != nil {
+		return err
+	}
+	if ca.Edit != nil {
+		if err := ed.LSProtoApplyWorkspaceEdit(ctx, ca.Edit); err != nil {
+			return err
+		}
+	}
+	// the command runs after the edit (if both are present); the server might request more edits
+	if ca.Command != nil {
+		if err := ed.LSProtoMan.WorkspaceExecuteCommand(ctx, filename, ca.Command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs with a busy cursor on the row. Canceled with the row internal cmds (ex: esc key).
+func (ed *Editor) LSProtoApplyCodeActionAsync(erow *ERow, ca *lsproto.CodeAction) {
+	ctx, cancel := erow.newInternalCmdCtx()
+	ed.RunAsyncBusyCursor(erow.Row, func() {
+		defer cancel()
+		ctx2, cancel2 := context.WithTimeout(ctx, 8*time.Second)
+		defer cancel2()
+		if err := ed.LSProtoApplyCodeAction(ctx2, erow.Info.Name(), ca); err != nil {
+			ed.Errorf("codeaction: %w", err)
+		}
+	})
+}
+
+//----------
+
+// Workspace edit waiting for confirmation (LSProtoApplyPreview), shown as a diff.
+type wsEditPreview struct {
+	wecs  []*lsproto.WorkspaceEditChange
+	texts map[string][]byte // content used for the diff, must be the same when applying
+}
+
+// Writes a unified diff of the changes to w, and keeps them to be applied with LSProtoApplyPreview. Replaces a previous preview. The content of open rows is used (may have edits).
+// Not to be called from the UI goroutine.
+func (ed *Editor) LSProtoPreviewWorkspaceEdit(we *lsproto.WorkspaceEdit, w io.Writer, baseDir string) error {
+	wecs, err := we.GetChanges()
+	if err != nil {
+		return err
+	}
+	if len(wecs) == 0 {
+		return fmt.Errorf("no changes")
+	}
+
+	p := &wsEditPreview{wecs: wecs, texts: map[string][]byte{}}
+	for _, wec := range wecs {
+		b, err := ed.lsprotoFileContent(wec.Filename)
+		if err != nil {
+			return err
+		}
+		p.texts[wec.Filename] = b
+
+		name := wec.Filename
+		if u, err := filepath.Rel(baseDir, name); err == nil {
+			name = u
+		}
+		// diff sorts the edits, use a copy
+		edits := append([]*lsproto.TextEdit(nil), wec.Edits...)
+		s, err := lsproto.TextEditsUnifiedDiff(name, b, edits)
+		if err != nil {
+			return fmt.Errorf("%v: %w", wec.Filename, err)
+		}
+		fmt.Fprint(w, s)
+	}
+
+	ed.UI.WaitRunOnUIGoRoutine(func() {
+		ed.wsEditPreview = p
+	})
+	return nil
+}
+
+// Applies the changes of the last preview. Files open in rows are patched as undoable edits (saving is up to the user), others are written to disk.
+// Fails if any of the files changed since the preview. UI goroutine.
+func (ed *Editor) LSProtoApplyPreview() error {
+	p := ed.wsEditPreview
+	if p == nil {
+		return fmt.Errorf("no changes to apply")
+	}
+
+	// check all before patching any
+	for _, wec := range p.wecs {
+		b, err := ed.lsprotoFileContent2(wec.Filename)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(b, p.texts[wec.Filename]) {
+			return fmt.Errorf("changed since the preview: %v", wec.Filename)
+		}
+	}
+	ed.wsEditPreview = nil
+
+	written := []*lsproto.WorkspaceEditChange{}
+	for _, wec := range p.wecs {
+		if patched, err := ed.lsprotoPatchRow(wec); err != nil {
+			return err
+		} else if patched {
+			continue
+		}
+
+		res, err := lsproto.PatchTextEdits(p.texts[wec.Filename], wec.Edits)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(wec.Filename, res, 0o644); err != nil {
+			return err
+		}
+		written = append(written, wec)
+	}
+
+	// open rows send their changes on write; sync the written files
+	if len(written) > 0 {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+			defer cancel()
+			for _, wec := range written {
+				b, err := os.ReadFile(wec.Filename)
+				if err != nil {
+					continue
+				}
+				rd := iorw.NewBytesReadWriterAt(b)
+				_ = ed.LSProtoMan.SyncText(ctx, wec.Filename, rd) // best effort
+			}
+		}()
+	}
+	return nil
+}
+
+// Content of the open row, or from disk.
+func (ed *Editor) lsprotoFileContent(filename string) ([]byte, error) {
+	var b []byte
+	var err error
+	ed.UI.WaitRunOnUIGoRoutine(func() {
+		b, err = ed.lsprotoFileContent2(filename)
+	})
+	return b, err
+}
+
+// UI goroutine.
+func (ed *Editor) lsprotoFileContent2(filename string) ([]byte, error) {
+	if info, ok := ed.ERowInfo(filename); ok && len(info.ERows) > 0 {
+		return iorw.ReadFullCopy(info.ERows[0].Row.TextArea.RW())
+	}
+	return os.ReadFile(filename)
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+// Debounced lsproto request of a file row (ex: inlay hints). Features keep one per row and supply the request and apply callbacks. The state is handled in the UI goroutine.
+type lsprotoRowReq struct {
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	version int // incremented on each write, results of older versions are dropped
+}
+
+// Runs outside the UI goroutine with a copy of the row content. Returns the func that applies the results in the UI goroutine, or nil.
+type lsprotoRowReqFn func(ctx context.Context, rd iorw.ReaderAt) func()
+
+// Requests after the delay, replacing a pending request. Prepare runs in the UI goroutine (ex: reads the cursor) and returns the request, or nil to skip. Open reports if the row still has the feature (not closed).
+func (r *lsprotoRowReq) schedule(ed *Editor, erow *ERow, delay, timeout time.Duration, open func() bool, prepare func() lsprotoRowReqFn) {
+	r.stop()
+	r.timer = time.AfterFunc(delay, func() {
+		ed.UI.RunOnUIGoRoutine(func() {
+			if !open() {
+				return
+			}
+			r.timer = nil
+			if fn := prepare(); fn != nil {
+				r.run(ed, erow, timeout, open, fn)
+			}
+		})
+	})
+}
+
+func (r *lsprotoRowReq) run(ed *Editor, erow *ERow, timeout time.Duration, open func() bool, fn lsprotoRowReqFn) {
+	rd, err := erow.ContentCopy()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(erow.ctx, timeout)
+	r.cancel = cancel
+	version := r.version
+	go func() {
+		defer cancel()
+		apply := fn(ctx, rd)
+		if apply == nil {
+			return
+		}
+		ed.UI.RunOnUIGoRoutine(func() {
+			if !open() || r.version != version {
+				return // closed, or edited while waiting
+			}
+			apply()
+		})
+	}()
+}
+
+func (r *lsprotoRowReq) stop() {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
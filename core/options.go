@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/friedelschoen/editor/core/dap"
 	"github.com/friedelschoen/editor/core/lsproto"
 	"github.com/friedelschoen/editor/util/parseutil"
 )
@@ -30,6 +31,7 @@ type Options struct {
 	Plugins []string `json:"plugins"`
 
 	LSProtos     []lsproto.Registration
+	DAPs         []dap.Registration
 	PreSaveHooks []PreSaveHook
 
 	ZipSessionsFile bool
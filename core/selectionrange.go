@@ -0,0 +1,306 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+	"github.com/friedelschoen/editor/util/uiutil/event"
+)
+
+// Expands/shrinks the selection by syntax. Uses lsproto selection ranges, with balanced brackets/quotes as fallback. Keeps a stack of the previous selections to retrace the steps when shrinking.
+// All state is handled in the UI goroutine.
+type SelectionRange struct {
+	ed   *Editor
+	rows map[*ERow]*selRangeRow
+}
+
+func NewSelectionRange(ed *Editor) *SelectionRange {
+	sr := &SelectionRange{ed: ed}
+	sr.rows = map[*ERow]*selRangeRow{}
+	return sr
+}
+
+//----------
+
+// Alt+shift+right expands, alt+shift+left shrinks.
+func (sr *SelectionRange) OnInput(erow *ERow, ev *ui.TextAreaInputEvent) event.Handled {
+	kd, ok := ev.Event.(*event.KeyDown)
+	if !ok {
+		return false
+	}
+	m := kd.Mods.ClearLocks()
+	if m.Is(event.ModAlt | event.ModShift) {
+		switch kd.KeySym {
+		case event.KSymRight:
+			sr.Expand(erow)
+			return true
+		case event.KSymLeft:
+			sr.Shrink(erow)
+			return true
+		}
+	}
+	return false
+}
+
+// The stack is no longer valid after an edit.
+func (sr *SelectionRange) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	if ev.Changed {
+		sr.Close(erow)
+	}
+}
+
+// Called on row close.
+func (sr *SelectionRange) Close(erow *ERow) {
+	r, ok := sr.rows[erow]
+	if !ok {
+		return
+	}
+	r.stop()
+	delete(sr.rows, erow)
+}
+
+//----------
+
+func (sr *SelectionRange) Expand(erow *ERow) {
+	ta := erow.Row.TextArea
+	cur := selRangeOf(ta)
+
+	r := sr.row(erow, cur)
+	r.stop()
+
+	rd, err := erow.ContentCopy()
+	if err != nil {
+		sr.ed.Error(err)
+		return
+	}
+
+	if !erow.Info.IsFileButNotDir() {
+		sr.expand2(erow, r, cur, expandSelectionBalanced(rd, cur))
+		return
+	}
+	if _, err := sr.ed.LSProtoMan.LangManager(erow.Info.Name()); err != nil {
+		sr.expand2(erow, r, cur, expandSelectionBalanced(rd, cur))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(erow.ctx, 5*time.Second)
+	r.cancel = cancel
+	filename := erow.Info.Name()
+	go func() {
+		defer cancel()
+		ranges, err := sr.ed.LSProtoMan.TextDocumentSelectionRange(ctx, filename, rd, cur.s)
+		next := selRange{}
+		found := false
+		if err == nil {
+			for _, u := range ranges {
+				next = selRange{u[0], u[0] + u[1]}
+				if next.encloses(cur) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			next = expandSelectionBalanced(rd, cur)
+		}
+		sr.ed.UI.RunOnUIGoRoutine(func() {
+			if ctx.Err() != nil || sr.rows[erow] != r {
+				return // canceled, or edited while waiting
+			}
+			sr.expand2(erow, r, cur, next)
+		})
+	}()
+}
+
+func (sr *SelectionRange) expand2(erow *ERow, r *selRangeRow, cur, next selRange) {
+	if !next.encloses(cur) {
+		return // no enclosing range
+	}
+	r.stack = append(r.stack, cur)
+	r.cur = next
+	sr.setSelection(erow.Row.TextArea, next)
+}
+
+func (sr *SelectionRange) Shrink(erow *ERow) {
+	ta := erow.Row.TextArea
+	r, ok := sr.rows[erow]
+	if !ok || r.cur != selRangeOf(ta) || len(r.stack) == 0 {
+		return
+	}
+	r.stop()
+	k := len(r.stack) - 1
+	r.cur = r.stack[k]
+	r.stack = r.stack[:k]
+	sr.setSelection(ta, r.cur)
+}
+
+//----------
+
+// Returns the row state, resetting the stack if the selection was changed elsewhere.
+func (sr *SelectionRange) row(erow *ERow, cur selRange) *selRangeRow {
+	r, ok := sr.rows[erow]
+	if !ok {
+		r = &selRangeRow{}
+		sr.rows[erow] = r
+	}
+	if r.cur != cur {
+		r.stack = nil
+		r.cur = cur
+	}
+	return r
+}
+
+func (sr *SelectionRange) setSelection(ta *ui.TextArea, u selRange) {
+	c := ta.Cursor()
+	if u.s == u.e {
+		c.SetIndexSelectionOff(u.s)
+	} else {
+		c.SetSelection(u.s, u.e)
+	}
+	ta.MakeCursorVisible()
+}
+
+//----------
+
+type selRangeRow struct {
+	stack  []selRange // previous selections
+	cur    selRange   // selection set by the last step
+	cancel context.CancelFunc
+}
+
+func (r *selRangeRow) stop() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+//----------
+
+type selRange struct {
+	s, e int // [s,e)
+}
+
+func selRangeOf(ta *ui.TextArea) selRange {
+	c := ta.Cursor()
+	if s, e, ok := c.SelectionIndexes(); ok {
+		return selRange{s, e}
+	}
+	ci := c.Index()
+	return selRange{ci, ci}
+}
+
+// Encloses and is bigger.
+func (u selRange) encloses(u2 selRange) bool {
+	return u.s <= u2.s && u2.e <= u.e && u.e-u.s > u2.e-u2.s
+}
+
+func (u selRange) smaller(u2 selRange) bool {
+	return u.e-u.s < u2.e-u2.s
+}
+
+//----------
+
+// Smallest range enclosing the selection: a word, the content inside brackets/quotes, or including the brackets/quotes. Returns the selection if there is none.
+func expandSelectionBalanced(rd iorw.ReaderAt, cur selRange) selRange {
+	best := cur
+	consider := func(u selRange) {
+		if u.encloses(cur) && (best == cur || u.smaller(best)) {
+			best = u
+		}
+	}
+
+	// word
+	if cur.s == cur.e {
+		if w, i, err := iorw.WordAtIndex(rd, cur.s); err == nil {
+			consider(selRange{i, i + len(w)})
+		}
+	}
+
+	// quotes on the same line
+	if s, e, ok := enclosingQuotes(rd, cur); ok {
+		consider(selRange{s + 1, e - 1})
+		consider(selRange{s, e})
+	}
+
+	// brackets
+	if s, e, ok := enclosingBrackets(rd, cur); ok {
+		consider(selRange{s + 1, e - 1})
+		consider(selRange{s, e})
+	}
+
+	return best
+}
+
+// Returns the range [s,e) of the closest brackets enclosing the selection (including the brackets).
+func enclosingBrackets(rd iorw.ReaderAt, cur selRange) (int, int, bool) {
+	depth := 0
+	limit := max(rd.Min(), cur.s-balancedPad)
+	for i := cur.s; i > limit; {
+		ru, size, err := iorw.ReadLastRuneAt(rd, i)
+		if err != nil {
+			return 0, 0, false
+		}
+		i -= size
+		switch ru {
+		case ')', ']', '}':
+			depth++
+		case '(', '[', '{':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			o, c, ok := drawutil.ParenthesisMatch(rd, i, balancedPad)
+			if !ok || o != i {
+				continue // unbalanced
+			}
+			if c+1 >= cur.e {
+				return o, c + 1, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Returns the range [s,e) of the quoted string enclosing the selection (including the quotes). Only considers the quotes in the line of the selection start.
+func enclosingQuotes(rd iorw.ReaderAt, cur selRange) (int, int, bool) {
+	ls, err := iorw.LineStartIndex(rd, cur.s)
+	if err != nil {
+		return 0, 0, false
+	}
+	le, _, err := iorw.LineEndIndex(rd, cur.s)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, 0, false
+	}
+	b, err := rd.ReadFastAt(ls, le-ls)
+	if err != nil {
+		return 0, 0, false
+	}
+	quote := byte(0)
+	start := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case quote == 0:
+			if c == '"' || c == '\'' || c == '`' {
+				quote, start = c, i
+			}
+		case c == '\\':
+			i++ // escaped
+		case c == quote:
+			u := selRange{ls + start, ls + i + 1}
+			if u.s <= cur.s && cur.e <= u.e {
+				return u.s, u.e, true
+			}
+			quote = 0
+		}
+	}
+	return 0, 0, false
+}
+
+const balancedPad = 100000
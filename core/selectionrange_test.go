@@ -0,0 +1,23 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+func TestExpandSelectionBalanced(t *testing.T) {
+	s := "f(a, g(\"b c\", [d]))"
+	rd := iorw.NewBytesReadWriterAt([]byte(s))
+	cur := selRange{10, 10} // at "c"
+	w := []string{"c", "b c", "\"b c\"", "\"b c\", [d]", "(\"b c\", [d])", "a, g(\"b c\", [d])", "(a, g(\"b c\", [d]))"}
+	for _, exp := range w {
+		cur = expandSelectionBalanced(rd, cur)
+		if got := s[cur.s:cur.e]; got != exp {
+			t.Fatalf("got %q, expected %q", got, exp)
+		}
+	}
+	if u := expandSelectionBalanced(rd, cur); u != cur {
+		t.Fatal(u)
+	}
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/friedelschoen/editor/core/lsproto"
+	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/drawutil"
+	"github.com/friedelschoen/editor/util/iout/iorw"
+)
+
+// Shows the signature of the function call at the cursor (lsproto) as an annotation, with the active parameter selected.
+type SignatureHelp struct {
+	ed *Editor
+
+	mu struct {
+		sync.Mutex
+		cancel context.CancelFunc
+		ta     *ui.TextArea // if not nil, signaturehelp is on
+	}
+}
+
+func NewSignatureHelp(ed *Editor) *SignatureHelp {
+	sh := &SignatureHelp{ed: ed}
+	sh.mu.cancel = func() {} // avoid nil call
+	return sh
+}
+
+//----------
+
+// Triggers on writes of "(" or ","; if already on, any write updates it.
+func (sh *SignatureHelp) OnWrite(erow *ERow, ev *iorw.RWEvWrite2) {
+	ta := erow.Row.TextArea
+	if !sh.IsOn(ta) {
+		if ev.In == 0 {
+			return
+		}
+		b, err := ta.RW().ReadFastAt(ev.Index+ev.In-1, 1)
+		if err != nil || len(b) == 0 {
+			return
+		}
+		if b[0] != '(' && b[0] != ',' {
+			return
+		}
+	}
+	sh.update(erow)
+}
+
+// Updates on cursor changes (keys/buttons) if on.
+func (sh *SignatureHelp) OnMouseKeyDown(erow *ERow) {
+	if sh.IsOn(erow.Row.TextArea) {
+		sh.update(erow)
+	}
+}
+
+//----------
+
+func (sh *SignatureHelp) update(erow *ERow) {
+	if !erow.Info.IsFileButNotDir() {
+		return
+	}
+	if _, err := sh.ed.LSProtoMan.LangManager(erow.Info.Name()); err != nil {
+		return
+	}
+
+	// run after the current event is handled (cursor is updated)
+	sh.ed.UI.RunOnUIGoRoutine(func() {
+		ta := erow.Row.TextArea
+
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+
+		sh.mu.cancel() // cancel previous run
+
+		// clear annotations at other textarea
+		if sh.mu.ta != nil && sh.mu.ta != ta {
+			// run async to avoid lockup
+			go sh.setAnnotations(sh.mu.ta, nil, -1)
+		}
+
+		ctx, cancel := context.WithCancel(erow.ctx)
+		sh.mu.cancel = cancel
+		sh.mu.ta = ta
+
+		go sh.update2(ctx, erow, ta, ta.CursorIndex())
+	})
+}
+func (sh *SignatureHelp) update2(ctx context.Context, erow *ERow, ta *ui.TextArea, offset int) {
+	defer sh.ed.UI.EnqueueNoOpEvent()
+
+	filename := erow.Info.Name()
+	shelp, err := sh.ed.LSProtoMan.TextDocumentSignatureHelp(ctx, filename, ta.RW(), offset)
+	if err != nil {
+		if ctx.Err() == nil {
+			sh.setAnnotations(ta, nil, -1)
+			sh.ed.Error(err)
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		return // canceled while waiting (newer update)
+	}
+
+	label, s, e, ok := lsproto.SignatureHelpActive(shelp)
+	if !ok {
+		sh.setAnnotations(ta, nil, -1) // not inside a call
+		return
+	}
+
+	// split label to have the active parameter as the selected entry
+	parts := []string{label}
+	selIndex := -1
+	if s < e {
+		// annotations on the same line are space separated
+		parts = []string{strings.TrimRight(label[:s], " "), label[s:e], label[e:]}
+		selIndex = 1
+	}
+	entries := drawutil.NewAnnotationGroup(len(parts))
+	for i, p := range parts {
+		entries.Anns[i].Offset = offset
+		entries.Anns[i].Bytes = []byte(p)
+	}
+	sh.setAnnotations(ta, entries, selIndex)
+}
+
+//----------
+
+func (sh *SignatureHelp) setAnnotations(ta *ui.TextArea, entries *drawutil.AnnotationGroup, selIndex int) {
+	if !entries.On() {
+		sh.setOff(ta)
+	}
+	sh.ed.SetAnnotations(AnnotatorSignatureHelp, ta, selIndex, entries)
+}
+
+//----------
+
+func (sh *SignatureHelp) IsOn(ta *ui.TextArea) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.mu.ta != nil && sh.mu.ta == ta
+}
+
+func (sh *SignatureHelp) setOff(ta *ui.TextArea) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.mu.ta == ta {
+		sh.mu.ta = nil
+		sh.mu.cancel()
+	}
+}
+
+//----------
+
+func (sh *SignatureHelp) CancelAndClear() {
+	sh.mu.Lock()
+	ta := sh.mu.ta
+	sh.mu.Unlock()
+	if ta != nil {
+		sh.setAnnotations(ta, nil, -1)
+	}
+}
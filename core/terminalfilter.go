@@ -3,6 +3,9 @@ package core
 import (
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"sync"
 	"unicode/utf8"
 )
 
@@ -10,6 +13,12 @@ type TerminalFilter struct {
 	tio  TerminalIO
 	erow *ERow // can be nil in tests
 
+	mu     sync.Mutex // writes from the process, input echo and resizes from the UI
+	screen *tfScreen
+	crlf   bool // linefeed also returns the carriage (no tty to translate "\n")
+
+	onResize func(cols, rows int) error // ex: pty size
+
 	// maintains state through write calls
 	p struct { // parser
 		src      []byte
@@ -17,6 +26,7 @@ type TerminalFilter struct {
 		stateFn  func() error
 
 		csi tfCsi
+		osc []rune
 	}
 }
 
@@ -28,7 +38,15 @@ func NewTerminalFilter(erow *ERow) *TerminalFilter {
 }
 
 func NewTerminalFilter2(tio TerminalIO, erow *ERow) *TerminalFilter {
-	tf := &TerminalFilter{tio: tio, erow: erow}
+	tf := &TerminalFilter{tio: tio, erow: erow, crlf: true}
+	if erow != nil && erow.terminalOpt.pty {
+		tf.crlf = false
+	}
+	cols, rows := 80, 24
+	if erow != nil {
+		cols, rows = erow.terminalSize()
+	}
+	tf.screen = newTfScreen(cols, rows)
 	tf.p.stateFn = tf.stParseDefault
 	tf.tio.Init(tf)
 	return tf
@@ -37,8 +55,13 @@ func NewTerminalFilter2(tio TerminalIO, erow *ERow) *TerminalFilter {
 //----------
 
 func (tf *TerminalFilter) Write(p []byte) (int, error) {
-	if tf.erow != nil && tf.erow.terminalOpt.filter {
+	if tf.filterOn() {
+		tf.mu.Lock()
+		defer tf.mu.Unlock()
 		tf.filter(p)
+		if err := tf.flush(); err != nil {
+			return 0, err
+		}
 	} else {
 		if err := tf.tio.WriteOp(p); err != nil {
 			return 0, err
@@ -55,6 +78,62 @@ func (tf *TerminalFilter) Close() error {
 	return tf.tio.Close()
 }
 
+func (tf *TerminalFilter) filterOn() bool {
+	return tf.erow == nil || tf.erow.terminalOpt.filter
+}
+
+// Safe to use concurrently.
+func (tf *TerminalFilter) Resize(cols, rows int) error {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	tf.screen.resize(cols, rows)
+	if tf.onResize != nil {
+		if err := tf.onResize(cols, rows); err != nil {
+			return err
+		}
+	}
+	if !tf.filterOn() {
+		return nil
+	}
+	return tf.flush()
+}
+
+// Called on resize with the new size. Safe to use concurrently.
+func (tf *TerminalFilter) SetOnResize(fn func(cols, rows int) error) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	tf.onResize = fn
+}
+
+// Safe to use concurrently.
+func (tf *TerminalFilter) AppCursorKeys() bool {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	return tf.screen.appCursorKeys
+}
+
+// Sends the screen changes to the output.
+func (tf *TerminalFilter) flush() error {
+	s := tf.screen
+	if !s.dirty && len(s.hist) == 0 && !s.clearHist {
+		return nil
+	}
+	b, styles, cursor := s.render()
+	op := &tfScreenOp{
+		ClearHist:  s.clearHist,
+		Hist:       s.hist,
+		HistStyles: s.histStyles,
+		Screen:     b,
+		Styles:     styles,
+		Cursor:     cursor,
+	}
+	s.hist = nil
+	s.histStyles = nil
+	s.clearHist = false
+	s.dirty = false
+	return tf.tio.WriteOp(op)
+}
+
 //----------
 
 func (tf *TerminalFilter) filter(p []byte) {
@@ -119,6 +198,7 @@ func (tf *TerminalFilter) stParseDefault() error {
 	if err != nil {
 		return err
 	}
+	sc := tf.screen
 	switch ru {
 	case 0x1b: // ESC: (1b,27)
 		tf.p.stateFn = tf.stParseEsc
@@ -129,14 +209,19 @@ func (tf *TerminalFilter) stParseDefault() error {
 		tf.advance()
 	case '\b': // backspace: (8,8), '\b'
 		tf.advance()
+		sc.backspace()
 	case 0x9: // next tab stop or end of the line
-	case '\n': // newline: (a,10), '\n'
 		tf.advance()
-		return tf.tio.WriteOp([]byte{'\n'})
-	case 0xb, 0xc: // formfeed, verticaltab
+		sc.tab()
+	case '\n', 0xb, 0xc: // newline: (a,10), '\n'; formfeed, verticaltab
 		tf.advance()
+		if tf.crlf {
+			sc.carriageReturn()
+		}
+		sc.index()
 	case '\r': // carriage return: (d,13), '\r'
 		tf.advance()
+		sc.carriageReturn()
 	case 0xe: // activate G1 char set: (e,14)
 		tf.advance()
 	case 0xf: // activate G0 char set: (f,15)
@@ -146,9 +231,12 @@ func (tf *TerminalFilter) stParseDefault() error {
 	case 0x7f: // DEL, ignored
 		tf.advance()
 	default:
+		tf.advance()
+		if ru < 0x20 || ru == utf8.RuneError {
+			return nil // other control bytes
+		}
 		// not a control byte, add to output
-		defer tf.advance()
-		return tf.tio.WriteOp(tf.value())
+		sc.put(ru)
 	}
 	return nil
 }
@@ -160,42 +248,47 @@ func (tf *TerminalFilter) stParseEsc() error {
 	if err != nil {
 		return err
 	}
+	sc := tf.screen
 	switch ru {
 	case '[': // CSI
 		tf.p.csi = tfCsi{} // reset data
 		tf.p.stateFn = tf.stParseCsi
+		return nil
 	case ']': // Operating system command
+		tf.p.osc = nil
 		tf.p.stateFn = tf.stParseOsc
+		return nil
 	case '(': // start sequence defining G0 char set
 		tf.p.stateFn = tf.stParseG0
+		return nil
 	case ')': // start sequence defining G1 char set
 		tf.p.stateFn = tf.stParseG1
+		return nil
 	case '#': // screen alignment test - fill screen with E's.
 		tf.p.stateFn = tf.stParseAlignmentTest
+		return nil
 
 	case 'D': // linefeed
-		tf.p.stateFn = tf.stParseDefault
-		defer tf.advance()
-		return tf.tio.WriteOp([]byte{'\n'})
+		sc.index()
 	case 'E': // newline
-		tf.p.stateFn = tf.stParseDefault
-		defer tf.advance()
-		return tf.tio.WriteOp([]byte{'\n'})
+		sc.carriageReturn()
+		sc.index()
 	case 'H': // Set tab stop at current column
 	case 'M': // reverse line feed
-		tf.advance()
-		tf.p.stateFn = tf.stParseDefault
+		sc.reverseIndex()
 	case '7': // Save cursor
-		tf.advance()
-		tf.p.stateFn = tf.stParseDefault
+		sc.saveCursor()
 	case '8': // Restore cursor location
-		tf.advance()
-		tf.p.stateFn = tf.stParseDefault
+		sc.restoreCursor()
+	case 'c': // reset
+		sc.reset()
+	case '=', '>': // application/normal keypad
+	case '\\': // string terminator (ex: end of osc)
 	default:
 		tf.todo("stParseEsc: %s", tf.value())
-		tf.advance()
-		tf.p.stateFn = tf.stParseDefault
 	}
+	tf.advance()
+	tf.p.stateFn = tf.stParseDefault
 	return nil
 }
 
@@ -219,6 +312,9 @@ func (tf *TerminalFilter) stParseCsi() error {
 		tf.p.stateFn = tf.stParseDefault
 
 	case ru >= 0x30 && ru < 0x3f: // param bytes: 0–9:;<=>
+		if len(tf.p.csi.param) == 0 && ru >= 0x3c {
+			tf.p.csi.prefix = ru
+		}
 		tf.p.csi.param = append(tf.p.csi.param, ru)
 	case ru >= 0x20 && ru <= 0x2f: // intermediary bytes: space !"#$%&'()*+,-./
 		tf.p.csi.intermid = append(tf.p.csi.intermid, ru)
@@ -238,38 +334,136 @@ func (tf *TerminalFilter) stParseCsi() error {
 //----------
 
 func (tf *TerminalFilter) interpretCSI() error {
-	switch tf.p.csi.final {
+	csi := &tf.p.csi
+	if len(csi.intermid) > 0 {
+		return nil // ex: cursor style "CSI n SP q"
+	}
+	if csi.qMark {
+		return tf.interpretCSIPrivate()
+	}
+	sc := tf.screen
+	b := sc.buf
+	n := csi.arg(0, 1)
+	switch csi.final {
+	case '@': // insert blank chars
+		sc.insertChars(n)
+	case 'A': // cursor up
+		sc.cursorMove(-n, 0)
+	case 'B', 'e': // cursor down
+		sc.cursorMove(n, 0)
+	case 'C', 'a': // cursor forward
+		sc.cursorMove(0, n)
+	case 'D': // cursor back
+		sc.cursorMove(0, -n)
+	case 'E': // cursor next line
+		sc.cursorMove(n, -b.x)
+	case 'F': // cursor previous line
+		sc.cursorMove(-n, -b.x)
+	case 'G', '`': // cursor column
+		sc.cursorTo(b.y, n-1)
+	case 'd': // cursor line
+		sc.cursorTo(n-1, b.x)
+	case 'H', 'f': // cursor position
+		sc.cursorTo(n-1, csi.arg(1, 1)-1)
 	case 'J': // erase display
-		return tf.eraseDisplay()
+		sc.eraseDisplay(csi.arg(0, 0))
+	case 'K': // erase line
+		sc.eraseLine(csi.arg(0, 0))
+	case 'L': // insert lines
+		sc.insertLines(n)
+	case 'M': // delete lines
+		sc.deleteLines(n)
+	case 'P': // delete chars
+		sc.deleteChars(n)
+	case 'X': // erase chars
+		sc.eraseChars(n)
+	case 'S': // scroll up
+		sc.scrollUp(n)
+	case 'T': // scroll down
+		sc.scrollDown(n)
+	case 'r': // scroll region
+		sc.setScrollRegion(csi.arg(0, 1)-1, csi.arg(1, sc.rows)-1)
+	case 's': // save cursor
+		sc.saveCursor()
+	case 'u': // restore cursor
+		sc.restoreCursor()
+	case 'n': // device status report
+		switch csi.arg(0, 0) {
+		case 5: // status
+			tf.tio.AddToRead([]byte("\x1b[0n"))
+		case 6: // cursor position
+			s := fmt.Sprintf("\x1b[%d;%dR", b.y+1, b.x+1)
+			tf.tio.AddToRead([]byte(s))
+		}
+	case 'c': // device attributes
+		if csi.prefix == 0 {
+			tf.tio.AddToRead([]byte("\x1b[?1;2c")) // vt100 with advanced video option
+		}
+	case 'm': // graphic rendition (colors)
+		if csi.prefix == 0 {
+			sc.attr.setSGR(string(csi.param))
+		}
+	case 'h', 'l': // set/reset mode (ex: insert mode)
+	case 't': // window manipulation
+	default:
+		tf.todo("interpretCSI: %s", tf.value())
 	}
 	return nil
 }
 
-func (tf *TerminalFilter) eraseDisplay() error {
-	switch string(tf.p.csi.param) {
-	case "1": // erase from start to cursor
-	case "2": // erase whole display
-		return tf.tio.WriteOp("clear")
-	case "3": // erase whole display including scroll-back buffer
-		return tf.tio.WriteOp("clear")
-	default: // from cursor to end of display
+// Private modes: "CSI ? n h" (set), "CSI ? n l" (reset).
+func (tf *TerminalFilter) interpretCSIPrivate() error {
+	csi := &tf.p.csi
+	if csi.final != 'h' && csi.final != 'l' {
+		return nil
+	}
+	sc := tf.screen
+	on := csi.final == 'h'
+	for i := range len(csi.args()) {
+		switch csi.arg(i, 0) {
+		case 1: // application cursor keys
+			sc.appCursorKeys = on
+		case 7: // auto wrap
+			sc.autoWrap = on
+		case 25: // show cursor
+		case 47, 1047: // alternate screen
+			sc.setAltScreen(on)
+		case 1049: // alternate screen, saving the cursor
+			if on {
+				sc.saveCursor()
+				sc.setAltScreen(true)
+			} else {
+				sc.setAltScreen(false)
+				sc.restoreCursor()
+			}
+		}
 	}
 	return nil
 }
 
 //----------
 
+// Ends with BEL or ST ("ESC \\"), ex: "ESC ] 0 ; title BEL" sets the window title.
 func (tf *TerminalFilter) stParseOsc() error {
 	ru, err := tf.nextRune()
 	if err != nil {
 		return err
 	}
-	switch ru {
-	case 'P': // set palette // TODO: parse nrrggbb
-	case 'R': // reset palette
+	if ru == 0x7 {
+		tf.advance()
+		tf.p.stateFn = tf.stParseDefault
+		return nil
+	}
+	tf.p.osc = append(tf.p.osc, ru)
+	// linux console palette sequences have no terminator
+	switch {
+	case len(tf.p.osc) == 1 && ru == 'R': // reset palette
+	case len(tf.p.osc) == 8 && tf.p.osc[0] == 'P': // set palette: nrrggbb
 	default:
-		tf.todo("stParseOsc: %s", tf.value())
+		return nil
 	}
+	tf.advance()
+	tf.p.stateFn = tf.stParseDefault
 	return nil
 }
 
@@ -284,10 +478,10 @@ func (tf *TerminalFilter) stParseG(g int) error {
 		return err
 	}
 	switch ru {
-	//case 'B': // Select default (ISO 8859-1 mapping)
+	case 'B': // Select default (ISO 8859-1 mapping), ex: sgr0 in xterm terminfo
 	case '0': // Select VT100 graphics mapping
-	//case 'U': // Select null mapping - straight to character ROM
-	//case 'K': // Select user mapping - the map that is loaded by the utility mapscrn(8).
+	case 'U': // Select null mapping - straight to character ROM
+	case 'K': // Select user mapping - the map that is loaded by the utility mapscrn(8).
 	default:
 		tf.todo("stParseG: %s, g=%v", tf.value(), g)
 	}
@@ -319,7 +513,13 @@ func (tf *TerminalFilter) todo(f string, a ...any) {
 	tf.debug("todo: "+f, a...)
 }
 
+// Unsupported sequences are ignored; set to report them while developing.
+const tfDebug = false
+
 func (tf *TerminalFilter) debug(f string, a ...any) {
+	if !tfDebug {
+		return
+	}
 	f = "tfdebug: " + f
 	if tf.erow != nil {
 		tf.erow.Ed.Messagef(f, a...)
@@ -335,6 +535,37 @@ type tfCsi struct {
 	intermid []rune
 	final    rune
 	qMark    bool
+	prefix   rune // '>', '<' or '=' before the params
+}
+
+func (csi *tfCsi) args() []string {
+	u := strings.TrimLeft(string(csi.param), "<=>")
+	return strings.Split(u, ";")
+}
+
+// Missing or zero args have the default value.
+func (csi *tfCsi) arg(i int, def int) int {
+	args := csi.args()
+	if i >= len(args) {
+		return def
+	}
+	v, err := strconv.Atoi(args[i])
+	if err != nil || v == 0 {
+		return def
+	}
+	return v
+}
+
+//----------
+
+// Replaces the screen lines in the output, after appending the history.
+type tfScreenOp struct {
+	ClearHist  bool // history in the output is removed first
+	Hist       []byte
+	HistStyles []tfStyle // offsets in hist
+	Screen     []byte
+	Styles     []tfStyle // offsets in screen
+	Cursor     int       // offset in screen
 }
 
 //----------
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTerminalFilter1(t *testing.T) {
+	type tcase struct {
+		in, out string
+	}
+	cases := []tcase{
+		{"a\nb\n", "a\nb\n"},
+		// history: lines scrolled off the top are kept
+		{"1\n2\n3\n4\n5", "1\n2\n3\n4\n5"},
+		// cursor back and erase line
+		{"abcd\x1b[2D\x1b[K", "ab"},
+		// carriage return overwrites
+		{"12345\rab", "ab345"},
+		// cursor position and erase display
+		{"aaa\nbbb\nccc\x1b[2;2H\x1b[J", "aaa\nb"},
+		// insert and delete chars
+		{"abc\x1b[1G\x1b[2@X\x1b[P", "Xabc"},
+		// alternate screen restores the main screen and cursor
+		{"main\x1b[?1049h\x1b[Halt\x1b[?1049l!", "main!"},
+		// scroll region: delete line inside the region
+		{"1\n2\n3\x1b[1;2r\x1b[1;1H\x1b[M", "2\n\n3"},
+		// reverse index at the top scrolls down
+		{"1\n2\x1b[H\x1bMx", "x\n1\n2"},
+		// osc (window title) is ignored
+		{"\x1b]0;title\x07ok", "ok"},
+		// clear screen and history
+		{"1\n2\n3\n4\n5\x1b[H\x1b[2J\x1b[3Jz", "z"},
+	}
+	for i, c := range cases {
+		tio := &testTermIO{}
+		tf := NewTerminalFilter2(tio, nil)
+		tf.Resize(10, 3)
+		if _, err := tf.Write([]byte(c.in)); err != nil {
+			t.Fatal(err)
+		}
+		if tio.out != c.out {
+			t.Fatalf("case %v: %q", i, tio.out)
+		}
+	}
+}
+
+func TestTerminalFilter2(t *testing.T) {
+	// sequences split across writes, and device status report
+	tio := &testTermIO{}
+	tf := NewTerminalFilter2(tio, nil)
+	for _, s := range []string{"ab\x1b", "[1", ";2H", "X\x1b[6n"} {
+		if _, err := tf.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tio.out != "aX" || string(tio.read) != "\x1b[1;3R" {
+		t.Fatalf("%q %q", tio.out, tio.read)
+	}
+}
+
+func TestTerminalFilter3(t *testing.T) {
+	// graphic rendition
+	tio := &testTermIO{}
+	tf := NewTerminalFilter2(tio, nil)
+	in := "a\x1b[1;31mb\x1b[0mc\x1b[38;5;21;4md"
+	if _, err := tf.Write([]byte(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := []tfStyle{
+		{1, tfAttr{fg: tfColor256(1), bold: true}},
+		{2, tfAttr{}},
+		{3, tfAttr{fg: tfColor256(21), underline: true}},
+		{4, tfAttr{}},
+	}
+	if tio.out != "abcd" || fmt.Sprint(tio.styles) != fmt.Sprint(want) {
+		t.Fatalf("%q %v", tio.out, tio.styles)
+	}
+}
+
+func TestTerminalFilter4(t *testing.T) {
+	// styles of several history lines in one flush
+	tio := &testTermIO{}
+	tf := NewTerminalFilter2(tio, nil)
+	tf.Resize(10, 2)
+	in := "\x1b[31mab\x1b[0m\nc\x1b[1md\x1b[0m\n\x1b[4me\x1b[0m\nf"
+	if _, err := tf.Write([]byte(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := []tfStyle{
+		{0, tfAttr{fg: tfColor256(1)}},
+		{2, tfAttr{}},
+		{4, tfAttr{bold: true}},
+		{5, tfAttr{}},
+	}
+	if tio.out != "ab\ncd\ne\nf" || fmt.Sprint(tio.histStyles) != fmt.Sprint(want) {
+		t.Fatalf("%q %v", tio.out, tio.histStyles)
+	}
+}
+
+func TestTerminalFilter5(t *testing.T) {
+	// charset selection (sgr0 in xterm terminfo)
+	tio := &testTermIO{}
+	tf := NewTerminalFilter2(tio, nil)
+	if _, err := tf.Write([]byte("a\x1b(B\x1b[mb\x1b)0c")); err != nil {
+		t.Fatal(err)
+	}
+	if tio.out != "abc" {
+		t.Fatalf("%q", tio.out)
+	}
+}
+
+func TestSGRStripper1(t *testing.T) {
+	// plain output: sequences split across writes
+	s := &tfSGRStripper{}
+	out, styles := "", []tfStyle{}
+	for _, in := range []string{"a\x1b[1;3", "1mb\x1b[m\x1b[Kc\x1b", "(Bd\x1b]8;;x\x07e"} {
+		b, sts := s.strip([]byte(in))
+		for _, st := range sts {
+			st.Offset += len(out)
+			styles = append(styles, st)
+		}
+		out += string(b)
+	}
+	want := []tfStyle{
+		{1, tfAttr{fg: tfColor256(1), bold: true}},
+		{2, tfAttr{}},
+	}
+	if out != "abcde" || fmt.Sprint(styles) != fmt.Sprint(want) {
+		t.Fatalf("%q %v", out, styles)
+	}
+}
+
+//----------
+
+type testTermIO struct {
+	out         string // history + screen
+	screenStart int
+	styles      []tfStyle // screen styles
+	histStyles  []tfStyle // offsets in out
+	read        []byte
+}
+
+func (tio *testTermIO) Init(tf *TerminalFilter)  {}
+func (tio *testTermIO) Read([]byte) (int, error) { return 0, nil }
+func (tio *testTermIO) AddToRead(b []byte)       { tio.read = append(tio.read, b...) }
+func (tio *testTermIO) Close() error             { return nil }
+func (tio *testTermIO) WriteOp(op any) error {
+	switch t := op.(type) {
+	case *tfScreenOp:
+		if t.ClearHist {
+			tio.out = tio.out[tio.screenStart:]
+			tio.screenStart = 0
+		}
+		if t.ClearHist {
+			tio.histStyles = nil
+		}
+		for _, st := range t.HistStyles {
+			st.Offset += tio.screenStart
+			tio.histStyles = append(tio.histStyles, st)
+		}
+		tio.out = tio.out[:tio.screenStart] + string(t.Hist) + string(t.Screen)
+		tio.screenStart += len(t.Hist)
+		tio.styles = t.Styles
+	case []byte:
+		tio.out += string(t)
+	}
+	return nil
+}
@@ -3,10 +3,13 @@ package core
 import (
 	"bytes"
 	"fmt"
+	"image"
 	"io"
+	"slices"
 	"sync"
 
 	"github.com/friedelschoen/editor/ui"
+	"github.com/friedelschoen/editor/util/drawutil"
 	"github.com/friedelschoen/editor/util/evreg"
 	"github.com/friedelschoen/editor/util/iout"
 	"github.com/friedelschoen/editor/util/uiutil/event"
@@ -31,7 +34,8 @@ type ERowTermIO struct {
 	erow *ERow
 	tf   *TerminalFilter
 
-	inputReg *evreg.Regist // input events
+	inputReg  *evreg.Regist // input events
+	layoutReg *evreg.Regist // resize
 
 	input struct {
 		sync.Mutex
@@ -44,10 +48,14 @@ type ERowTermIO struct {
 		updating bool
 		ops      []any
 	}
+	screenStart int                    // screen lines offset in the textarea (ui goroutine), -1 if not set
+	ops         []*drawutil.ColorizeOp // history styles followed by the screen styles (ui goroutine)
+	nHistOps    int                    // number of history styles in ops
+	size        [2]int                 // last size sent to the filter (ui goroutine)
 }
 
 func NewERowTermIO(erow *ERow) *ERowTermIO {
-	tio := &ERowTermIO{erow: erow}
+	tio := &ERowTermIO{erow: erow, screenStart: -1}
 	tio.input.cond = sync.NewCond(&tio.input)
 	return tio
 }
@@ -59,6 +67,7 @@ func (tio *ERowTermIO) Init(tf *TerminalFilter) {
 
 func (tio *ERowTermIO) Close() error {
 	tio.inputReg.Unregister()
+	tio.layoutReg.Unregister()
 
 	// signal to unblock waiting for a read
 	tio.input.Lock()
@@ -132,6 +141,8 @@ func (tio *ERowTermIO) updateWriteOp2(op any) error {
 		if err := erow.AppendBytesClearHistory2(t); err != nil {
 			return err
 		}
+	case *tfScreenOp:
+		return tio.updateScreen(t)
 	case string:
 		switch t {
 		case "clear":
@@ -147,9 +158,62 @@ func (tio *ERowTermIO) updateWriteOp2(op any) error {
 	return nil
 }
 
+func (tio *ERowTermIO) updateScreen(op *tfScreenOp) error {
+	erow := tio.tf.erow
+	ta := erow.Row.TextArea
+	rw := ta.RW()
+	if tio.screenStart < 0 || tio.screenStart > rw.Max() {
+		tio.screenStart = rw.Max()
+	}
+	if op.ClearHist {
+		if err := ta.DeleteBytesClearHistory(0, tio.screenStart); err != nil {
+			return err
+		}
+		tio.screenStart = 0
+		tio.ops, tio.nHistOps = nil, 0
+	}
+	b := append(op.Hist, op.Screen...)
+	if err := erow.OverwriteBytesClearHistory2(tio.screenStart, rw.Max()-tio.screenStart, b); err != nil {
+		return err
+	}
+	// replace the previous screen styles (same slice, no copy)
+	tio.ops = append(tio.ops[:tio.nHistOps], erow.terminalColorizeOps(tio.screenStart, op.HistStyles)...)
+	tio.ops = capColorizeOps(tio.ops)
+	tio.nHistOps = len(tio.ops)
+	tio.screenStart += len(op.Hist)
+	tio.ops = append(tio.ops, erow.terminalColorizeOps(tio.screenStart, op.Styles)...)
+	ta.SetTerminalColorize(tio.ops)
+	// show the terminal cursor while receiving keys
+	if erow.terminalOpt.keyEvents {
+		ta.SetCursorIndex(tio.screenStart + op.Cursor)
+	}
+	return nil
+}
+
 func (tio *ERowTermIO) appendOp(op any) {
 	o := &tio.update.ops
 	switch t := op.(type) {
+	case *tfScreenOp:
+		// only the last screen matters, but keep the history
+		l := len(*o)
+		if l > 0 {
+			last := &(*o)[l-1]
+			if lop, ok := (*last).(*tfScreenOp); ok {
+				t2 := *t
+				if !t.ClearHist {
+					t2.ClearHist = lop.ClearHist
+					t2.HistStyles = slices.Clone(lop.HistStyles)
+					for _, st := range t.HistStyles {
+						st.Offset += len(lop.Hist)
+						t2.HistStyles = append(t2.HistStyles, st)
+					}
+					t2.Hist = append(iout.CopyBytes(lop.Hist), t.Hist...)
+				}
+				*last = &t2
+				return
+			}
+		}
+		*o = append(*o, t)
 	case []byte:
 		// copy to avoid losing/overwriting content
 		b := iout.CopyBytes(t)
@@ -170,11 +234,60 @@ func (tio *ERowTermIO) appendOp(op any) {
 	}
 }
 
+// Converts the styles to textarea colorize ops.
+func (erow *ERow) terminalColorizeOps(offset int, styles []tfStyle) []*drawutil.ColorizeOp {
+	pcol := erow.Row.TextArea.TreeThemePaletteColor
+	ops := []*drawutil.ColorizeOp{}
+	for _, st := range styles {
+		a := st.Attr
+		op := &drawutil.ColorizeOp{
+			Offset:    offset + st.Offset,
+			Fg:        a.fg,
+			Bg:        a.bg,
+			Bold:      a.bold,
+			Underline: a.underline,
+		}
+		if a.reverse {
+			fg, bg := a.fg, a.bg
+			if fg == nil {
+				fg = pcol("text_fg")
+			}
+			if bg == nil {
+				bg = pcol("text_bg")
+			}
+			op.Fg, op.Bg = bg, fg
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Keeps the number of colorize ops bounded on long outputs by dropping the oldest (the older lines lose the colors).
+func capColorizeOps(ops []*drawutil.ColorizeOp) []*drawutil.ColorizeOp {
+	const max = 8192
+	if len(ops) <= max {
+		return ops
+	}
+	return slices.Clone(ops[len(ops)-max/2:])
+}
+
 //----------
 
 func (tio *ERowTermIO) initInput() {
 	ta := tio.erow.Row.TextArea
 	tio.inputReg = ta.EvReg.Add(ui.TextAreaInputEventId, tio.onTextAreaInputEvent)
+	tio.layoutReg = ta.EvReg.Add(ui.TextAreaLayoutEventId, tio.onTextAreaLayoutEvent)
+}
+
+func (tio *ERowTermIO) onTextAreaLayoutEvent(ev0 any) {
+	cols, rows := tio.erow.terminalSize()
+	if tio.size == [2]int{cols, rows} {
+		return
+	}
+	tio.size = [2]int{cols, rows}
+	if err := tio.tf.Resize(cols, rows); err != nil {
+		tio.erow.Ed.Error(err)
+	}
 }
 
 func (tio *ERowTermIO) onTextAreaInputEvent(ev0 any) {
@@ -193,13 +306,19 @@ func (tio *ERowTermIO) eventToBytes(ev any) ([]byte, event.Handled) {
 	keyboardEvs := func() bool {
 		return tio.erow.terminalOpt.keyEvents
 	}
+	pty := func() bool {
+		return tio.erow.terminalOpt.pty
+	}
 	byteOut := func(v byte, ru rune) []byte {
 		b := []byte{v}
 		// also add to output
 		if ru != 0 {
 			b2 := []byte(string(ru))
-			//_, _ = tio.tf.Write(b2) // send back to filter
-			_ = tio.WriteOp(b2) // add directly to output
+			if tio.erow.terminalOpt.filter {
+				_, _ = tio.tf.Write(b2) // send back to filter (screen)
+			} else {
+				_ = tio.WriteOp(b2) // add directly to output
+			}
 		}
 		return b
 	}
@@ -208,20 +327,81 @@ func (tio *ERowTermIO) eventToBytes(ev any) ([]byte, event.Handled) {
 	case *event.KeyDown:
 		if keyboardEvs() {
 			var b []byte
+			mods := t.Mods.ClearLocks()
 			switch t.KeySym {
 			case event.KSymReturn, event.KSymKeypadEnter:
-				b = byteOut('\n', '\n')
+				if pty() {
+					b = []byte{'\r'} // the tty echoes and translates
+				} else {
+					b = byteOut('\n', '\n')
+				}
 			case event.KSymEscape:
 				b = []byte{27}
 			case event.KSymTab:
 				b = []byte{'\t'}
 			case event.KSymBackspace:
-				b = []byte{'\b'}
+				if pty() {
+					b = []byte{127} // tty erase char
+				} else {
+					b = []byte{'\b'}
+				}
+			case event.KSymUp, event.KSymDown, event.KSymRight, event.KSymLeft:
+				// cursor keys: "ESC [ A" or "ESC O A" in application mode
+				c := map[event.KeySym]byte{event.KSymUp: 'A', event.KSymDown: 'B', event.KSymRight: 'C', event.KSymLeft: 'D'}[t.KeySym]
+				if tio.tf.AppCursorKeys() {
+					b = []byte{27, 'O', c}
+				} else {
+					b = []byte{27, '[', c}
+				}
+			case event.KSymHome:
+				b = []byte("\x1b[H")
+			case event.KSymEnd:
+				b = []byte("\x1b[F")
+			case event.KSymInsert:
+				b = []byte("\x1b[2~")
+			case event.KSymDelete:
+				b = []byte("\x1b[3~")
+			case event.KSymPageUp:
+				b = []byte("\x1b[5~")
+			case event.KSymPageDown:
+				b = []byte("\x1b[6~")
 			default:
-				b = []byte(string(t.Rune))
+				switch {
+				case mods.Is(event.ModCtrl) && t.KeySym >= event.KSymA && t.KeySym <= event.KSymZ:
+					b = []byte{byte(t.KeySym-event.KSymA) + 1} // ex: ctrl+c=0x3
+				case t.Rune != 0:
+					b = []byte(string(t.Rune))
+				}
 			}
 			return b, true
 		}
 	}
 	return nil, false
 }
+
+//----------
+
+// Updates the terminal size (cells) from the textarea size.
+func (erow *ERow) updateTerminalSize() {
+	d := erow.Row.TextArea.Drawer
+	ff := d.FontFace()
+	if ff == nil {
+		return
+	}
+	adv, ok := ff.GlyphAdvance('M')
+	lh := d.LineHeight()
+	if !ok || adv.Ceil() <= 0 || lh <= 0 {
+		return
+	}
+	r := d.Bounds()
+	p := image.Point{max(r.Dx()/adv.Ceil(), 1), max(r.Dy()/lh, 1)}
+	erow.termSize.Store(&p)
+}
+
+// Terminal size (cols, rows), with a default if the textarea was not measured yet. Safe to use concurrently.
+func (erow *ERow) terminalSize() (int, int) {
+	if p := erow.termSize.Load(); p != nil {
+		return p.X, p.Y
+	}
+	return 80, 24
+}
@@ -0,0 +1,450 @@
+package core
+
+import (
+	"unicode/utf8"
+)
+
+// Screen model of the terminal filter (vt100/xterm subset). Lines that scroll off the top of the main screen become history (written once to the row), while the screen lines are rewritten on each update.
+type tfScreen struct {
+	cols, rows int
+
+	main, alt tfBuffer
+	buf       *tfBuffer // current buffer (main or alt)
+
+	top, bot      int // scroll region, inclusive
+	autoWrap      bool
+	appCursorKeys bool
+	attr          tfAttr // graphic rendition of new runes
+
+	hist       []byte    // lines that scrolled off, not yet written
+	histStyles []tfStyle // offsets in hist
+	clearHist  bool      // erase the written history
+	dirty      bool
+}
+
+type tfCell struct {
+	ru   rune
+	attr tfAttr
+}
+
+// Spaces that don't show a color (can be trimmed at the end of the line).
+func (c tfCell) blank() bool {
+	return c.ru == ' ' && c.attr.bg == nil && !c.attr.reverse && !c.attr.underline
+}
+
+type tfBuffer struct {
+	lines    [][]tfCell
+	x, y     int
+	wrapNext bool // cursor is past the last column, wraps on the next rune
+	saved    struct {
+		x, y int
+	}
+}
+
+func newTfScreen(cols, rows int) *tfScreen {
+	s := &tfScreen{autoWrap: true}
+	s.buf = &s.main
+	s.resize(cols, rows)
+	return s
+}
+
+//----------
+
+func (s *tfScreen) resize(cols, rows int) {
+	cols, rows = max(cols, 1), max(rows, 1)
+	if cols == s.cols && rows == s.rows {
+		return
+	}
+	for _, b := range []*tfBuffer{&s.main, &s.alt} {
+		// remove lines from the top, keeping the cursor line
+		for len(b.lines) > rows {
+			if b.y == 0 {
+				b.lines = b.lines[:rows]
+				break
+			}
+			if b == &s.main {
+				s.addHist(b.lines[0])
+			}
+			b.lines = b.lines[1:]
+			b.y--
+		}
+		for len(b.lines) < rows {
+			b.lines = append(b.lines, nil)
+		}
+		for i, l := range b.lines {
+			b.lines[i] = resizeLine(l, cols)
+		}
+		b.x = min(b.x, cols-1)
+		b.y = min(b.y, rows-1)
+		b.wrapNext = false
+	}
+	s.cols, s.rows = cols, rows
+	s.top, s.bot = 0, rows-1
+	s.dirty = true
+}
+
+func resizeLine(l []tfCell, cols int) []tfCell {
+	if len(l) >= cols {
+		return l[:cols]
+	}
+	for len(l) < cols {
+		l = append(l, tfCell{ru: ' '})
+	}
+	return l
+}
+
+func (s *tfScreen) blankLine() []tfCell {
+	return resizeLine(make([]tfCell, 0, s.cols), s.cols)
+}
+
+//----------
+
+func (s *tfScreen) put(ru rune) {
+	b := s.buf
+	if b.wrapNext {
+		b.x = 0
+		s.index()
+	}
+	b.lines[b.y][b.x] = tfCell{ru: ru, attr: s.attr}
+	if b.x == s.cols-1 {
+		b.wrapNext = s.autoWrap
+	} else {
+		b.x++
+	}
+	s.dirty = true
+}
+
+// Moves down one line, scrolling at the bottom of the scroll region.
+func (s *tfScreen) index() {
+	b := s.buf
+	b.wrapNext = false
+	switch {
+	case b.y == s.bot:
+		s.scrollUp(1)
+	case b.y < s.rows-1:
+		b.y++
+	}
+}
+
+// Moves up one line, scrolling at the top of the scroll region.
+func (s *tfScreen) reverseIndex() {
+	b := s.buf
+	b.wrapNext = false
+	switch {
+	case b.y == s.top:
+		s.scrollDown(1)
+	case b.y > 0:
+		b.y--
+	}
+}
+
+func (s *tfScreen) carriageReturn() {
+	s.buf.x = 0
+	s.buf.wrapNext = false
+}
+
+func (s *tfScreen) backspace() {
+	b := s.buf
+	if b.x > 0 {
+		b.x--
+	}
+	b.wrapNext = false
+}
+
+func (s *tfScreen) tab() {
+	b := s.buf
+	b.x = min(s.cols-1, (b.x/8+1)*8)
+	b.wrapNext = false
+}
+
+//----------
+
+func (s *tfScreen) cursorTo(y, x int) {
+	b := s.buf
+	b.y = max(0, min(y, s.rows-1))
+	b.x = max(0, min(x, s.cols-1))
+	b.wrapNext = false
+}
+
+func (s *tfScreen) cursorMove(dy, dx int) {
+	b := s.buf
+	y := b.y + dy
+	// don't leave the scroll region when inside it
+	if b.y >= s.top && b.y <= s.bot {
+		y = max(s.top, min(y, s.bot))
+	}
+	s.cursorTo(y, b.x+dx)
+}
+
+func (s *tfScreen) saveCursor() {
+	b := s.buf
+	b.saved.x, b.saved.y = b.x, b.y
+}
+
+func (s *tfScreen) restoreCursor() {
+	b := s.buf
+	s.cursorTo(b.saved.y, b.saved.x)
+}
+
+//----------
+
+// Erase in display: 0=cursor to end, 1=start to cursor, 2=all, 3=all including history.
+func (s *tfScreen) eraseDisplay(mode int) {
+	b := s.buf
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for y := b.y + 1; y < s.rows; y++ {
+			b.lines[y] = s.blankLine()
+		}
+	case 1:
+		s.eraseLine(1)
+		for y := 0; y < b.y; y++ {
+			b.lines[y] = s.blankLine()
+		}
+	case 2, 3:
+		for y := range b.lines {
+			b.lines[y] = s.blankLine()
+		}
+		if mode == 3 {
+			s.hist = nil
+			s.histStyles = nil
+			s.clearHist = true
+		}
+	}
+	s.dirty = true
+}
+
+// Erase in line: 0=cursor to end, 1=start to cursor, 2=all.
+func (s *tfScreen) eraseLine(mode int) {
+	b := s.buf
+	l := b.lines[b.y]
+	x0, x1 := 0, s.cols
+	switch mode {
+	case 0:
+		x0 = b.x
+	case 1:
+		x1 = b.x + 1
+	}
+	for x := x0; x < x1; x++ {
+		l[x] = tfCell{ru: ' '}
+	}
+	b.wrapNext = false
+	s.dirty = true
+}
+
+func (s *tfScreen) eraseChars(n int) {
+	b := s.buf
+	l := b.lines[b.y]
+	for x := b.x; x < min(b.x+n, s.cols); x++ {
+		l[x] = tfCell{ru: ' '}
+	}
+	s.dirty = true
+}
+
+func (s *tfScreen) insertChars(n int) {
+	b := s.buf
+	l := b.lines[b.y]
+	n = min(n, s.cols-b.x)
+	copy(l[b.x+n:], l[b.x:])
+	for x := b.x; x < b.x+n; x++ {
+		l[x] = tfCell{ru: ' '}
+	}
+	s.dirty = true
+}
+
+func (s *tfScreen) deleteChars(n int) {
+	b := s.buf
+	l := b.lines[b.y]
+	n = min(n, s.cols-b.x)
+	copy(l[b.x:], l[b.x+n:])
+	for x := s.cols - n; x < s.cols; x++ {
+		l[x] = tfCell{ru: ' '}
+	}
+	s.dirty = true
+}
+
+//----------
+
+func (s *tfScreen) insertLines(n int) {
+	b := s.buf
+	if b.y < s.top || b.y > s.bot {
+		return
+	}
+	s.scrollRegionDown(b.y, s.bot, n)
+	b.x = 0
+	b.wrapNext = false
+}
+
+func (s *tfScreen) deleteLines(n int) {
+	b := s.buf
+	if b.y < s.top || b.y > s.bot {
+		return
+	}
+	s.scrollRegionUp(b.y, s.bot, n, false)
+	b.x = 0
+	b.wrapNext = false
+}
+
+func (s *tfScreen) scrollUp(n int) {
+	// lines scrolled off the top of the main screen are kept in history
+	hist := s.buf == &s.main && s.top == 0
+	s.scrollRegionUp(s.top, s.bot, n, hist)
+}
+
+func (s *tfScreen) scrollDown(n int) {
+	s.scrollRegionDown(s.top, s.bot, n)
+}
+
+func (s *tfScreen) scrollRegionUp(top, bot, n int, hist bool) {
+	b := s.buf
+	n = min(n, bot-top+1)
+	if hist {
+		for _, l := range b.lines[top : top+n] {
+			s.addHist(l)
+		}
+	}
+	copy(b.lines[top:], b.lines[top+n:bot+1])
+	for y := bot - n + 1; y <= bot; y++ {
+		b.lines[y] = s.blankLine()
+	}
+	s.dirty = true
+}
+
+func (s *tfScreen) scrollRegionDown(top, bot, n int) {
+	b := s.buf
+	n = min(n, bot-top+1)
+	copy(b.lines[top+n:bot+1], b.lines[top:])
+	for y := top; y < top+n; y++ {
+		b.lines[y] = s.blankLine()
+	}
+	s.dirty = true
+}
+
+// Top and bottom are inclusive. Bad values reset to the whole screen.
+func (s *tfScreen) setScrollRegion(top, bot int) {
+	if top < 0 || bot >= s.rows || top >= bot {
+		top, bot = 0, s.rows-1
+	}
+	s.top, s.bot = top, bot
+	s.cursorTo(0, 0)
+}
+
+//----------
+
+func (s *tfScreen) setAltScreen(on bool) {
+	if on == (s.buf == &s.alt) {
+		return
+	}
+	if on {
+		s.buf = &s.alt
+		for y := range s.alt.lines {
+			s.alt.lines[y] = s.blankLine()
+		}
+		s.alt.x, s.alt.y = s.main.x, s.main.y
+	} else {
+		s.buf = &s.main
+	}
+	s.alt.wrapNext = false
+	s.top, s.bot = 0, s.rows-1
+	s.dirty = true
+}
+
+func (s *tfScreen) reset() {
+	for _, b := range []*tfBuffer{&s.main, &s.alt} {
+		for y := range b.lines {
+			b.lines[y] = s.blankLine()
+		}
+		b.x, b.y, b.wrapNext = 0, 0, false
+	}
+	s.buf = &s.main
+	s.top, s.bot = 0, s.rows-1
+	s.autoWrap = true
+	s.appCursorKeys = false
+	s.attr = tfAttr{}
+	s.dirty = true
+}
+
+//----------
+
+func (s *tfScreen) addHist(l []tfCell) {
+	st := tfStyler{} // offsets from the start of hist (appendLine uses len(hist))
+	s.hist = st.appendLine(s.hist, l[:trimLineLen(l)])
+	s.histStyles = append(s.histStyles, st.styles...)
+	s.hist = append(s.hist, '\n')
+}
+
+// Returns the screen lines (trailing spaces and trailing empty lines removed), the styles and the byte offset of the cursor in them. The cursor line is padded up to the cursor.
+func (s *tfScreen) render() ([]byte, []tfStyle, int) {
+	b := s.buf
+	last := b.y
+	for y := s.rows - 1; y > last; y-- {
+		if trimLineLen(b.lines[y]) > 0 {
+			last = y
+			break
+		}
+	}
+	st := tfStyler{}
+	u := []byte{}
+	cursor := 0
+	for y := 0; y <= last; y++ {
+		if y > 0 {
+			u = append(u, '\n')
+		}
+		l := b.lines[y]
+		n := trimLineLen(l)
+		if y == b.y {
+			x := b.x
+			if b.wrapNext {
+				x = s.cols
+			}
+			n = max(n, x)
+			cursor = len(u) + runesLen(l[:x])
+		}
+		u = st.appendLine(u, l[:n])
+	}
+	return u, st.styles, cursor
+}
+
+func trimLineLen(l []tfCell) int {
+	k := len(l)
+	for k > 0 && l[k-1].blank() {
+		k--
+	}
+	return k
+}
+
+func runesLen(l []tfCell) int {
+	n := 0
+	for _, c := range l {
+		n += utf8.RuneLen(c.ru)
+	}
+	return n
+}
+
+//----------
+
+// Builds the styles of the rendered lines.
+type tfStyler struct {
+	offset int // added to the styles offsets
+	styles []tfStyle
+	cur    tfAttr
+}
+
+func (st *tfStyler) appendLine(u []byte, l []tfCell) []byte {
+	for _, c := range l {
+		st.set(st.offset+len(u), c.attr)
+		u = utf8.AppendRune(u, c.ru)
+	}
+	// styles don't continue to the next line
+	st.set(st.offset+len(u), tfAttr{})
+	return u
+}
+
+func (st *tfStyler) set(offset int, a tfAttr) {
+	if a == st.cur {
+		return
+	}
+	st.cur = a
+	st.styles = append(st.styles, tfStyle{Offset: offset, Attr: a})
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Graphic rendition (SGR) of a terminal cell.
+type tfAttr struct {
+	fg, bg    color.Color // nil is the default color
+	bold      bool
+	underline bool
+	reverse   bool
+}
+
+// Starts a run of runes with the attribute (until the next style).
+type tfStyle struct {
+	Offset int
+	Attr   tfAttr
+}
+
+//----------
+
+// Sets the attribute from the SGR ("ESC [ ... m") params.
+func (a *tfAttr) setSGR(params string) {
+	args := strings.FieldsFunc(params, func(ru rune) bool {
+		return ru == ';' || ru == ':'
+	})
+	if len(args) == 0 {
+		*a = tfAttr{}
+		return
+	}
+	arg := func(i int) int {
+		if i >= len(args) {
+			return -1
+		}
+		v, err := strconv.Atoi(args[i])
+		if err != nil {
+			return -1
+		}
+		return v
+	}
+	for i := 0; i < len(args); i++ {
+		v := arg(i)
+		switch {
+		case v == 0:
+			*a = tfAttr{}
+		case v == 1:
+			a.bold = true
+		case v == 4:
+			a.underline = true
+		case v == 7:
+			a.reverse = true
+		case v == 21 || v == 22:
+			a.bold = false
+		case v == 24:
+			a.underline = false
+		case v == 27:
+			a.reverse = false
+		case v >= 30 && v <= 37:
+			a.fg = tfColor256(v - 30)
+		case v == 38 || v == 48:
+			// extended: "5;n" (256 colors) or "2;r;g;b" (rgb)
+			var c color.Color
+			switch arg(i + 1) {
+			case 5:
+				if n := arg(i + 2); n >= 0 && n <= 255 {
+					c = tfColor256(n)
+				}
+				i += 2
+			case 2:
+				r, g, b := arg(i+2), arg(i+3), arg(i+4)
+				if r >= 0 && g >= 0 && b >= 0 {
+					c = color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+				}
+				i += 4
+			default:
+				i = len(args) // unknown, ignore the rest
+			}
+			if v == 38 {
+				a.fg = c
+			} else {
+				a.bg = c
+			}
+		case v == 39:
+			a.fg = nil
+		case v >= 40 && v <= 47:
+			a.bg = tfColor256(v - 40)
+		case v == 49:
+			a.bg = nil
+		case v >= 90 && v <= 97:
+			a.fg = tfColor256(v - 90 + 8)
+		case v >= 100 && v <= 107:
+			a.bg = tfColor256(v - 100 + 8)
+		}
+	}
+}
+
+//----------
+
+// xterm colors
+var tfColors16 = [16]uint32{
+	0x000000, 0xcd0000, 0x00cd00, 0xcdcd00, 0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+	0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00, 0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+}
+
+func tfColor256(n int) color.Color {
+	switch {
+	case n < 16:
+		c := tfColors16[n]
+		return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 255}
+	case n < 232:
+		// 6x6x6 cube
+		n -= 16
+		lvl := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		return color.RGBA{lvl(n / 36), lvl(n / 6 % 6), lvl(n % 6), 255}
+	default:
+		// grayscale
+		v := uint8(8 + (n-232)*10)
+		return color.RGBA{v, v, v, 255}
+	}
+}
+
+//----------
+
+// Strips the escape sequences from output that is not filtered as a terminal (ex: "grep --color=always"), keeping the SGR styles. Sequences split across writes are kept until complete.
+type tfSGRStripper struct {
+	attr    tfAttr
+	pending []byte // incomplete sequence from the previous write
+}
+
+// Returns the text without escape sequences and the styles (offsets in the text).
+func (s *tfSGRStripper) strip(p []byte) ([]byte, []tfStyle) {
+	src := p
+	if len(s.pending) > 0 {
+		src = append(s.pending, p...)
+		s.pending = nil
+	}
+	out := make([]byte, 0, len(src))
+	styles := []tfStyle{}
+	for i := 0; i < len(src); {
+		if src[i] != 27 {
+			out = append(out, src[i])
+			i++
+			continue
+		}
+		n, params, final, ok := tfEscapeSeq(src[i:])
+		if !ok {
+			s.pending = append([]byte(nil), src[i:]...)
+			break
+		}
+		i += n
+		if final != 'm' {
+			continue
+		}
+		prev := s.attr
+		s.attr.setSGR(params)
+		if s.attr == prev {
+			continue
+		}
+		st := tfStyle{Offset: len(out), Attr: s.attr}
+		if l := len(styles); l > 0 && styles[l-1].Offset == st.Offset {
+			styles[l-1] = st
+		} else {
+			styles = append(styles, st)
+		}
+	}
+	return out, styles
+}
+
+// Parses the escape sequence at the start of b (b[0] is ESC). Returns the sequence length, and the params and final byte if it is a CSI sequence. Not ok if the sequence is incomplete.
+func tfEscapeSeq(b []byte) (n int, params string, final byte, ok bool) {
+	const maxLen = 256 // don't wait forever for a broken sequence
+	incomplete := func() (int, string, byte, bool) {
+		if len(b) >= maxLen {
+			return 1, "", 0, true // drop the escape only
+		}
+		return 0, "", 0, false
+	}
+	if len(b) < 2 {
+		return incomplete()
+	}
+	switch c := b[1]; {
+	case c == '[': // CSI: params, intermediates, final byte
+		for i := 2; i < len(b); i++ {
+			switch c := b[i]; {
+			case c >= 0x20 && c <= 0x3f:
+			case c >= 0x40 && c <= 0x7e:
+				return i + 1, string(b[2:i]), c, true
+			default:
+				return 1, "", 0, true // broken, drop the escape only
+			}
+		}
+		return incomplete()
+	case c == ']': // OSC: ends with BEL or "ESC \"
+		for i := 2; i < len(b); i++ {
+			if b[i] == 7 {
+				return i + 1, "", 0, true
+			}
+			if b[i] == 27 && i+1 < len(b) && b[i+1] == '\\' {
+				return i + 2, "", 0, true
+			}
+		}
+		return incomplete()
+	case c >= 0x20 && c <= 0x2f: // intermediates, then final byte (ex: "ESC ( B")
+		for i := 2; i < len(b); i++ {
+			if b[i] < 0x20 || b[i] > 0x2f {
+				return i + 1, "", 0, true
+			}
+		}
+		return incomplete()
+	default:
+		return 2, "", 0, true
+	}
+}
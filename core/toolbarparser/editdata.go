@@ -89,6 +89,26 @@ func insertCmdPartAtEnd(data *Data, cmd, arg string) uoipcResult {
 
 //----------
 
+// Replaces the args of the part at index with the string (ex: a cmd from history). If the index is at the first part (row name), a new part is inserted at the end. The result positions are the string positions.
+func ReplacePartArgsAtIndex(data *Data, i int, str string) uoipcResult {
+	part, ok := data.PartAtIndex(i)
+	if !ok || part == data.Parts[0] {
+		res := insertCmdPartAtEnd(data, str, "")
+		res.Pos -= len(str) + 1 // inserted with a space after
+		res.End = res.Pos + len(str)
+		return res
+	}
+	start, end := i, i
+	if len(part.Args) > 0 {
+		start, end = part.Args[0].Pos(), part.Args[len(part.Args)-1].End()
+	}
+	res := uoipcResult{Pos: start}
+	res.S, res.End = insDelStr(data.Str, start, end, str)
+	return res
+}
+
+//----------
+
 func insDelStr(s string, i1, i2 int, a string) (string, int) {
 	u := s[:i1] + a + s[i2:]
 	return u, i1 + len(a)
@@ -80,3 +80,22 @@ func TestUpdateOrInsertCmd8(t *testing.T) {
 		t.Fatal(res)
 	}
 }
+
+func TestReplacePartArgsAtIndex1(t *testing.T) {
+	s := "aa|bb| cmd1 cc |dd"
+	data := Parse(s)
+	res := ReplacePartArgsAtIndex(data, 8, "go test")
+	if res.S != "aa|bb| go test |dd" || res.S[res.Pos:res.End] != "go test" {
+		t.Fatal(res)
+	}
+}
+
+// index at the first part, need to insert
+func TestReplacePartArgsAtIndex2(t *testing.T) {
+	s := "aa|bb"
+	data := Parse(s)
+	res := ReplacePartArgsAtIndex(data, 1, "go test")
+	if res.S != "aa|bb | go test " || res.S[res.Pos:res.End] != "go test" {
+		t.Fatal(res)
+	}
+}
@@ -71,6 +71,10 @@ func (ta *TextArea) handleInputEvent2(ev0 any, p image.Point) event.Handled {
 				if ta.selAnnCurEv(ev.Point, TasatPrintPreviousAll) {
 					return true
 				}
+			case m.IsEmpty():
+				if ta.selInlayHintEv(ev.Point) {
+					return true
+				}
 			}
 			if !ta.SupportClickInsideSelection || !ta.PointIndexInsideSelection(ev.Point) {
 				rwedit.MoveCursorToPoint(ta.EditCtx(), ev.Point, false)
@@ -170,6 +174,18 @@ func (ta *TextArea) selAnnCurEv(p image.Point, typ TASelAnnType) bool {
 
 //----------
 
+func (ta *TextArea) selInlayHintEv(p image.Point) event.Handled {
+	i, o, ok := ta.Drawer.InlayHintsIndexOf(p)
+	if !ok {
+		return false
+	}
+	ev2 := &TextAreaSelectInlayHintEvent{ta, i, o, false}
+	ta.EvReg.RunCallbacks(TextAreaSelectInlayHintEventId, ev2)
+	return ev2.ReplyHandled
+}
+
+//----------
+
 func (ta *TextArea) inlineCompleteEv() event.Handled {
 	c := ta.Cursor()
 	if c.HaveSelection() {
@@ -225,6 +241,7 @@ const (
 	TextAreaInlineCompleteEventId
 	TextAreaInputEventId
 	TextAreaLayoutEventId
+	TextAreaSelectInlayHintEventId
 )
 
 //----------
@@ -259,6 +276,16 @@ const (
 
 //----------
 
+type TextAreaSelectInlayHintEvent struct {
+	TextArea  *TextArea
+	HintIndex int // drawer inlay hints entry index
+	Offset    int // hint string click offset
+
+	ReplyHandled event.Handled // allow callbacks to set value (ex: hint not clickable)
+}
+
+//----------
+
 type TextAreaInlineCompleteEvent struct {
 	TextArea *TextArea
 	Offset   int
@@ -37,10 +37,14 @@ func lightThemeColorsPal() widget.Palette {
 		"text_colorize_comments_fg": cint(0x008b00), // green
 		"text_highlightword_fg":     nil,
 		"text_highlightword_bg":     cint(0xc6ee9e), // green
+		"text_highlightread_bg":     cint(0xc6e2ee), // blue
+		"text_highlightwrite_bg":    cint(0xeed2c6), // orange
 		"text_wrapline_fg":          cint(0x0),
 		"text_wrapline_bg":          cint(0xd8d8d8),
 		"text_parenthesis_fg":       nil,
 		"text_parenthesis_bg":       cint(0xd8d8d8),
+		"text_inlayhints_fg":        cint(0x9e9e9e), // grey
+		"text_linehighlight_bg":     cint(0xd6eec6), // light green
 
 		"toolbar_text_bg":          cint(0xecf0f1), // "clouds" grey
 		"toolbar_text_wrapline_bg": cint(0xccccd8),
@@ -94,8 +98,12 @@ func acmeThemeColorsPal() widget.Palette {
 		"text_colorize_comments_fg": cint(0x007500), // green
 		"text_highlightword_fg":     nil,
 		"text_highlightword_bg":     cint(0xc6ee9e), // green
+		"text_highlightread_bg":     cint(0xc6e2ee), // blue
+		"text_highlightwrite_bg":    cint(0xeed2c6), // orange
 		"text_wrapline_fg":          cint(0x0),
 		"text_wrapline_bg":          cint(0xd8d8c6),
+		"text_inlayhints_fg":        cint(0x9e9e86), // grey
+		"text_linehighlight_bg":     cint(0xd2e6b8), // light green
 
 		"toolbar_text_bg":          cint(0xeaffff),
 		"toolbar_text_wrapline_bg": cint(0xc6d8d8),
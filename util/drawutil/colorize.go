@@ -57,6 +57,12 @@ func (c *Colorize) applyOp(op *ColorizeOp) {
 		st := &c.d.st.curColors
 		st.fg, st.bg = op.ProcColor(st.fg, st.bg)
 	}
+	if op.Bold {
+		c.d.st.curColors.bold = true
+	}
+	if op.Underline {
+		c.d.st.curColors.underline = true
+	}
 	if op.Line {
 		// run only once or will paint over runes
 		if op.Offset == c.d.st.runeR.ri {
@@ -78,4 +84,6 @@ type ColorizeOp struct {
 	ProcColor func(fg, bg color.Color) (fg2, bg2 color.Color)
 	Line      bool
 	SetNil    bool
+	Bold      bool
+	Underline bool
 }
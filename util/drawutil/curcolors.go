@@ -12,6 +12,8 @@ func (cc *CurColors) Iter() {
 	st.fg = cc.d.fg
 	st.bg = nil
 	st.lineBg = nil
+	st.bold = false
+	st.underline = false
 	if !cc.d.iterNext() {
 		return
 	}
@@ -45,6 +45,8 @@ type Drawer struct {
 		colorize           Colorize    // init
 		annotations        Annotations // insert
 		annotationsIndexOf AnnotationsIndexOf
+		inlayHints         InlayHints // insert
+		inlayHintsIndexOf  InlayHintsIndexOf
 	}
 
 	st State
@@ -108,10 +110,21 @@ type Drawer struct {
 			}
 			Entries *AnnotationGroup // must be ordered by offset
 		}
+		InlayHints struct {
+			On      bool
+			Fg, Bg  color.Color
+			Entries []*InlayHint // must be ordered by offset
+		}
 		WordHighlight struct {
 			On     bool
 			Fg, Bg color.Color
 			Group  ColorizeGroup
+			// symbol occurrences (ex: lsproto), used instead of the word matches while the cursor is inside one of them
+			Occurrences struct {
+				Entries []*WordOccurrence // set with SetWordOccurrences
+				Read    struct{ Fg, Bg color.Color }
+				Write   struct{ Fg, Bg color.Color }
+			}
 		}
 		ParenthesisHighlight struct {
 			On     bool
@@ -177,8 +190,10 @@ type State struct {
 		extraLine bool
 	}
 	curColors struct {
-		fg, bg color.Color
-		lineBg color.Color
+		fg, bg    color.Color
+		lineBg    color.Color
+		bold      bool
+		underline bool
 	}
 	cursor struct {
 		delay *CursorDelay
@@ -198,6 +213,19 @@ type State struct {
 		cei    int // current entries index (to add to q)
 		indexQ []int
 	}
+	inlayHints struct {
+		i int // current entries index
+	}
+	inlayHintsIndexOf struct {
+		p      mathutil.PointIntf
+		eindex int
+		offset int
+		inside struct { // inside a hint
+			on      bool
+			ei      int // entry index
+			soffset int // start offset
+		}
+	}
 	annotationsIndexOf struct {
 		p      mathutil.PointIntf
 		eindex int
@@ -239,6 +267,8 @@ func New() *Drawer {
 	d.iters.colorize.d = d
 	d.iters.annotations.d = d
 	d.iters.annotationsIndexOf.d = d
+	d.iters.inlayHints.d = d
+	d.iters.inlayHintsIndexOf.d = d
 	return d
 }
 
@@ -346,6 +376,14 @@ func (d *Drawer) SetRuneOffset(v int) {
 
 //----------
 
+// Entries must be ordered by offset and not overlap.
+func (d *Drawer) SetWordOccurrences(entries []*WordOccurrence) {
+	d.Opt.WordHighlight.Occurrences.Entries = entries
+	d.opt.wordH.updatedOps = false
+}
+
+//----------
+
 func (d *Drawer) SetCursorOffset(v int) {
 	d.opt.cursor.offset = v
 
@@ -407,6 +445,7 @@ func (d *Drawer) Draw(img draw.Image) {
 		&d.iters.curColors,
 		&d.iters.colorize,
 		&d.iters.line,
+		&d.iters.inlayHints, // before linewrap to wrap with the hints
 		&d.iters.lineWrap,
 		&d.iters.earlyExit, // after iters that change pen.Y
 		&d.iters.indent,
@@ -453,6 +492,27 @@ func (d *Drawer) LocalIndexOf(p image.Point) int {
 
 //----------
 
+// Returns the entry index of the inlay hint at the point, and the byte offset inside the hint string.
+func (d *Drawer) InlayHintsIndexOf(p image.Point) (int, int, bool) {
+	if !d.ready() || !d.Opt.InlayHints.On {
+		return 0, 0, false
+	}
+	d.st = State{}
+	d.st.inlayHintsIndexOf.p = mathutil.PIntf2(p)
+	iters := d.sIters(true, &d.iters.inlayHintsIndexOf)
+	d.loopInit(iters)
+	d.header0()
+	d.loop()
+
+	st := &d.st.inlayHintsIndexOf
+	if st.eindex < 0 {
+		return 0, 0, false
+	}
+	return st.eindex, st.offset, true
+}
+
+//----------
+
 func (d *Drawer) AnnotationsIndexOf(p image.Point) (int, int, bool) {
 	if !d.ready() {
 		return 0, 0, false
@@ -930,6 +990,7 @@ func (d *Drawer) sIters(earlyExit bool, more ...Iterator) []Iterator {
 	iters := []Iterator{
 		&d.iters.runeR,
 		&d.iters.line,
+		&d.iters.inlayHints,
 		&d.iters.lineWrap,
 	}
 	if earlyExit {
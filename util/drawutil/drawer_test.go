@@ -409,6 +409,110 @@ func TestImg18LongWrap(t *testing.T) {
 
 //----------
 
+func TestInlayHints1(t *testing.T) {
+	d, _ := newTestDrawerRect(image.Rect(0, 0, 300, 100))
+
+	s := "f(1, 2)\nabc"
+	r := iorw.NewStringReaderAt(s)
+	d.SetReader(r)
+
+	p0 := d.LocalPointOf(2)
+	p1 := d.LocalPointOf(5)
+	p4 := d.LocalPointOf(8)
+
+	d.Opt.InlayHints.On = true
+	d.Opt.InlayHints.Entries = []*InlayHint{
+		{Offset: 2, Bytes: []byte("a: ")},
+		{Offset: 5, Bytes: []byte("b: ")},
+	}
+
+	// hints are inserted before the rune at the offset
+	p2 := d.LocalPointOf(2)
+	p3 := d.LocalPointOf(5)
+	if p2.X <= p0.X || p3.X-p1.X <= p2.X-p0.X {
+		t.Fatal(p0, p1, p2, p3)
+	}
+	// next line not affected
+	if p5 := d.LocalPointOf(8); p5 != p4 {
+		t.Fatal(p4, p5)
+	}
+	// hints are not indexable, the rune after is used
+	if i := d.LocalIndexOf(p2); i != 2 {
+		t.Fatal(i)
+	}
+	if i := d.LocalIndexOf(image.Pt(p0.X+1, p0.Y)); i != 2 {
+		t.Fatal(i)
+	}
+}
+
+func TestInlayHints2(t *testing.T) {
+	d, _ := newTestDrawerRect(image.Rect(0, 0, 300, 100))
+
+	s := "a\nb\nc"
+	r := iorw.NewStringReaderAt(s)
+	d.SetReader(r)
+
+	p0 := d.LocalPointOf(2)
+	p1 := d.LocalPointOf(4)
+
+	d.Opt.InlayHints.On = true
+	d.Opt.InlayHints.Entries = []*InlayHint{
+		{Offset: 2, Bytes: []byte("lens1 | lens2"), Line: true},
+	}
+
+	// line hint in its own line above the offset
+	p2 := d.LocalPointOf(2)
+	if p2.X != p0.X || p2.Y <= p0.Y {
+		t.Fatal(p0, p2)
+	}
+	if p3 := d.LocalPointOf(4); p3.Y-p1.Y != p2.Y-p0.Y {
+		t.Fatal(p1, p3)
+	}
+	// hint string at the previous position
+	i, o, ok := d.InlayHintsIndexOf(image.Pt(p0.X+1, p0.Y+1))
+	if !ok || i != 0 || o != 0 {
+		t.Fatal(i, o, ok)
+	}
+	if _, _, ok := d.InlayHintsIndexOf(image.Pt(p2.X+1, p2.Y+1)); ok {
+		t.Fatal("rune at the offset is not a hint")
+	}
+}
+
+func TestWordOccurrences1(t *testing.T) {
+	d, _ := newTestDrawerRect(image.Rect(0, 0, 300, 100))
+	d.Opt.Cursor.On = true
+	d.Opt.WordHighlight.On = true
+	d.Opt.WordHighlight.Occurrences.Write.Bg = colornames.Red
+
+	s := "a := 1\nb := a\nfunc(a int)"
+	r := iorw.NewStringReaderAt(s)
+	d.SetReader(r)
+	d.SetWordOccurrences([]*WordOccurrence{
+		{Offset: 0, N: 1, Kind: WOKWrite},
+		{Offset: 12, N: 1, Kind: WOKRead},
+	})
+
+	// cursor inside an occurrence: only the occurrences
+	d.SetCursorOffset(12)
+	updateWordHighlightWord(d)
+	updateWordHighlightOps(d)
+	ops := d.Opt.WordHighlight.Group.Ops
+	if len(ops) != 4 || ops[0].Offset != 0 || ops[0].Bg != colornames.Red || ops[2].Offset != 12 {
+		t.Fatal(ops)
+	}
+
+	// cursor outside: word matches (includes the func arg)
+	d.SetCursorOffset(19)
+	updateWordHighlightWord(d)
+	updateWordHighlightOps(d)
+	ops = d.Opt.WordHighlight.Group.Ops
+	if len(ops) != 6 {
+		t.Fatal(ops)
+	}
+}
+
+//----------
+
 func newTestDrawer() (*Drawer, draw.Image) {
 	rect := image.Rect(0, 0, 70, 70)
 	return newTestDrawerRect(rect)
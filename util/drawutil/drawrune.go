@@ -37,15 +37,26 @@ func (dr *DrawRune) draw() {
 
 	// delayed draw
 	if st.delay != nil {
-		dr.draw2(st.delay.fface, st.delay.pen, st.delay.ru, st.delay.fg)
+		dl := st.delay
+		dr.draw2(dl.fface, dl.pen, dl.ru, dl.fg)
+		if dl.bold {
+			// draw again one pixel to the right
+			dr.draw2(dl.fface, dl.pen.Add(image.Point{1, 0}), dl.ru, dl.fg)
+		}
+		if dl.underline {
+			dr.drawUnderline(dl)
+		}
 	}
 
 	// delay drawing by one rune to allow drawing the kern bg correctly. The last position is also drawn because the runereader emits a final ru=0 at the end
 	st.delay = &DrawRuneDelay{
-		pen:   pen,
-		ru:    dr.d.st.runeR.ru,
-		fg:    dr.d.st.curColors.fg,
-		fface: dr.d.st.runeR.fface,
+		pen:       pen,
+		ru:        dr.d.st.runeR.ru,
+		fg:        dr.d.st.curColors.fg,
+		fface:     dr.d.st.runeR.fface,
+		bold:      dr.d.st.curColors.bold,
+		underline: dr.d.st.curColors.underline,
+		penb:      dr.d.iters.runeR.penBoundsRect(),
 	}
 }
 
@@ -77,11 +88,25 @@ func (dr *DrawRune) draw2(fface font.Face, pen image.Point, ru rune, fg color.Co
 	draw.DrawMask(dr.d.st.drawR.img, gr, image.NewUniform(fg), image.Point{}, mask, maskp, draw.Over)
 }
 
+func (dr *DrawRune) drawUnderline(dl *DrawRuneDelay) {
+	if dl.ru < 0 || dl.ru == '\n' {
+		return
+	}
+	// below the baseline
+	y := dl.penb.Min.Y + dl.fface.Metrics().Ascent.Ceil() + 1
+	r := image.Rect(dl.penb.Min.X, y, dl.penb.Max.X, y+1)
+	r = r.Intersect(dr.d.Bounds())
+	draw.Draw(dr.d.st.drawR.img, r, image.NewUniform(dl.fg), image.Point{}, draw.Over)
+}
+
 //----------
 
 type DrawRuneDelay struct {
-	pen   image.Point
-	ru    rune
-	fg    color.Color
-	fface font.Face
+	pen       image.Point
+	ru        rune
+	fg        color.Color
+	fface     font.Face
+	bold      bool
+	underline bool
+	penb      image.Rectangle
 }
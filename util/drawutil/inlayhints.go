@@ -0,0 +1,137 @@
+package drawutil
+
+// Inserts extra strings (not part of the content) before the runes at the hint offsets. Positions (cursor, indexOf, pointOf) account for the inserted strings, but the strings can't be selected.
+type InlayHints struct {
+	d *Drawer
+}
+
+func (ih *InlayHints) Init() {
+	ih.d.st.inlayHints.i = 0
+}
+
+func (ih *InlayHints) Iter() {
+	if ih.d.Opt.InlayHints.On && ih.d.iters.runeR.isNormal() {
+		if !ih.iter2() {
+			return
+		}
+	}
+	if !ih.d.iterNext() {
+		return
+	}
+}
+
+func (ih *InlayHints) End() {}
+
+//----------
+
+func (ih *InlayHints) iter2() bool {
+	entries := ih.d.Opt.InlayHints.Entries
+	i := &ih.d.st.inlayHints.i
+	ri := ih.d.st.runeR.ri
+	for ; *i < len(entries); *i++ {
+		e := entries[*i]
+		if e == nil || e.Offset < ri {
+			continue // past hint
+		}
+		if e.Offset > ri {
+			break // future hint
+		}
+		if !ih.insertHint(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ih *InlayHints) insertHint(e *InlayHint) bool {
+	// keep state
+	rr := ih.d.st.runeR
+	defer func() {
+		pen := ih.d.st.runeR.pen
+		ih.d.st.runeR = rr
+		ih.d.st.runeR.pen.X = pen.X // use the new penX
+		if e.Line {
+			ih.d.st.runeR.pen.Y = pen.Y // use the new line
+		}
+		// recalc (tab) advance after the insertion
+		ih.d.st.runeR.advance = ih.d.iters.runeR.tabbedGlyphAdvance(rr.ru)
+	}()
+
+	cc := ih.d.st.curColors // keep state
+	defer func() { ih.d.st.curColors = cc }()
+	assignColor(&ih.d.st.curColors.fg, ih.d.Opt.InlayHints.Fg)
+	assignColor(&ih.d.st.curColors.bg, ih.d.Opt.InlayHints.Bg)
+
+	// update inlayhintsindexof state
+	ih.d.st.inlayHintsIndexOf.inside.on = true
+	ih.d.st.inlayHintsIndexOf.inside.ei = ih.d.st.inlayHints.i
+	ih.d.st.inlayHintsIndexOf.inside.soffset = ih.d.st.runeR.ri
+	defer func() { ih.d.st.inlayHintsIndexOf.inside.on = false }()
+
+	if !ih.d.iters.runeR.insertExtraString(string(e.Bytes)) {
+		return false
+	}
+	if e.Line {
+		ih.d.iters.line.newLine()
+	}
+	return true
+}
+
+//----------
+
+// Should not be changed after being set in the drawer options (not locked while drawing).
+type InlayHint struct {
+	Offset int
+	Bytes  []byte // single line
+	Line   bool   // drawn in its own line above the offset (should be a line start)
+}
+
+//----------
+//----------
+//----------
+
+type InlayHintsIndexOf struct {
+	d *Drawer
+}
+
+func (hio *InlayHintsIndexOf) Init() {
+	hio.d.st.inlayHintsIndexOf.eindex = -1
+}
+
+func (hio *InlayHintsIndexOf) Iter() {
+	if hio.d.st.inlayHintsIndexOf.inside.on {
+		hio.iter2()
+	}
+	_ = hio.d.iterNext()
+}
+
+func (hio *InlayHintsIndexOf) End() {}
+
+//----------
+
+func (hio *InlayHintsIndexOf) iter2() {
+	p := &hio.d.st.inlayHintsIndexOf.p
+	pb := hio.d.iters.runeR.penBounds()
+
+	// before the y start
+	if p.Y < pb.Min.Y {
+		hio.d.iterStop()
+		return
+	}
+	// in the line
+	if p.Y < pb.Max.Y {
+		// before the x start
+		if p.X < pb.Min.X {
+			hio.d.iterStop()
+			return
+		}
+		// inside
+		if p.X < pb.Max.X {
+			st := &hio.d.st.inlayHintsIndexOf
+			st.eindex = st.inside.ei
+			st.offset = hio.d.st.runeR.ri - st.inside.soffset
+			hio.d.iterStop()
+			return
+		}
+	}
+}
@@ -30,28 +30,54 @@ func updateParenthesisHighlight(d *Drawer) {
 
 type ParenthesisHighlight struct {
 	d   *Drawer
-	sc  *pscan.Scanner
 	ops []*ColorizeOp
 	pad int
 }
 
 func (ph *ParenthesisHighlight) do() []*ColorizeOp {
 	ci := ph.d.opt.cursor.offset
-	r := iorw.NewLimitedReaderAtPad(ph.d.reader, ci, ci, ph.pad)
+	points := parenthesisMatch(ph.d.reader, ci, ph.pad)
+
+	// build colorize ops
+	opt := &ph.d.Opt.ParenthesisHighlight
+	fg := opt.Fg
+	bg := opt.Bg
+	for _, p := range points {
+		op1 := &ColorizeOp{Offset: p, Fg: fg, Bg: bg}
+		op2 := &ColorizeOp{Offset: p + 1} // assumes rune size 1
+		ph.ops = append(ph.ops, op1, op2)
+	}
+
+	return ph.ops
+}
+
+//----------
+
+// Returns the offsets of the open and close parenthesis, given the offset of one of them. Reads at most pad bytes around the index.
+func ParenthesisMatch(rd iorw.ReaderAt, index, pad int) (int, int, bool) {
+	points := parenthesisMatch(rd, index, pad)
+	if len(points) != 2 {
+		return 0, 0, false
+	}
+	return points[0], points[1], true
+}
+
+// Returns the sorted offsets of the parenthesis at the index (or before the index) and of its match (if found).
+func parenthesisMatch(rd iorw.ReaderAt, ci, pad int) []int {
+	r := iorw.NewLimitedReaderAtPad(rd, ci, ci, pad)
 
 	sc, pos0 := iorw.NewScanner(r, ci)
-	ph.sc = sc
 
 	// match a parenthesis
 	pairs := []rune("(){}[]")
 	sym := rune(0)
-	parseOpen := pscan.WKeep(&sym, ph.sc.W.RuneValue(ph.sc.W.RuneOneOf(pairs)))
+	parseOpen := pscan.WKeep(&sym, sc.W.RuneValue(sc.W.RuneOneOf(pairs)))
 	_, err := parseOpen(pos0)
 	if err != nil {
 		//return nil // error: no results returned
 
 		// try reading previous
-		if p3, err2 := ph.sc.M.ReverseMode(pos0, true, parseOpen); err2 != nil {
+		if p3, err2 := sc.M.ReverseMode(pos0, true, parseOpen); err2 != nil {
 			return nil // error: no results returned
 		} else {
 			pos0 = p3
@@ -94,24 +120,24 @@ func (ph *ParenthesisHighlight) do() []*ColorizeOp {
 		}
 		return pos, nil
 	}
-	_, _ = ph.sc.M.ReverseMode(pos0,
+	_, _ = sc.M.ReverseMode(pos0,
 		reverse,
-		ph.sc.W.LoopOneOrMore(ph.sc.W.And(
-			ph.sc.W.PtrFalse(&done),
-			ph.sc.W.Or(
+		sc.W.LoopOneOrMore(sc.W.And(
+			sc.W.PtrFalse(&done),
+			sc.W.Or(
 
 				// might not work well (forward vs reverse)
-				// ph.sc.W.QuotedString(),
+				// sc.W.QuotedString(),
 
-				ph.sc.W.AndNoReverse(
-					ph.sc.W.Rune(openRu),
+				sc.W.AndNoReverse(
+					sc.W.Rune(openRu),
 					pushOpen,
 				),
-				ph.sc.W.AndNoReverse(
-					ph.sc.W.Rune(closeRu),
+				sc.W.AndNoReverse(
+					sc.W.Rune(closeRu),
 					popClose,
 				),
-				ph.sc.M.OneRune,
+				sc.M.OneRune,
 			),
 		)),
 	)
@@ -125,16 +151,5 @@ func (ph *ParenthesisHighlight) do() []*ColorizeOp {
 			points[0], points[1] = points[1], points[0]
 		}
 	}
-
-	// build colorize ops
-	opt := &ph.d.Opt.ParenthesisHighlight
-	fg := opt.Fg
-	bg := opt.Bg
-	for _, p := range points {
-		op1 := &ColorizeOp{Offset: p, Fg: fg, Bg: bg}
-		op2 := &ColorizeOp{Offset: p + 1} // assumes rune size 1
-		ph.ops = append(ph.ops, op1, op2)
-	}
-
-	return ph.ops
+	return points
 }
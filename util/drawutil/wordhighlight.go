@@ -45,6 +45,10 @@ func updateWordHighlightOps(d *Drawer) {
 }
 
 func wordHOps(d *Drawer) []*ColorizeOp {
+	if ops, ok := wordHOccurrencesOps(d); ok {
+		return ops
+	}
+
 	word := d.opt.wordH.word
 	if word == nil {
 		return nil
@@ -94,3 +98,54 @@ func wordHOps(d *Drawer) []*ColorizeOp {
 	}
 	return ops
 }
+
+//----------
+
+func wordHOccurrencesOps(d *Drawer) ([]*ColorizeOp, bool) {
+	opt := &d.Opt.WordHighlight
+	entries := opt.Occurrences.Entries
+
+	// cursor must be inside an occurrence, otherwise they are from another symbol
+	ci := d.opt.cursor.offset
+	inside := false
+	for _, e := range entries {
+		if ci >= e.Offset && ci <= e.Offset+e.N {
+			inside = true
+			break
+		}
+	}
+	if !inside {
+		return nil, false
+	}
+
+	var ops []*ColorizeOp
+	for _, e := range entries {
+		fg, bg := opt.Fg, opt.Bg
+		switch e.Kind {
+		case WOKRead:
+			fg, bg = opt.Occurrences.Read.Fg, opt.Occurrences.Read.Bg
+		case WOKWrite:
+			fg, bg = opt.Occurrences.Write.Fg, opt.Occurrences.Write.Bg
+		}
+		op1 := &ColorizeOp{Offset: e.Offset, Fg: fg, Bg: bg}
+		op2 := &ColorizeOp{Offset: e.Offset + e.N}
+		ops = append(ops, op1, op2)
+	}
+	return ops, true
+}
+
+//----------
+
+type WordOccurrence struct {
+	Offset int
+	N      int
+	Kind   WordOccurrenceKind
+}
+
+type WordOccurrenceKind int
+
+const (
+	WOKText WordOccurrenceKind = iota // uses the word highlight colors
+	WOKRead
+	WOKWrite
+)
@@ -230,3 +230,23 @@ func (h *Hanger) Write(b []byte) (int, error) {
 	//return 0, io.EOF
 	return h.buf.Write(b)
 }
+
+func TestCmdIPty1(t *testing.T) {
+	m, s, err := OpenPty()
+	if err != nil {
+		t.Skip(err)
+	}
+	_ = m.Close()
+	_ = s.Close()
+
+	cmd := NewCmdI2([]string{"sh", "-c", "test -t 0 && stty size"})
+	c := NewPtyCmd(cmd, 50, 10)
+	buf := &bytes.Buffer{}
+	c.Cmd().Stdout = buf
+	if err := RunCmdI(c); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(buf.String()); s != "10 50" {
+		t.Fatalf("%q", s)
+	}
+}
@@ -27,6 +27,14 @@ func KillExecCmd(cmd *exec.Cmd) error {
 	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 }
 
+func SignalExecCmd(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return fmt.Errorf("process is nil")
+	}
+	// process group
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
 //----------
 
 // deals correctly with args that contain spaces
@@ -33,6 +33,13 @@ func KillExecCmd(cmd *exec.Cmd) error {
 	//return c.Run()
 }
 
+func SignalExecCmd(cmd *exec.Cmd, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return KillExecCmd(cmd)
+	}
+	return fmt.Errorf("signal not supported: %v", sig)
+}
+
 //----------
 
 func ShellCmdArgs(args ...string) []string {
@@ -0,0 +1,89 @@
+package osutil
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Runs the cmd with a pseudo-terminal as stdin/stdout/stderr. The cmd stdin/stdout are copied to/from the pty master, stderr is ignored (merged into stdout by the terminal).
+type PtyCmd struct {
+	CmdI
+	mu         sync.Mutex
+	master     *os.File
+	cols, rows int
+	outCopy    sync.WaitGroup
+}
+
+func NewPtyCmd(cmdi CmdI, cols, rows int) *PtyCmd {
+	return &PtyCmd{CmdI: cmdi, cols: cols, rows: rows}
+}
+
+func (c *PtyCmd) Start() error {
+	master, slave, err := OpenPty()
+	if err != nil {
+		return err
+	}
+	// closed here, the cmd keeps its own copy
+	defer slave.Close()
+
+	if err := SetPtySize(master, c.cols, c.rows); err != nil {
+		_ = master.Close()
+		return err
+	}
+
+	cmd := c.Cmd()
+	r, w := cmd.Stdin, cmd.Stdout
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+
+	setupPtyCmdSysProcAttr(cmd)
+
+	if err := c.CmdI.Start(); err != nil {
+		_ = master.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.master = master
+	c.mu.Unlock()
+
+	if r != nil {
+		go func() {
+			_, _ = io.Copy(master, r)
+		}()
+	}
+	c.outCopy.Add(1)
+	go func() {
+		defer c.outCopy.Done()
+		if w == nil {
+			w = io.Discard
+		}
+		// ends with an error (ex: EIO) when all slave copies are closed
+		_, _ = io.Copy(w, master)
+	}()
+	return nil
+}
+
+func (c *PtyCmd) Wait() error {
+	err := c.CmdI.Wait()
+	c.outCopy.Wait() // wait for the output to be read
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.master != nil {
+		_ = c.master.Close()
+		c.master = nil
+	}
+	return err
+}
+
+// Safe to use concurrently. Can be called before start to set the initial size.
+func (c *PtyCmd) Resize(cols, rows int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cols, c.rows = cols, rows
+	if c.master == nil {
+		return nil
+	}
+	return SetPtySize(c.master, cols, rows)
+}
@@ -0,0 +1,52 @@
+package osutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func OpenPty() (master, slave *os.File, _ error) {
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fd := int(m.Fd())
+
+	// unlock slave and get its number
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		_ = m.Close()
+		return nil, nil, fmt.Errorf("pty unlock: %w", err)
+	}
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	if err != nil {
+		_ = m.Close()
+		return nil, nil, fmt.Errorf("pty number: %w", err)
+	}
+
+	name := fmt.Sprintf("/dev/pts/%d", n)
+	s, err := os.OpenFile(name, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		_ = m.Close()
+		return nil, nil, err
+	}
+	return m, s, nil
+}
+
+func SetPtySize(f *os.File, cols, rows int) error {
+	ws := &unix.Winsize{Col: uint16(cols), Row: uint16(rows)}
+	return unix.IoctlSetWinsize(int(f.Fd()), unix.TIOCSWINSZ, ws)
+}
+
+// new session with the pty as the controlling terminal
+func setupPtyCmdSysProcAttr(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0 // child stdin
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package osutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+func OpenPty() (master, slave *os.File, _ error) {
+	return nil, nil, fmt.Errorf("pty: not supported on %v", runtime.GOOS)
+}
+
+func SetPtySize(f *os.File, cols, rows int) error {
+	return fmt.Errorf("pty: not supported on %v", runtime.GOOS)
+}
+
+func setupPtyCmdSysProcAttr(cmd *exec.Cmd) {}
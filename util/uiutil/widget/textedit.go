@@ -198,6 +198,16 @@ func (te *TextEdit) AppendBytesClearHistory(b []byte) error {
 	return nil
 }
 
+func (te *TextEdit) DeleteBytesClearHistory(i, n int) error {
+	return te.OverwriteBytesClearHistory(i, n, nil)
+}
+
+func (te *TextEdit) OverwriteBytesClearHistory(i, n int, b []byte) error {
+	te.rwu.History.Clear()
+	rw := te.rwu.ReadWriterAt // bypass history
+	return rw.OverwriteAt(i, n, b)
+}
+
 //----------
 
 func (te *TextEdit) SetStr(str string) error {
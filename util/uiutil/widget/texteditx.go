@@ -27,6 +27,10 @@ type TextEditX struct {
 			len   int
 		}
 	}
+	lineHighlight struct {
+		on    bool
+		index int
+	}
 }
 
 func NewTextEditX(uiCtx UIContext) *TextEditX {
@@ -43,8 +47,9 @@ func NewTextEditX(uiCtx UIContext) *TextEditX {
 		&d.Opt.WordHighlight.Group,
 		&d.Opt.ParenthesisHighlight.Group,
 		{}, // 3=terminal
-		{}, // 4=selection
-		{}, // 5=flash
+		{}, // 4=line highlight (ex: debugger stop location)
+		{}, // 5=selection
+		{}, // 6=flash
 	}
 
 	return te
@@ -58,6 +63,7 @@ func (te *TextEditX) PaintBase() {
 }
 
 func (te *TextEditX) Paint() {
+	te.updateLineHighlightOpt()
 	te.updateSelectionOpt()
 	te.updateFlashOpt()
 	te.TextEdit.Paint()
@@ -67,7 +73,7 @@ func (te *TextEditX) Paint() {
 
 func (te *TextEditX) updateSelectionOpt() {
 	d := te.Drawer
-	g := d.Opt.Colorize.Groups[4]
+	g := d.Opt.Colorize.Groups[5]
 	c := te.Cursor()
 	if s, e, ok := c.SelectionIndexes(); ok {
 		// colors
@@ -92,6 +98,57 @@ func (te *TextEditX) updateSelectionOpt() {
 
 //----------
 
+// Colors of the terminal output (ex: ansi escape sequences). Not safe to use concurrently.
+func (te *TextEditX) SetTerminalColorize(ops []*drawutil.ColorizeOp) {
+	te.Drawer.Opt.Colorize.Groups[3].Ops = ops
+	te.MarkNeedsPaint()
+}
+
+// Not safe to use concurrently.
+func (te *TextEditX) TerminalColorize() []*drawutil.ColorizeOp {
+	return te.Drawer.Opt.Colorize.Groups[3].Ops
+}
+
+//----------
+
+// Highlights the line at index until cleared (ex: debugger stop location). Not safe to use concurrently.
+func (te *TextEditX) SetLineHighlight(index int) {
+	te.lineHighlight.on = true
+	te.lineHighlight.index = index
+	te.MarkNeedsPaint()
+}
+
+func (te *TextEditX) ClearLineHighlight() {
+	if te.lineHighlight.on {
+		te.lineHighlight.on = false
+		te.MarkNeedsPaint()
+	}
+}
+
+func (te *TextEditX) LineHighlight() (int, bool) {
+	return te.lineHighlight.index, te.lineHighlight.on
+}
+
+func (te *TextEditX) updateLineHighlightOpt() {
+	g := te.Drawer.Opt.Colorize.Groups[4]
+	if !te.lineHighlight.on {
+		g.Ops = nil
+		return
+	}
+	// recalc every paint, the content might have changed
+	s, e := te.flashLineIndexes(te.lineHighlight.index)
+	if e == s {
+		e++ // need at least len 1 or the colorize op will be canceled
+	}
+	bg := te.TreeThemePaletteColor("text_linehighlight_bg")
+	g.Ops = []*drawutil.ColorizeOp{
+		{Offset: s, Bg: bg, Line: true},
+		{Offset: e},
+	}
+}
+
+//----------
+
 func (te *TextEditX) FlashLine(index int) {
 	te.startFlash(index, 0, true)
 }
@@ -167,7 +224,7 @@ func (te *TextEditX) updateFlashOpt() {
 }
 
 func (te *TextEditX) updateFlashOpt4(d *drawutil.Drawer) {
-	g := d.Opt.Colorize.Groups[5]
+	g := d.Opt.Colorize.Groups[6]
 	if !te.flash.index.on {
 		g.Ops = nil
 		return
@@ -266,9 +323,17 @@ func (te *TextEditX) OnThemeChange() {
 	d.Opt.Annotations.Selected.Fg = pcol("text_annotations_select_fg")
 	d.Opt.Annotations.Selected.Bg = pcol("text_annotations_select_bg")
 
+	// inlay hints
+	d.Opt.InlayHints.Fg = pcol("text_inlayhints_fg")
+	d.Opt.InlayHints.Bg = pcol("text_inlayhints_bg")
+
 	// word highlight
 	d.Opt.WordHighlight.Fg = pcol("text_highlightword_fg")
 	d.Opt.WordHighlight.Bg = pcol("text_highlightword_bg")
+	d.Opt.WordHighlight.Occurrences.Read.Fg = pcol("text_highlightread_fg")
+	d.Opt.WordHighlight.Occurrences.Read.Bg = pcol("text_highlightread_bg")
+	d.Opt.WordHighlight.Occurrences.Write.Fg = pcol("text_highlightwrite_fg")
+	d.Opt.WordHighlight.Occurrences.Write.Bg = pcol("text_highlightwrite_bg")
 
 	// parenthesis highlight
 	d.Opt.ParenthesisHighlight.Fg = pcol("text_parenthesis_fg")
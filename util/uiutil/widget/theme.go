@@ -21,6 +21,10 @@ var DefaultPalette = Palette{
 	"text_colorize_comments_bg":  nil,
 	"text_highlightword_fg":      nil,
 	"text_highlightword_bg":      cint(0xc6ee9e), // green
+	"text_highlightread_fg":      nil,
+	"text_highlightread_bg":      cint(0xc6e2ee), // blue
+	"text_highlightwrite_fg":     nil,
+	"text_highlightwrite_bg":     cint(0xeed2c6), // orange
 	"text_wrapline_fg":           cint(0x0),
 	"text_wrapline_bg":           cint(0xd8d8d8),
 	"text_parenthesis_fg":        cint(0x0),
@@ -29,6 +33,9 @@ var DefaultPalette = Palette{
 	"text_annotations_bg":        cint(0xb0e0ef),
 	"text_annotations_select_fg": cint(0x0),
 	"text_annotations_select_bg": cint(0xefc7b0),
+	"text_inlayhints_fg":         cint(0x9e9e9e), // grey 500
+	"text_inlayhints_bg":         nil,
+	"text_linehighlight_bg":      cint(0xd6eec6), // light green
 
 	"scrollbar_bg":        cint(0xf2f2f2),
 	"scrollhandle_normal": cint(0xb2b2b2),